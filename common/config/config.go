@@ -2,6 +2,7 @@ package configx
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/naturalselectionlabs/pregod/common/protocol"
 )
@@ -13,6 +14,8 @@ type File interface{}
 type HTTP struct {
 	Host string `mapstructure:"host"`
 	Port int    `mapstructure:"port"`
+	// TrustedProxies are the CIDRs of the proxies whose X-Forwarded-For is trusted besides the private networks
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 var _ fmt.Stringer = &Postgres{}
@@ -100,6 +103,113 @@ func (r *RabbitMQ) String() string {
 	)
 }
 
+const (
+	MQDriverAMQP   = "amqp"
+	MQDriverRedis  = "redis"
+	MQDriverMemory = "memory"
+)
+
+type MQ struct {
+	// Driver is one of amqp, redis and memory, defaults to amqp
+	Driver   string `mapstructure:"driver"`
+	Prefetch int    `mapstructure:"prefetch"`
+}
+
+type Scheduler struct {
+	// Concurrency is the maximum number of running jobs
+	Concurrency int `mapstructure:"concurrency"`
+	// Capacity is the maximum number of pending jobs, submitting blocks when it is reached
+	Capacity int `mapstructure:"capacity"`
+	// Networks limits the running jobs of each network, the networks not listed are only limited by Concurrency
+	Networks map[string]int `mapstructure:"networks"`
+	// Weights is the number of jobs taken from each lane in a round, the lanes are refresh, io, background and backfill
+	Weights map[string]int `mapstructure:"weights"`
+}
+
+type Backfill struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Window is the number of blocks indexed at a time
+	Window int64 `mapstructure:"window"`
+	// Interval is the time waited between the windows of an address
+	Interval time.Duration `mapstructure:"interval"`
+	// Jobs is the maximum number of addresses backfilled at the same time
+	Jobs int `mapstructure:"jobs"`
+}
+
+type Reorg struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is the time waited between the checks of a network
+	Interval time.Duration `mapstructure:"interval"`
+	// Depths is the number of confirmations of each network, the blocks deeper than it are final and no longer checked
+	Depths map[string]int64 `mapstructure:"depths"`
+}
+
+type Registry struct {
+	Datasources Components `mapstructure:"datasources"`
+	Workers     Components `mapstructure:"workers"`
+	// Precedence is the sources of each network from the highest priority,
+	// a transaction returned by several datasources keeps the fields of the highest one
+	Precedence map[string][]string `mapstructure:"precedence"`
+}
+
+type Components struct {
+	// Enabled is the names of the enabled components, all of them are enabled if it is empty
+	Enabled []string `mapstructure:"enabled"`
+	// Disabled is the names of the disabled components, it takes precedence over Enabled
+	Disabled []string `mapstructure:"disabled"`
+	// Timeout limits every component without a timeout of its own, no limit if it is zero
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Overrides are matched by the names of components, names are case-insensitive
+	Overrides []Component `mapstructure:"overrides"`
+}
+
+type Component struct {
+	Name string `mapstructure:"name"`
+	// Networks restricts the networks of the component, the networks it doesn't support are ignored
+	Networks []string      `mapstructure:"networks"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+type APIKey struct {
+	// Tiers are the quotas of the API keys by their type, the unlisted types use the default tiers
+	Tiers []Tier `mapstructure:"tiers"`
+	// Anonymous is the quota of each IP requesting without an API key
+	Anonymous *Tier `mapstructure:"anonymous"`
+	// SignIn is the quota of each IP requesting the nonces of the sign-in messages
+	SignIn *Tier `mapstructure:"sign_in"`
+	// Domain is the domain of the Sign-In with Ethereum messages issuing the API keys, defaults to the host of the request
+	Domain string `mapstructure:"domain"`
+	// Frontend is the key of our own front-ends, it isn't stored and its requests share its tier
+	Frontend *FrontendKey `mapstructure:"frontend"`
+}
+
+type FrontendKey struct {
+	// Key is a secret, it's set by the environment rather than committed
+	Key  string `mapstructure:"key"`
+	Tier Tier   `mapstructure:"tier"`
+}
+
+type Tier struct {
+	Type int    `mapstructure:"type"`
+	Name string `mapstructure:"name"`
+	// PerMinute and Daily are the maximum number of requests, no limit if they are zero
+	PerMinute int64 `mapstructure:"per_minute"`
+	Daily     int64 `mapstructure:"daily"`
+}
+
+type Webhook struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is the time waited between the polls of the due deliveries
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout limits each delivery request
+	Timeout time.Duration `mapstructure:"timeout"`
+	// MaxAttempts is the number of attempts before a delivery fails, a failed delivery can be replayed
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Backoff is the delay before the first retry, it doubles after each attempt up to MaxBackoff
+	Backoff    time.Duration `mapstructure:"backoff"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+}
+
 var _ fmt.Stringer = &OpenTelemetry{}
 
 type OpenTelemetry struct {
@@ -36,6 +36,15 @@ var tables = []any{
 	&model.APIKey{},
 	&model.Address{},
 	&collectibe.FriendTech{},
+	&model.BackfillCheckpoint{},
+	&model.Webhook{},
+	&model.WebhookDelivery{},
+	&model.Price{},
+}
+
+// indexes can't be declared by the tags of tables, they are created after the tables are migrated
+var indexes = []string{
+	`CREATE INDEX IF NOT EXISTS idx_transfers_document ON transfers USING GIN (` + model.TransferDocument + `) WHERE tag = 'social'`,
 }
 
 var (
@@ -116,6 +125,12 @@ func Dial(dsn string, autoMigrate bool) (*gorm.DB, error) {
 		if err := client.AutoMigrate(tables...); err != nil {
 			return nil, err
 		}
+
+		for _, index := range indexes {
+			if err := client.Exec(index).Error; err != nil {
+				return nil, err
+			}
+		}
 	}
 	sqlDB, _ := client.DB()
 
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// BackfillCheckpoint is the progress of indexing the history of an address backwards
+type BackfillCheckpoint struct {
+	Address string `gorm:"column:address;primaryKey" json:"address"`
+	Network string `gorm:"column:network;primaryKey" json:"network"`
+	// BlockNumberHead is the latest block when the backfill started, 0 means it hasn't started
+	BlockNumberHead int64 `gorm:"column:block_number_head;default:0" json:"block_number_head"`
+	// BlockNumber is the lowest block which has been indexed, the next window ends before it
+	BlockNumber  int64  `gorm:"column:block_number;default:0" json:"block_number"`
+	Transactions int64  `gorm:"column:transactions;default:0" json:"transactions"`
+	Done         bool   `gorm:"column:done;index;default:false" json:"done"`
+	Error        string `gorm:"column:error" json:"error,omitempty"`
+
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;not null;default:now();index" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime;not null;default:now();index" json:"updated_at"`
+}
+
+func (BackfillCheckpoint) TableName() string {
+	return "backfill_checkpoint"
+}
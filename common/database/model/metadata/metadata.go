@@ -36,6 +36,7 @@ type Token struct {
 	ID              string           `json:"id,omitempty"`
 	Value           *decimal.Decimal `json:"value,omitempty"`
 	ValueDisplay    *decimal.Decimal `json:"value_display,omitempty"` // TODO Refactor it
+	ValueUSD        *decimal.Decimal `json:"value_usd,omitempty"`     // ValueDisplay in USD at the time of the transaction
 	Cost            *Token           `json:"cost,omitempty"`          // TODO Differentiate between UMS
 	Description     string           `json:"description,omitempty"`
 	Attributes      []TokenAttribute `json:"attributes,omitempty"`
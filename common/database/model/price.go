@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Price is the USD price of a token at a time, the native tokens have an empty contract address
+type Price struct {
+	Network         string          `gorm:"column:network;primaryKey" json:"network"`
+	ContractAddress string          `gorm:"column:contract_address;primaryKey" json:"contract_address"`
+	Timestamp       time.Time       `gorm:"column:timestamp;primaryKey" json:"timestamp"`
+	Price           decimal.Decimal `gorm:"column:price;type:numeric;not null" json:"price"`
+	Source          string          `gorm:"column:source" json:"source"`
+
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;not null;default:now();index" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime;not null;default:now();index" json:"updated_at"`
+}
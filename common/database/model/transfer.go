@@ -8,6 +8,13 @@ import (
 	"github.com/lib/pq"
 )
 
+// TransferDocument is the full-text search document of the social posts in metadata, weighted from the title to the body.
+// The queries must use it as is to match the index of it.
+const TransferDocument = `(setweight(to_tsvector('simple', COALESCE(metadata->>'title', '')), 'A') || ` +
+	`setweight(jsonb_to_tsvector('simple', COALESCE(metadata->'tags', '[]'), '["string"]'), 'B') || ` +
+	`setweight(to_tsvector('simple', COALESCE(metadata->>'summary', '')), 'C') || ` +
+	`setweight(to_tsvector('simple', COALESCE(metadata->>'body', '')), 'D'))`
+
 type Transfer struct {
 	TransactionHash string          `gorm:"column:transaction_hash;primaryKey" json:"-"`
 	Timestamp       time.Time       `gorm:"column:timestamp" json:"-"`
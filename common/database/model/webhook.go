@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook delivers the notes matching its filters to URL, an empty filter matches everything but Addresses
+type Webhook struct {
+	ID string `gorm:"column:id;primaryKey" json:"id"`
+	// Owner is the address of the API key which registered the webhook
+	Owner string `gorm:"column:owner;index;not null" json:"-"`
+	URL   string `gorm:"column:url;not null" json:"url"`
+	// Secret signs the payloads with HMAC-SHA256
+	Secret    string         `gorm:"column:secret;not null" json:"secret"`
+	Addresses pq.StringArray `gorm:"column:addresses;type:text[]" json:"address"`
+	Tags      pq.StringArray `gorm:"column:tags;type:text[]" json:"tag,omitempty"`
+	Types     pq.StringArray `gorm:"column:types;type:text[]" json:"type,omitempty"`
+	Networks  pq.StringArray `gorm:"column:networks;type:text[]" json:"network,omitempty"`
+	Platforms pq.StringArray `gorm:"column:platforms;type:text[]" json:"platform,omitempty"`
+	Status    bool           `gorm:"column:status;index;not null;default:true" json:"status"`
+
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;not null;default:now();index" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime;not null;default:now();index" json:"updated_at"`
+}
+
+func (Webhook) TableName() string {
+	return "webhook"
+}
+
+// WebhookDelivery is a payload sent to a webhook and the result of its latest attempt
+type WebhookDelivery struct {
+	ID        string          `gorm:"column:id;primaryKey" json:"id"`
+	WebhookID string          `gorm:"column:webhook_id;index;not null" json:"webhook_id"`
+	Status    string          `gorm:"column:status;index;not null;default:pending" json:"status"`
+	Attempts  int             `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	Payload   json.RawMessage `gorm:"column:payload;type:jsonb" json:"payload"`
+	// ResponseCode and Error are the result of the latest attempt
+	ResponseCode  int        `gorm:"column:response_code;default:0" json:"response_code,omitempty"`
+	Error         string     `gorm:"column:error" json:"error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;index;not null;default:now()" json:"next_attempt_at"`
+	DeliveredAt   *time.Time `gorm:"column:delivered_at" json:"delivered_at,omitempty"`
+
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;not null;default:now();index" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime;not null;default:now();index" json:"updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"sync"
 
 	"github.com/naturalselectionlabs/pregod/common/database/model"
 	"github.com/naturalselectionlabs/pregod/common/database/model/metadata"
@@ -11,9 +12,26 @@ import (
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// UpsertHook is called with the transactions created by UpsertTransactions
+type UpsertHook func(ctx context.Context, transactions []model.Transaction)
+
+var (
+	upsertHookLocker sync.RWMutex
+	upsertHook       UpsertHook
+)
+
+func ReplaceUpsertHook(hook UpsertHook) {
+	upsertHookLocker.Lock()
+
+	defer upsertHookLocker.Unlock()
+
+	upsertHook = hook
+}
+
 func UpsertTransactions(ctx context.Context, transactions []*model.Transaction, dedupTransfer bool) error {
 	dbChunkSize := 800
 	var transfers []model.Transfer
@@ -56,7 +74,18 @@ func UpsertTransactions(ctx context.Context, transactions []*model.Transaction,
 		transfers = transfersMap2Array(transfersMap)
 	}
 
+	var createdTransactions []model.Transaction
+
 	for _, ts := range lo.Chunk(updatedTransactions, dbChunkSize) {
+		created, err := CreatedTransactions(Global(), ts)
+		if err != nil {
+			loggerx.Global().Error("failed to get created transactions", zap.Error(err))
+
+			return err
+		}
+
+		createdTransactions = append(createdTransactions, created...)
+
 		if err := Global().
 			Clauses(clause.OnConflict{
 				UpdateAll: true,
@@ -81,9 +110,78 @@ func UpsertTransactions(ctx context.Context, transactions []*model.Transaction,
 		}
 	}
 
+	upsertHookLocker.RLock()
+	hook := upsertHook
+	upsertHookLocker.RUnlock()
+
+	if hook != nil && len(createdTransactions) > 0 {
+		hook(ctx, createdTransactions)
+	}
+
 	return nil
 }
 
+// UpdateAllExcept is the conflict clause of UpdateAll without the omitted columns, the stored values of them are kept
+func UpdateAllExcept(db *gorm.DB, value interface{}, omitted ...string) (clause.OnConflict, error) {
+	statement := &gorm.Statement{DB: db}
+	if err := statement.Parse(value); err != nil {
+		return clause.OnConflict{}, err
+	}
+
+	var (
+		onConflict clause.OnConflict
+		columns    []string
+	)
+
+	for _, field := range statement.Schema.PrimaryFields {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field.DBName})
+	}
+
+	for _, field := range statement.Schema.Fields {
+		if field.DBName == "" || !field.Creatable || field.PrimaryKey || field.AutoCreateTime > 0 || lo.Contains(omitted, field.DBName) {
+			continue
+		}
+
+		columns = append(columns, field.DBName)
+	}
+
+	onConflict.DoUpdates = clause.AssignmentColumns(columns)
+
+	return onConflict, nil
+}
+
+// CreatedTransactions returns the transactions which are not stored yet, it must be called before they are upserted
+func CreatedTransactions(tx *gorm.DB, transactions []model.Transaction) ([]model.Transaction, error) {
+	if len(transactions) == 0 {
+		return nil, nil
+	}
+
+	var stored []model.Transaction
+
+	hashes := lo.Uniq(lo.Map(transactions, func(transaction model.Transaction, _ int) string {
+		return transaction.Hash
+	}))
+
+	if err := tx.
+		Model((*model.Transaction)(nil)).
+		Select("hash", "owner", "network").
+		Where("hash IN ?", hashes).
+		Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]struct{}, len(stored))
+	for _, transaction := range stored {
+		keys[transaction.Owner+":"+transaction.Network+":"+transaction.Hash] = struct{}{}
+	}
+
+	return lo.Filter(transactions, func(transaction model.Transaction, _ int) bool {
+		_, exists := keys[transaction.Owner+":"+transaction.Network+":"+transaction.Hash]
+
+		return !exists
+	}), nil
+}
+
 func DeduplicateTransactions(ctx context.Context, transactions []*model.Transaction) ([]*model.Transaction, error) {
 	var hashList []string
 	for _, transaction := range transactions {
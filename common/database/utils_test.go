@@ -0,0 +1,37 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestUpdateAllExcept(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	assert.NoError(t, err)
+
+	onConflict, err := UpdateAllExcept(db, &model.Transaction{}, "finality", "confirmations")
+	assert.NoError(t, err)
+
+	result := db.Clauses(onConflict).Create(&model.Transaction{Hash: "0x01", Finality: model.FinalityPending})
+	assert.NoError(t, result.Error)
+
+	statement := result.Statement.SQL.String()
+	assert.Contains(t, statement, `ON CONFLICT ("hash","owner","network","source") DO UPDATE SET "block_number"="excluded"."block_number"`)
+	assert.Contains(t, statement, `"updated_at"="excluded"."updated_at"`)
+
+	// The new transactions are created with their finality, and the stored ones keep theirs
+	updates := statement[strings.Index(statement, "DO UPDATE"):]
+	assert.Contains(t, statement, `"finality","confirmations") VALUES`)
+	assert.NotContains(t, updates, "finality")
+	assert.NotContains(t, updates, "confirmations")
+	assert.NotContains(t, updates, "created_at\"=")
+}
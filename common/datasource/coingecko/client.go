@@ -90,6 +90,49 @@ func (c *Client) Coin(ctx context.Context, id string, parameter CoinParameter) (
 	return &result, nil
 }
 
+// MarketChartRange returns the prices of a coin in the time range, the granularity depends on the length of the range,
+// it's hourly within 90 days and daily beyond
+func (c *Client) MarketChartRange(ctx context.Context, id string, parameter MarketChartRangeParameter) (*MarketChart, error) {
+	values, err := query.Values(parameter)
+	if err != nil {
+		return nil, err
+	}
+
+	internalURL, err := c.buildURL(fmt.Sprintf("/coins/%s/market_chart/range", id))
+	if err != nil {
+		return nil, err
+	}
+
+	internalURL.RawQuery = values.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, internalURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	// Rate limited responses are not empty charts
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", response.Status)
+	}
+
+	var result MarketChart
+
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 func New() *Client {
 	return &Client{
 		httpClient: http.DefaultClient,
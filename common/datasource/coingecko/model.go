@@ -1,5 +1,7 @@
 package coingecko
 
+import "encoding/json"
+
 type CoinListParameter struct {
 	IncludePlatform bool `url:"include_platform,omitempty"`
 }
@@ -26,3 +28,15 @@ type CoinParameter struct {
 	DeveloperData bool   `url:"developer_data,omitempty"`
 	Sparkline     bool   `url:"sparkline,omitempty"`
 }
+
+type MarketChartRangeParameter struct {
+	VsCurrency string `url:"vs_currency"`
+	// From and To are unix timestamps in seconds
+	From int64 `url:"from"`
+	To   int64 `url:"to"`
+}
+
+type MarketChart struct {
+	// Prices are the pairs of unix timestamp in milliseconds and price
+	Prices [][2]json.Number `json:"prices"`
+}
@@ -226,6 +226,9 @@ func makeTransactionHandlerFunc(ctx context.Context, message *protocol.Message,
 			return nil, fmt.Errorf("unmarshal transaction failed: %w", err)
 		}
 
+		// Used to detect the transactions orphaned by reorgs
+		transaction.BlockHash = originReceipt.BlockHash.String()
+
 		// Handle receipt status
 		transactionSuccess := receipt.Status == types.ReceiptStatusSuccessful
 		transaction.Success = &transactionSuccess
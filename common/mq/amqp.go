@@ -0,0 +1,356 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	rabbitmq "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+const (
+	amqpReconnectRetry    = 3
+	amqpReconnectInterval = 10 * time.Second
+	amqpConsumeInterval   = 3 * time.Second
+)
+
+var _ Queue = (*amqpQueue)(nil)
+
+type amqpQueue struct {
+	config   *configx.RabbitMQ
+	prefetch int
+
+	locker     sync.RWMutex
+	connection *rabbitmq.Connection
+	channel    *rabbitmq.Channel
+	closed     bool
+
+	// The exchanges declared by publishing, publishing to an undeclared exchange closes the channel
+	exchanges sync.Map
+	// The delay queues declared by delayed publishing
+	delayQueues sync.Map
+}
+
+func (q *amqpQueue) Name() string {
+	return configx.MQDriverAMQP
+}
+
+func (q *amqpQueue) Declare(_ context.Context, binding Binding) error {
+	_, err := q.declare(q.currentChannel(), binding)
+
+	return err
+}
+
+func (q *amqpQueue) Publish(ctx context.Context, exchange, routingKey string, body []byte) error {
+	if err := q.declareExchange(exchange); err != nil {
+		return err
+	}
+
+	return q.currentChannel().PublishWithContext(ctx, exchange, routingKey, false, false, rabbitmq.Publishing{
+		ContentType: protocol.ContentTypeJSON,
+		Body:        body,
+	})
+}
+
+// PublishDelayed publishes body to a queue without consumers, whose messages expire after delay and are dead-lettered
+// to exchange. Every delay has its own queue, since the messages expire only at the head of a queue.
+func (q *amqpQueue) PublishDelayed(ctx context.Context, exchange, routingKey string, body []byte, delay time.Duration) error {
+	if err := q.declareExchange(exchange); err != nil {
+		return err
+	}
+
+	queueName := fmt.Sprintf("mq.delay.%s.%s.%d", exchange, routingKey, delay.Milliseconds())
+
+	if _, declared := q.delayQueues.Load(queueName); !declared {
+		if _, err := q.currentChannel().QueueDeclare(queueName, true, false, false, false, rabbitmq.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    exchange,
+			"x-dead-letter-routing-key": routingKey,
+		}); err != nil {
+			return err
+		}
+
+		q.delayQueues.Store(queueName, struct{}{})
+	}
+
+	return q.currentChannel().PublishWithContext(ctx, "", queueName, false, false, rabbitmq.Publishing{
+		ContentType:  protocol.ContentTypeJSON,
+		DeliveryMode: rabbitmq.Persistent,
+		Body:         body,
+	})
+}
+
+func (q *amqpQueue) Consume(ctx context.Context, binding Binding) (<-chan *Delivery, error) {
+	consumer, err := q.consume(binding)
+	if err != nil {
+		return nil, err
+	}
+
+	resultCh := make(chan *Delivery)
+
+	go func() {
+		defer close(resultCh)
+
+		for {
+			select {
+			case <-ctx.Done():
+				consumer.cancel()
+
+				return
+			case delivery, ok := <-consumer.deliveryCh:
+				if ok {
+					select {
+					case resultCh <- &Delivery{Queue: consumer.queue, Body: delivery.Body, tag: delivery}:
+					case <-ctx.Done():
+						_ = delivery.Nack(false, true)
+
+						consumer.cancel()
+
+						return
+					}
+
+					continue
+				}
+
+				// The delivery channel is closed with its amqp channel, wait for reconnecting,
+				// exclusive queues are declared again with a new name
+				if consumer = q.reconsume(ctx, binding); consumer == nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return resultCh, nil
+}
+
+func (q *amqpQueue) Ack(_ context.Context, delivery *Delivery) error {
+	internalDelivery, ok := delivery.tag.(rabbitmq.Delivery)
+	if !ok {
+		return fmt.Errorf("invalid delivery of %s driver", q.Name())
+	}
+
+	return internalDelivery.Ack(false)
+}
+
+func (q *amqpQueue) Nack(_ context.Context, delivery *Delivery, requeue bool) error {
+	internalDelivery, ok := delivery.tag.(rabbitmq.Delivery)
+	if !ok {
+		return fmt.Errorf("invalid delivery of %s driver", q.Name())
+	}
+
+	return internalDelivery.Nack(false, requeue)
+}
+
+func (q *amqpQueue) Close() error {
+	q.locker.Lock()
+
+	defer q.locker.Unlock()
+
+	q.closed = true
+
+	return q.connection.Close()
+}
+
+func (q *amqpQueue) consume(binding Binding) (*amqpConsumer, error) {
+	channel := q.currentChannel()
+
+	queueName, err := q.declare(channel, binding)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer := amqpConsumer{
+		channel: channel,
+		queue:   queueName,
+		tag:     uuid.New().String(),
+	}
+
+	if consumer.deliveryCh, err = channel.Consume(queueName, consumer.tag, false, binding.Queue == "", false, false, nil); err != nil {
+		return nil, err
+	}
+
+	return &consumer, nil
+}
+
+// reconsume waits for the connection to be recovered, it returns nil if the context is done or the queue is closed
+func (q *amqpQueue) reconsume(ctx context.Context, binding Binding) *amqpConsumer {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(amqpConsumeInterval):
+		}
+
+		if q.isClosed() {
+			return nil
+		}
+
+		consumer, err := q.consume(binding)
+		if err != nil {
+			loggerx.Global().Info("wait mq reconnected", zap.Error(err), zap.String("queue", binding.Queue))
+
+			continue
+		}
+
+		return consumer
+	}
+}
+
+func (q *amqpQueue) declareExchange(exchange string) error {
+	if _, declared := q.exchanges.Load(exchange); declared {
+		return nil
+	}
+
+	kind, exists := protocol.ExchangeKinds[exchange]
+	if !exists {
+		return fmt.Errorf("invalid exchange: %s", exchange)
+	}
+
+	if err := q.currentChannel().ExchangeDeclare(exchange, kind, true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	q.exchanges.Store(exchange, struct{}{})
+
+	return nil
+}
+
+func (q *amqpQueue) declare(channel *rabbitmq.Channel, binding Binding) (string, error) {
+	kind, exists := protocol.ExchangeKinds[binding.Exchange]
+	if !exists {
+		return "", fmt.Errorf("invalid exchange: %s", binding.Exchange)
+	}
+
+	if err := channel.ExchangeDeclare(binding.Exchange, kind, true, false, false, false, nil); err != nil {
+		return "", err
+	}
+
+	queue, err := channel.QueueDeclare(binding.Queue, false, false, binding.Queue == "", false, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if err := channel.QueueBind(queue.Name, routingKey(binding.Exchange, binding.RoutingKey), binding.Exchange, false, nil); err != nil {
+		return "", err
+	}
+
+	return queue.Name, nil
+}
+
+func (q *amqpQueue) connect() error {
+	connection, err := rabbitmq.Dial(q.config.String())
+	if err != nil {
+		return err
+	}
+
+	channel, err := connection.Channel()
+	if err != nil {
+		return err
+	}
+
+	if err := channel.Qos(q.prefetch, 0, false); err != nil {
+		return err
+	}
+
+	q.locker.Lock()
+
+	defer q.locker.Unlock()
+
+	q.connection, q.channel = connection, channel
+
+	return nil
+}
+
+func (q *amqpQueue) reconnect(connection *rabbitmq.Connection) {
+	for {
+		<-connection.NotifyClose(make(chan *rabbitmq.Error))
+
+		if q.isClosed() {
+			return
+		}
+
+		loggerx.Global().Error("rabbitmq connection closed, reconnecting...")
+
+		for retry := 1; ; retry++ {
+			time.Sleep(amqpReconnectInterval)
+
+			err := q.connect()
+			if err == nil {
+				loggerx.Global().Info("connect mq success")
+
+				break
+			}
+
+			loggerx.Global().Error("connect mq failed", zap.Error(err), zap.Int("retry", retry))
+
+			if retry == amqpReconnectRetry {
+				panic("rabbitmq reconnect failed")
+			}
+		}
+
+		q.locker.RLock()
+		connection = q.connection
+		q.locker.RUnlock()
+	}
+}
+
+func (q *amqpQueue) currentChannel() *rabbitmq.Channel {
+	q.locker.RLock()
+
+	defer q.locker.RUnlock()
+
+	return q.channel
+}
+
+func (q *amqpQueue) isClosed() bool {
+	q.locker.RLock()
+
+	defer q.locker.RUnlock()
+
+	return q.closed
+}
+
+type amqpConsumer struct {
+	channel    *rabbitmq.Channel
+	queue      string
+	tag        string
+	deliveryCh <-chan rabbitmq.Delivery
+	once       sync.Once
+}
+
+// cancel stops the broker delivering to this consumer and requeues the buffered deliveries
+func (c *amqpConsumer) cancel() {
+	c.once.Do(func() {
+		if err := c.channel.Cancel(c.tag, false); err != nil {
+			return
+		}
+
+		go func() {
+			for delivery := range c.deliveryCh {
+				_ = delivery.Nack(false, true)
+			}
+		}()
+	})
+}
+
+func newAMQP(config *configx.RabbitMQ, prefetch int) (*amqpQueue, error) {
+	queue := amqpQueue{
+		config:   config,
+		prefetch: prefetch,
+	}
+
+	if err := queue.connect(); err != nil {
+		return nil, err
+	}
+
+	go queue.reconnect(queue.connection)
+
+	return &queue, nil
+}
@@ -0,0 +1,187 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+)
+
+const (
+	memoryQueueSize = 4096
+)
+
+var (
+	_ Queue = (*memoryQueue)(nil)
+
+	memoryOnce   sync.Once
+	memoryClient *memoryQueue
+)
+
+// memoryQueue is an in-process message bus, services running in the same process share one instance
+type memoryQueue struct {
+	locker   sync.RWMutex
+	queues   map[string]chan *Delivery
+	bindings map[string]map[string]struct{}
+}
+
+func (q *memoryQueue) Name() string {
+	return configx.MQDriverMemory
+}
+
+func (q *memoryQueue) Declare(_ context.Context, binding Binding) error {
+	if binding.Queue == "" {
+		return fmt.Errorf("exclusive queue can only be declared by consumer")
+	}
+
+	q.declare(binding)
+
+	return nil
+}
+
+func (q *memoryQueue) Publish(ctx context.Context, exchange, key string, body []byte) error {
+	q.locker.RLock()
+
+	queues := make(map[string]chan *Delivery)
+
+	for name := range q.bindings[bindingKey(exchange, key)] {
+		queues[name] = q.queues[name]
+	}
+
+	q.locker.RUnlock()
+
+	for name, queue := range queues {
+		select {
+		case queue <- &Delivery{Queue: name, Body: body}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (q *memoryQueue) PublishDelayed(_ context.Context, exchange, key string, body []byte, delay time.Duration) error {
+	time.AfterFunc(delay, func() {
+		_ = q.Publish(context.Background(), exchange, key, body)
+	})
+
+	return nil
+}
+
+func (q *memoryQueue) Consume(ctx context.Context, binding Binding) (<-chan *Delivery, error) {
+	exclusive := binding.Queue == ""
+	if exclusive {
+		binding.Queue = fmt.Sprintf("mq.exclusive.%s", uuid.New().String())
+	}
+
+	queue := q.declare(binding)
+	resultCh := make(chan *Delivery)
+
+	go func() {
+		defer close(resultCh)
+
+		if exclusive {
+			defer q.remove(binding)
+		}
+
+		for {
+			select {
+			case delivery := <-queue:
+				select {
+				case resultCh <- delivery:
+				case <-ctx.Done():
+					// Put it back without blocking the consumer
+					go func() { queue <- delivery }()
+
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return resultCh, nil
+}
+
+func (q *memoryQueue) Ack(context.Context, *Delivery) error {
+	return nil
+}
+
+func (q *memoryQueue) Nack(ctx context.Context, delivery *Delivery, requeue bool) error {
+	if !requeue {
+		return nil
+	}
+
+	q.locker.RLock()
+	queue, exists := q.queues[delivery.Queue]
+	q.locker.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("queue %s does not exist", delivery.Queue)
+	}
+
+	select {
+	case queue <- delivery:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *memoryQueue) Close() error {
+	return nil
+}
+
+func (q *memoryQueue) declare(binding Binding) chan *Delivery {
+	q.locker.Lock()
+
+	defer q.locker.Unlock()
+
+	queue, exists := q.queues[binding.Queue]
+	if !exists {
+		queue = make(chan *Delivery, memoryQueueSize)
+		q.queues[binding.Queue] = queue
+	}
+
+	key := bindingKey(binding.Exchange, binding.RoutingKey)
+	if q.bindings[key] == nil {
+		q.bindings[key] = make(map[string]struct{})
+	}
+
+	q.bindings[key][binding.Queue] = struct{}{}
+
+	return queue
+}
+
+func (q *memoryQueue) remove(binding Binding) {
+	q.locker.Lock()
+
+	defer q.locker.Unlock()
+
+	delete(q.queues, binding.Queue)
+	delete(q.bindings[bindingKey(binding.Exchange, binding.RoutingKey)], binding.Queue)
+}
+
+func bindingKey(exchange, key string) string {
+	return fmt.Sprintf("%s:%s", exchange, routingKey(exchange, key))
+}
+
+func memoryGlobal() *memoryQueue {
+	memoryOnce.Do(func() {
+		memoryClient = newMemory()
+	})
+
+	return memoryClient
+}
+
+func newMemory() *memoryQueue {
+	return &memoryQueue{
+		queues:   make(map[string]chan *Delivery),
+		bindings: make(map[string]map[string]struct{}),
+	}
+}
@@ -0,0 +1,115 @@
+package mq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDirect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	queue := newMemory()
+
+	binding := Binding{
+		Exchange:   protocol.ExchangeJob,
+		RoutingKey: protocol.IndexerWorkRoutingKey,
+		Queue:      protocol.IndexerWorkQueue,
+	}
+
+	// Messages published after declaring are kept until consuming
+	assert.NoError(t, queue.Declare(ctx, binding))
+	assert.NoError(t, queue.Publish(ctx, protocol.ExchangeJob, protocol.IndexerWorkRoutingKey, []byte("work")))
+	assert.NoError(t, queue.Publish(ctx, protocol.ExchangeJob, protocol.IndexerWorkRoutingKeyIO, []byte("io")))
+
+	deliveryCh, err := queue.Consume(ctx, binding)
+	assert.NoError(t, err)
+
+	delivery := <-deliveryCh
+	assert.Equal(t, "work", string(delivery.Body))
+	assert.Equal(t, protocol.IndexerWorkQueue, delivery.Queue)
+
+	// Requeue and receive it again
+	assert.NoError(t, queue.Nack(ctx, delivery, true))
+
+	delivery = <-deliveryCh
+	assert.Equal(t, "work", string(delivery.Body))
+	assert.NoError(t, queue.Ack(ctx, delivery))
+
+	select {
+	case delivery := <-deliveryCh:
+		t.Fatalf("unexpected delivery %s", delivery.Body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMemoryFanout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	queue := newMemory()
+
+	binding := Binding{
+		Exchange: protocol.ExchangeRefresh,
+	}
+
+	firstCh, err := queue.Consume(ctx, binding)
+	assert.NoError(t, err)
+
+	secondCh, err := queue.Consume(ctx, binding)
+	assert.NoError(t, err)
+
+	assert.NoError(t, queue.Publish(ctx, protocol.ExchangeRefresh, "ignored", []byte("refresh")))
+
+	assert.Equal(t, "refresh", string((<-firstCh).Body))
+	assert.Equal(t, "refresh", string((<-secondCh).Body))
+}
+
+func TestMemoryExclusiveRemoved(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	queue := newMemory()
+
+	deliveryCh, err := queue.Consume(ctx, Binding{Exchange: protocol.ExchangeRefresh})
+	assert.NoError(t, err)
+
+	cancel()
+
+	// Wait for the consumer to stop
+	for range deliveryCh {
+	}
+
+	queue.locker.RLock()
+	defer queue.locker.RUnlock()
+
+	assert.Empty(t, queue.queues)
+	assert.Empty(t, queue.bindings[bindingKey(protocol.ExchangeRefresh, "")])
+}
+
+func TestMemoryDelayed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	queue := newMemory()
+
+	binding := Binding{
+		Exchange:   protocol.ExchangeJob,
+		RoutingKey: protocol.IndexerWorkRoutingKey,
+		Queue:      protocol.IndexerWorkQueue,
+	}
+
+	deliveryCh, err := queue.Consume(ctx, binding)
+	assert.NoError(t, err)
+
+	publishedAt := time.Now()
+
+	assert.NoError(t, queue.PublishDelayed(ctx, protocol.ExchangeJob, protocol.IndexerWorkRoutingKey, []byte("retry"), 200*time.Millisecond))
+
+	delivery := <-deliveryCh
+	assert.Equal(t, "retry", string(delivery.Body))
+	assert.GreaterOrEqual(t, time.Since(publishedAt), 200*time.Millisecond)
+}
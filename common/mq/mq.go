@@ -0,0 +1,78 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+)
+
+const (
+	DefaultPrefetch = 100
+)
+
+// Binding routes the messages published to Exchange with RoutingKey into Queue.
+// An empty Queue declares an exclusive queue, which is removed once its consumer stops.
+type Binding struct {
+	Exchange   string
+	RoutingKey string
+	Queue      string
+}
+
+// Delivery is a message taken from a queue, it must be settled with Queue.Ack or Queue.Nack.
+type Delivery struct {
+	Queue string
+	Body  []byte
+
+	tag any
+}
+
+type Queue interface {
+	Name() string
+	Declare(ctx context.Context, binding Binding) error
+	Publish(ctx context.Context, exchange, routingKey string, body []byte) error
+	// PublishDelayed publishes body after delay, the message is held by the message bus rather than the publisher
+	PublishDelayed(ctx context.Context, exchange, routingKey string, body []byte, delay time.Duration) error
+	Consume(ctx context.Context, binding Binding) (<-chan *Delivery, error)
+	Ack(ctx context.Context, delivery *Delivery) error
+	Nack(ctx context.Context, delivery *Delivery, requeue bool) error
+	Close() error
+}
+
+// Dial connects to the message bus selected by config, the redis driver requires cache.Global to be initialized.
+func Dial(config *configx.MQ, rabbitmqConfig *configx.RabbitMQ) (Queue, error) {
+	if config == nil {
+		config = &configx.MQ{}
+	}
+
+	prefetch := config.Prefetch
+	if prefetch <= 0 {
+		prefetch = DefaultPrefetch
+	}
+
+	switch config.Driver {
+	case configx.MQDriverAMQP, "":
+		if rabbitmqConfig == nil {
+			return nil, fmt.Errorf("rabbitmq config is required by %s driver", configx.MQDriverAMQP)
+		}
+
+		return newAMQP(rabbitmqConfig, prefetch)
+	case configx.MQDriverRedis:
+		return newRedis(prefetch)
+	case configx.MQDriverMemory:
+		return memoryGlobal(), nil
+	default:
+		return nil, fmt.Errorf("unsupported mq driver: %s", config.Driver)
+	}
+}
+
+// routingKey ignores the routing key of fanout exchanges
+func routingKey(exchange, key string) string {
+	if protocol.ExchangeKinds[exchange] == "fanout" {
+		return ""
+	}
+
+	return key
+}
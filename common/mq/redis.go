@@ -0,0 +1,341 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/naturalselectionlabs/pregod/common/cache"
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"go.uber.org/zap"
+)
+
+const (
+	redisGroup         = "pregod"
+	redisFieldBody     = "body"
+	redisBlockDuration = 5 * time.Second
+	redisRetryInterval = 3 * time.Second
+	redisClaimInterval = time.Minute
+	// Messages pending longer than it are considered lost by their consumers
+	redisClaimMinIdle = 10 * time.Minute
+	// Keep streams from growing unbounded when nobody consumes them
+	redisStreamMaxLen = 1_000_000
+	// The delayed messages are scored by when they are due, and forwarded by every queue polling the set
+	redisDelayedKey      = "mq:delayed"
+	redisDelayedInterval = time.Second
+	redisDelayedBatch    = 100
+)
+
+var _ Queue = (*redisQueue)(nil)
+
+// redisQueue is a message bus built on Redis Streams, every queue is a stream read by a consumer group
+type redisQueue struct {
+	client   *redis.Client
+	consumer string
+	prefetch int
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+type redisDelayed struct {
+	ID         string `json:"id"`
+	Exchange   string `json:"exchange"`
+	RoutingKey string `json:"routing_key"`
+	Body       []byte `json:"body"`
+}
+
+func (q *redisQueue) Name() string {
+	return configx.MQDriverRedis
+}
+
+func (q *redisQueue) Declare(ctx context.Context, binding Binding) error {
+	if binding.Queue == "" {
+		return fmt.Errorf("exclusive queue can only be declared by consumer")
+	}
+
+	return q.declare(ctx, binding)
+}
+
+func (q *redisQueue) Publish(ctx context.Context, exchange, key string, body []byte) error {
+	queues, err := q.client.SMembers(ctx, redisBindingKey(exchange, key)).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, queue := range queues {
+		if err := q.client.XAdd(ctx, &redis.XAddArgs{
+			Stream: redisStreamKey(queue),
+			MaxLen: redisStreamMaxLen,
+			Approx: true,
+			Values: map[string]interface{}{
+				redisFieldBody: body,
+			},
+		}).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q *redisQueue) PublishDelayed(ctx context.Context, exchange, key string, body []byte, delay time.Duration) error {
+	// The id keeps the same bodies from being merged into one member
+	member, err := json.Marshal(&redisDelayed{
+		ID:         uuid.New().String(),
+		Exchange:   exchange,
+		RoutingKey: key,
+		Body:       body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.client.ZAdd(ctx, redisDelayedKey, &redis.Z{
+		Score:  float64(time.Now().Add(delay).UnixMilli()),
+		Member: member,
+	}).Err()
+}
+
+func (q *redisQueue) Consume(ctx context.Context, binding Binding) (<-chan *Delivery, error) {
+	exclusive := binding.Queue == ""
+	if exclusive {
+		binding.Queue = fmt.Sprintf("mq.exclusive.%s", uuid.New().String())
+	}
+
+	if err := q.declare(ctx, binding); err != nil {
+		return nil, err
+	}
+
+	resultCh := make(chan *Delivery)
+
+	go func() {
+		defer close(resultCh)
+
+		if exclusive {
+			defer q.remove(binding)
+		}
+
+		// deliver returns false if the context is done, the undelivered messages stay pending and will be claimed
+		deliver := func(messages []redis.XMessage) bool {
+			for _, message := range messages {
+				body, _ := message.Values[redisFieldBody].(string)
+
+				select {
+				case resultCh <- &Delivery{Queue: binding.Queue, Body: []byte(body), tag: message.ID}:
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			return true
+		}
+
+		var claimedAt time.Time
+
+		for {
+			// Claim the messages left pending by the consumers which are gone
+			if time.Since(claimedAt) > redisClaimInterval {
+				claimedAt = time.Now()
+
+				messages, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+					Stream:   redisStreamKey(binding.Queue),
+					Group:    redisGroup,
+					Consumer: q.consumer,
+					MinIdle:  redisClaimMinIdle,
+					Start:    "0-0",
+					Count:    int64(q.prefetch),
+				}).Result()
+				if err != nil && ctx.Err() == nil {
+					loggerx.Global().Error("claim redis stream failed", zap.Error(err), zap.String("queue", binding.Queue))
+				}
+
+				if !deliver(messages) {
+					return
+				}
+			}
+
+			streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    redisGroup,
+				Consumer: q.consumer,
+				Streams:  []string{redisStreamKey(binding.Queue), ">"},
+				Count:    int64(q.prefetch),
+				Block:    redisBlockDuration,
+			}).Result()
+
+			switch {
+			case ctx.Err() != nil:
+				return
+			case errors.Is(err, redis.Nil):
+				continue
+			case err != nil:
+				loggerx.Global().Error("read redis stream failed", zap.Error(err), zap.String("queue", binding.Queue))
+
+				time.Sleep(redisRetryInterval)
+
+				continue
+			}
+
+			for _, stream := range streams {
+				if !deliver(stream.Messages) {
+					return
+				}
+			}
+		}
+	}()
+
+	return resultCh, nil
+}
+
+func (q *redisQueue) Ack(ctx context.Context, delivery *Delivery) error {
+	id, ok := delivery.tag.(string)
+	if !ok {
+		return fmt.Errorf("invalid delivery of %s driver", q.Name())
+	}
+
+	pipeline := q.client.TxPipeline()
+	pipeline.XAck(ctx, redisStreamKey(delivery.Queue), redisGroup, id)
+	pipeline.XDel(ctx, redisStreamKey(delivery.Queue), id)
+
+	_, err := pipeline.Exec(ctx)
+
+	return err
+}
+
+func (q *redisQueue) Nack(ctx context.Context, delivery *Delivery, requeue bool) error {
+	id, ok := delivery.tag.(string)
+	if !ok {
+		return fmt.Errorf("invalid delivery of %s driver", q.Name())
+	}
+
+	pipeline := q.client.TxPipeline()
+
+	if requeue {
+		pipeline.XAdd(ctx, &redis.XAddArgs{
+			Stream: redisStreamKey(delivery.Queue),
+			Values: map[string]interface{}{
+				redisFieldBody: delivery.Body,
+			},
+		})
+	}
+
+	pipeline.XAck(ctx, redisStreamKey(delivery.Queue), redisGroup, id)
+	pipeline.XDel(ctx, redisStreamKey(delivery.Queue), id)
+
+	_, err := pipeline.Exec(ctx)
+
+	return err
+}
+
+func (q *redisQueue) Close() error {
+	q.closeOnce.Do(func() {
+		close(q.done)
+	})
+
+	return nil
+}
+
+// forwardDelayed publishes the delayed messages which are due, a message is taken by the queue removing it from the set
+func (q *redisQueue) forwardDelayed() {
+	ticker := time.NewTicker(redisDelayedInterval)
+	defer ticker.Stop()
+
+	ctx := context.Background()
+
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-ticker.C:
+		}
+
+		members, err := q.client.ZRangeByScore(ctx, redisDelayedKey, &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatInt(time.Now().UnixMilli(), 10),
+			Count: redisDelayedBatch,
+		}).Result()
+		if err != nil {
+			loggerx.Global().Error("get redis delayed messages failed", zap.Error(err))
+
+			continue
+		}
+
+		for _, member := range members {
+			if removed, err := q.client.ZRem(ctx, redisDelayedKey, member).Result(); err != nil || removed == 0 {
+				continue
+			}
+
+			var delayed redisDelayed
+
+			if err := json.Unmarshal([]byte(member), &delayed); err != nil {
+				loggerx.Global().Error("invalid redis delayed message", zap.Error(err), zap.String("member", member))
+
+				continue
+			}
+
+			if err := q.Publish(ctx, delayed.Exchange, delayed.RoutingKey, delayed.Body); err != nil {
+				loggerx.Global().Error("publish redis delayed message failed", zap.Error(err), zap.String("exchange", delayed.Exchange))
+
+				// Put it back to be forwarded by the next poll
+				_ = q.client.ZAdd(ctx, redisDelayedKey, &redis.Z{Score: float64(time.Now().UnixMilli()), Member: member}).Err()
+			}
+		}
+	}
+}
+
+func (q *redisQueue) declare(ctx context.Context, binding Binding) error {
+	if err := q.client.XGroupCreateMkStream(ctx, redisStreamKey(binding.Queue), redisGroup, "0").Err(); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	return q.client.SAdd(ctx, redisBindingKey(binding.Exchange, binding.RoutingKey), binding.Queue).Err()
+}
+
+func (q *redisQueue) remove(binding Binding) {
+	ctx := context.Background()
+
+	if err := q.client.SRem(ctx, redisBindingKey(binding.Exchange, binding.RoutingKey), binding.Queue).Err(); err != nil {
+		loggerx.Global().Error("remove redis binding failed", zap.Error(err), zap.String("queue", binding.Queue))
+	}
+
+	if err := q.client.Del(ctx, redisStreamKey(binding.Queue)).Err(); err != nil {
+		loggerx.Global().Error("remove redis stream failed", zap.Error(err), zap.String("queue", binding.Queue))
+	}
+}
+
+func redisStreamKey(queue string) string {
+	return fmt.Sprintf("mq:queue:%s", queue)
+}
+
+func redisBindingKey(exchange, key string) string {
+	return fmt.Sprintf("mq:binding:%s", bindingKey(exchange, key))
+}
+
+func newRedis(prefetch int) (*redisQueue, error) {
+	client := cache.Global()
+	if client == nil {
+		return nil, fmt.Errorf("redis is required by %s driver", configx.MQDriverRedis)
+	}
+
+	hostname, _ := os.Hostname()
+
+	queue := redisQueue{
+		client:   client,
+		consumer: fmt.Sprintf("%s-%s", hostname, uuid.New().String()),
+		prefetch: prefetch,
+		done:     make(chan struct{}),
+	}
+
+	go queue.forwardDelayed()
+
+	return &queue, nil
+}
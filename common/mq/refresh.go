@@ -0,0 +1,35 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/samber/lo"
+)
+
+// The notes carried by a refresh message
+const RefreshChunkSize = 100
+
+// PublishRefresh broadcasts the newly written notes of address on network to the hubs in chunks
+func PublishRefresh(ctx context.Context, queue Queue, address, network string, transactions []model.Transaction) error {
+	for _, chunk := range lo.Chunk(transactions, RefreshChunkSize) {
+		messageData, err := json.Marshal(&protocol.RefreshMessage{
+			Address: model.Address{
+				Address: address,
+			},
+			Network:      network,
+			Transactions: chunk,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := queue.Publish(ctx, protocol.ExchangeRefresh, "", messageData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,303 @@
+package price
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/datasource/coingecko"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Window is the time range of the prices fetched at a time, CoinGecko returns hourly prices within 90 days
+	Window = 90 * 24 * time.Hour
+	// MaxAge is the oldest price taken as the price at a time, the prices of the windows longer ago than 90 days are daily
+	MaxAge = 25 * time.Hour
+
+	// The window containing the time it was fetched is fetched again after the interval
+	refreshInterval = time.Hour
+
+	// The prices missing from Stored are fetched in the background, the requests beyond the queue are dropped
+	warmQueueSize = 1024
+	warmTimeout   = time.Minute
+
+	currency = "usd"
+)
+
+// NativeCoins are the CoinGecko IDs of the native tokens of networks
+var NativeCoins = map[string]string{
+	protocol.NetworkEthereum:          "ethereum",
+	protocol.NetworkPolygon:           "matic-network",
+	protocol.NetworkBinanceSmartChain: "binancecoin",
+	protocol.NetworkXDAI:              "xdai",
+	protocol.NetworkZkSync:            "ethereum",
+	protocol.NetworkArbitrum:          "ethereum",
+	protocol.NetworkOptimism:          "ethereum",
+	protocol.NetworkAvalanche:         "avalanche-2",
+	protocol.NetworkCelo:              "celo",
+	protocol.NetworkFantom:            "fantom",
+	protocol.NetworkBase:              "ethereum",
+}
+
+// Client looks up the price history of tokens by network and contract address, the missing windows
+// are fetched from CoinGecko by the coin IDs of the token list and stored
+type Client struct {
+	databaseClient  *gorm.DB
+	coingeckoClient *coingecko.Client
+	rateLimiter     *rate.Limiter
+
+	locker  sync.Mutex
+	coins   map[string]string
+	windows map[string]time.Time
+
+	warmOnce sync.Once
+	warmCh   chan warmRequest
+	warming  map[warmRequest]struct{}
+}
+
+type warmRequest struct {
+	network         string
+	contractAddress string
+	window          time.Time
+}
+
+// Price returns the USD price of a token at timestamp, it's nil if the token isn't listed on CoinGecko or had no price then.
+// The native tokens have an empty contract address.
+func (c *Client) Price(ctx context.Context, network, contractAddress string, timestamp time.Time) (*decimal.Decimal, error) {
+	contractAddress = strings.ToLower(contractAddress)
+
+	price, err := c.lookup(ctx, network, contractAddress, timestamp)
+	if err != nil || price != nil {
+		return price, err
+	}
+
+	coinID, err := c.coinID(ctx, network, contractAddress)
+	if err != nil || coinID == "" {
+		return nil, err
+	}
+
+	fetched, err := c.fetch(ctx, coinID, network, contractAddress, timestamp)
+	if err != nil || !fetched {
+		return nil, err
+	}
+
+	return c.lookup(ctx, network, contractAddress, timestamp)
+}
+
+// Stored returns the stored USD price of a token at timestamp without fetching it, so that it doesn't wait for CoinGecko.
+// A missing price is fetched in the background, and it's returned by the later calls once stored.
+func (c *Client) Stored(ctx context.Context, network, contractAddress string, timestamp time.Time) (*decimal.Decimal, error) {
+	contractAddress = strings.ToLower(contractAddress)
+
+	price, err := c.lookup(ctx, network, contractAddress, timestamp)
+	if err == nil && price == nil {
+		c.warm(warmRequest{network: network, contractAddress: contractAddress, window: timestamp.Truncate(Window)})
+	}
+
+	return price, err
+}
+
+// warm queues fetching the window of request, a window is queued once until it's fetched
+func (c *Client) warm(request warmRequest) {
+	c.warmOnce.Do(func() {
+		go c.warmLoop()
+	})
+
+	c.locker.Lock()
+	defer c.locker.Unlock()
+
+	if _, exists := c.warming[request]; exists {
+		return
+	}
+
+	select {
+	case c.warmCh <- request:
+		c.warming[request] = struct{}{}
+	default:
+	}
+}
+
+func (c *Client) warmLoop() {
+	for request := range c.warmCh {
+		ctx, cancel := context.WithTimeout(context.Background(), warmTimeout)
+
+		if _, err := c.Price(ctx, request.network, request.contractAddress, request.window); err != nil {
+			loggerx.Global().Warn("failed to warm price", zap.Error(err), zap.String("network", request.network), zap.String("contract_address", request.contractAddress))
+		}
+
+		cancel()
+
+		c.locker.Lock()
+		delete(c.warming, request)
+		c.locker.Unlock()
+	}
+}
+
+// lookup returns the latest stored price at timestamp within MaxAge
+func (c *Client) lookup(ctx context.Context, network, contractAddress string, timestamp time.Time) (*decimal.Decimal, error) {
+	var prices []model.Price
+
+	if err := c.databaseClient.WithContext(ctx).
+		Where("network = ? AND contract_address = ?", network, contractAddress).
+		Where("timestamp <= ? AND timestamp > ?", timestamp, timestamp.Add(-MaxAge)).
+		Order("timestamp DESC").
+		Limit(1).
+		Find(&prices).Error; err != nil {
+		return nil, fmt.Errorf("get price: %w", err)
+	}
+
+	if len(prices) == 0 {
+		return nil, nil
+	}
+
+	return &prices[0].Price, nil
+}
+
+// coinID returns the CoinGecko ID of a token, it's empty for the tokens not in the token list
+func (c *Client) coinID(ctx context.Context, network, contractAddress string) (string, error) {
+	if contractAddress == "" {
+		return NativeCoins[network], nil
+	}
+
+	key := network + ":" + contractAddress
+
+	c.locker.Lock()
+	coinID, exists := c.coins[key]
+	c.locker.Unlock()
+
+	if exists {
+		return coinID, nil
+	}
+
+	var tokens []model.Token
+
+	if err := c.databaseClient.WithContext(ctx).
+		Where("network = ? AND contract_address = ?", network, contractAddress).
+		Limit(1).
+		Find(&tokens).Error; err != nil {
+		return "", fmt.Errorf("get token: %w", err)
+	}
+
+	if len(tokens) > 0 {
+		coinID = tokens[0].ID
+	}
+
+	c.locker.Lock()
+	c.coins[key] = coinID
+	c.locker.Unlock()
+
+	return coinID, nil
+}
+
+// fetch stores the prices of the window containing timestamp, it returns false if the window was fetched recently
+func (c *Client) fetch(ctx context.Context, coinID, network, contractAddress string, timestamp time.Time) (bool, error) {
+	now := time.Now()
+
+	from := timestamp.Truncate(Window)
+	if from.After(now) {
+		return false, nil
+	}
+
+	to := from.Add(Window)
+	if to.After(now) {
+		to = now
+	}
+
+	key := fmt.Sprintf("%s:%s:%s:%d", coinID, network, contractAddress, from.Unix())
+
+	c.locker.Lock()
+	fetchedAt, exists := c.windows[key]
+	c.locker.Unlock()
+
+	// A window is complete once it has been fetched after its end
+	if exists && (fetchedAt.After(from.Add(Window)) || now.Sub(fetchedAt) < refreshInterval) {
+		return false, nil
+	}
+
+	// The requests share the quota of CoinGecko, a request doesn't wait past its deadline
+	if err := c.rateLimiter.Wait(ctx); err != nil {
+		return false, err
+	}
+
+	chart, err := c.coingeckoClient.MarketChartRange(ctx, coinID, coingecko.MarketChartRangeParameter{
+		VsCurrency: currency,
+		From:       from.Unix(),
+		To:         to.Unix(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("fetch %s prices from coingecko: %w", coinID, err)
+	}
+
+	prices, err := buildPrices(network, contractAddress, chart)
+	if err != nil {
+		return false, err
+	}
+
+	if len(prices) > 0 {
+		if err := c.databaseClient.WithContext(ctx).
+			Clauses(clause.OnConflict{UpdateAll: true}).
+			CreateInBatches(prices, 500).Error; err != nil {
+			return false, fmt.Errorf("store prices: %w", err)
+		}
+	}
+
+	c.locker.Lock()
+	c.windows[key] = now
+	c.locker.Unlock()
+
+	return len(prices) > 0, nil
+}
+
+func buildPrices(network, contractAddress string, chart *coingecko.MarketChart) ([]model.Price, error) {
+	prices := make([]model.Price, 0, len(chart.Prices))
+
+	for _, point := range chart.Prices {
+		milliseconds, err := point[0].Int64()
+		if err != nil {
+			return nil, fmt.Errorf("parse timestamp %s: %w", point[0], err)
+		}
+
+		price, err := decimal.NewFromString(point[1].String())
+		if err != nil {
+			return nil, fmt.Errorf("parse price %s: %w", point[1], err)
+		}
+
+		prices = append(prices, model.Price{
+			Network:         network,
+			ContractAddress: contractAddress,
+			Timestamp:       time.UnixMilli(milliseconds),
+			Price:           price,
+			Source:          protocol.SourceCoinGecko,
+		})
+	}
+
+	// The last price of a range may be at the same time as the next one
+	return lo.UniqBy(prices, func(price model.Price) int64 {
+		return price.Timestamp.UnixMilli()
+	}), nil
+}
+
+func New(databaseClient *gorm.DB) *Client {
+	return &Client{
+		databaseClient:  databaseClient,
+		coingeckoClient: coingecko.New(),
+		// https://www.coingecko.com/en/api/documentation
+		// `Our Free API* has a rate limit of 10-50 calls/minute, and doesn't require API key.`
+		rateLimiter: rate.NewLimiter(rate.Every(time.Minute/25), 1),
+		coins:       make(map[string]string),
+		windows:     make(map[string]time.Time),
+		warmCh:      make(chan warmRequest, warmQueueSize),
+		warming:     make(map[warmRequest]struct{}),
+	}
+}
@@ -0,0 +1,171 @@
+package price
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/shopspring/decimal"
+)
+
+// Precision is the decimal places of the USD values
+const Precision = 8
+
+// PriceFunc returns the USD price of a fungible token by its contract address, it's nil if the price is unknown
+type PriceFunc func(contractAddress string) (*decimal.Decimal, error)
+
+// Lookup returns the USD price of a token on network at timestamp, it's either Client.Price which fetches
+// the missing prices or Client.Stored which doesn't wait for them
+type Lookup func(ctx context.Context, network, contractAddress string, timestamp time.Time) (*decimal.Decimal, error)
+
+// Enrich sets the USD values of the fee of transaction and the tokens in the metadata of its transfers
+// at the time of transaction with the prices of lookup, the values already set are kept
+func Enrich(ctx context.Context, transaction *model.Transaction, lookup Lookup) error {
+	if transaction.Fee != nil && transaction.FeeValueUSD == nil {
+		price, err := lookup(ctx, transaction.Network, "", transaction.Timestamp)
+		if err != nil {
+			return fmt.Errorf("get price of fee: %w", err)
+		}
+
+		if price != nil {
+			value := transaction.Fee.Mul(*price).Round(Precision)
+			transaction.FeeValueUSD = &value
+		}
+	}
+
+	for index := range transaction.Transfers {
+		transfer := &transaction.Transfers[index]
+
+		if transfer.Network == "" {
+			transfer.Network = transaction.Network
+		}
+
+		if transfer.Timestamp.IsZero() {
+			transfer.Timestamp = transaction.Timestamp
+		}
+
+		if _, err := EnrichTransfer(ctx, transfer, lookup); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnrichTransfer sets the USD values of the tokens in the metadata of transfer at its timestamp with the prices of lookup,
+// it returns whether the metadata is changed
+func EnrichTransfer(ctx context.Context, transfer *model.Transfer, lookup Lookup) (bool, error) {
+	metadata, changed, err := SetValues(transfer.Metadata, func(contractAddress string) (*decimal.Decimal, error) {
+		return lookup(ctx, transfer.Network, contractAddress, transfer.Timestamp)
+	})
+	if err != nil {
+		return false, fmt.Errorf("set values of transfer %d: %w", transfer.Index, err)
+	}
+
+	if changed {
+		transfer.Metadata = metadata
+	}
+
+	return changed, nil
+}
+
+// SetValues sets value_usd of the fungible tokens in metadata, including the nested ones such as swap legs and costs,
+// it returns whether metadata is changed
+func SetValues(metadata json.RawMessage, price PriceFunc) (json.RawMessage, bool, error) {
+	if len(metadata) == 0 {
+		return metadata, false, nil
+	}
+
+	// Numbers are kept as they are, some of them are too large for float64
+	decoder := json.NewDecoder(bytes.NewReader(metadata))
+	decoder.UseNumber()
+
+	var value any
+
+	if err := decoder.Decode(&value); err != nil {
+		return metadata, false, fmt.Errorf("decode metadata: %w", err)
+	}
+
+	changed, err := setValues(value, price)
+	if err != nil || !changed {
+		return metadata, false, err
+	}
+
+	result, err := json.Marshal(value)
+	if err != nil {
+		return metadata, false, fmt.Errorf("encode metadata: %w", err)
+	}
+
+	return result, true, nil
+}
+
+func setValues(value any, price PriceFunc) (changed bool, err error) {
+	switch value := value.(type) {
+	case map[string]any:
+		if changed, err = setValue(value, price); err != nil {
+			return false, err
+		}
+
+		for _, child := range value {
+			childChanged, err := setValues(child, price)
+			if err != nil {
+				return false, err
+			}
+
+			changed = changed || childChanged
+		}
+	case []any:
+		for _, child := range value {
+			childChanged, err := setValues(child, price)
+			if err != nil {
+				return false, err
+			}
+
+			changed = changed || childChanged
+		}
+	}
+
+	return changed, nil
+}
+
+// setValue sets value_usd of token if it's a fungible token with value_display but no value_usd
+func setValue(token map[string]any, price PriceFunc) (bool, error) {
+	standard, _ := token["standard"].(string)
+	if standard != protocol.TokenStandardNative && standard != protocol.TokenStandardERC20 {
+		return false, nil
+	}
+
+	if _, exists := token["value_usd"]; exists {
+		return false, nil
+	}
+
+	valueDisplay, exists := token["value_display"]
+	if !exists || valueDisplay == nil {
+		return false, nil
+	}
+
+	amount, err := decimal.NewFromString(fmt.Sprint(valueDisplay))
+	if err != nil {
+		return false, fmt.Errorf("parse value display %v: %w", valueDisplay, err)
+	}
+
+	contractAddress, _ := token["contract_address"].(string)
+
+	// Native tokens have no contract address
+	if standard == protocol.TokenStandardNative {
+		contractAddress = ""
+	}
+
+	tokenPrice, err := price(contractAddress)
+	if err != nil || tokenPrice == nil {
+		return false, err
+	}
+
+	token["value_usd"] = amount.Mul(*tokenPrice).Round(Precision)
+
+	return true, nil
+}
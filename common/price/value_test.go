@@ -0,0 +1,107 @@
+package price
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetValues(t *testing.T) {
+	prices := map[string]decimal.Decimal{
+		"":       decimal.NewFromInt(2000),
+		"0xusdc": decimal.NewFromInt(1),
+	}
+
+	price := func(contractAddress string) (*decimal.Decimal, error) {
+		if price, exists := prices[contractAddress]; exists {
+			return &price, nil
+		}
+
+		return nil, nil
+	}
+
+	testcases := []struct {
+		name     string
+		metadata string
+		expected string
+		changed  bool
+	}{
+		{
+			name:     "token",
+			metadata: `{"standard":"ERC-20","contract_address":"0xusdc","value":"1500000","value_display":"1.5"}`,
+			expected: `{"contract_address":"0xusdc","standard":"ERC-20","value":"1500000","value_display":"1.5","value_usd":"1.5"}`,
+			changed:  true,
+		},
+		{
+			name:     "swap",
+			metadata: `{"from":{"standard":"Native","value_display":"0.5"},"to":{"standard":"ERC-20","contract_address":"0xusdc","value_display":"1000"}}`,
+			expected: `{"from":{"standard":"Native","value_display":"0.5","value_usd":"1000"},"to":{"contract_address":"0xusdc","standard":"ERC-20","value_display":"1000","value_usd":"1000"}}`,
+			changed:  true,
+		},
+		{
+			name:     "cost",
+			metadata: `{"standard":"ERC-721","id":"1","cost":{"standard":"Native","value_display":0.01}}`,
+			expected: `{"cost":{"standard":"Native","value_display":0.01,"value_usd":"20"},"id":"1","standard":"ERC-721"}`,
+			changed:  true,
+		},
+		{
+			name:     "valued",
+			metadata: `{"standard":"Native","value_display":"1","value_usd":"1800"}`,
+			expected: `{"standard":"Native","value_display":"1","value_usd":"1800"}`,
+		},
+		{
+			name:     "unknown",
+			metadata: `{"standard":"ERC-20","contract_address":"0xunknown","value_display":"1"}`,
+			expected: `{"standard":"ERC-20","contract_address":"0xunknown","value_display":"1"}`,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			metadata, changed, err := SetValues(json.RawMessage(testcase.metadata), price)
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.changed, changed)
+			assert.JSONEq(t, testcase.expected, string(metadata))
+		})
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	var lookups []string
+
+	// Only the price of the native token is stored
+	stored := func(ctx context.Context, network, contractAddress string, timestamp time.Time) (*decimal.Decimal, error) {
+		lookups = append(lookups, network+":"+contractAddress)
+
+		if contractAddress != "" {
+			return nil, nil
+		}
+
+		price := decimal.NewFromInt(2000)
+
+		return &price, nil
+	}
+
+	fee := decimal.RequireFromString("0.001")
+
+	transaction := model.Transaction{
+		Network:   protocol.NetworkEthereum,
+		Timestamp: time.Unix(1700000000, 0),
+		Fee:       &fee,
+		Transfers: []model.Transfer{
+			{Metadata: json.RawMessage(`{"standard":"ERC-20","contract_address":"0xusdc","value_display":"1"}`)},
+		},
+	}
+
+	assert.NoError(t, Enrich(context.Background(), &transaction, stored))
+	assert.Equal(t, "2", transaction.FeeValueUSD.String())
+	assert.JSONEq(t, `{"standard":"ERC-20","contract_address":"0xusdc","value_display":"1"}`, string(transaction.Transfers[0].Metadata), "the values without prices are left to the backfill")
+	assert.Equal(t, protocol.NetworkEthereum, transaction.Transfers[0].Network)
+	assert.Equal(t, []string{"ethereum:", "ethereum:0xusdc"}, lookups)
+}
@@ -13,11 +13,22 @@ const (
 	IndexerAssetRoutingKey  = "pregod11.indexer.asset"
 	ContentTypeJSON         = "application/json"
 
+	// Messages failed more than the retry ceiling
+	IndexerDeadLetterQueue      = "pregod11.indexer.dead"
+	IndexerDeadLetterRoutingKey = "pregod11.indexer.dead"
+
 	ExchangeRefresh = "pregod11.refresh"
+	// Refresh messages shared by the hubs to deliver webhooks once
+	HubWebhookQueue = "pregod11.hub.webhook"
 
 	IndexVirtual int64 = -1
 )
 
+var ExchangeKinds = map[string]string{
+	ExchangeJob:     "direct",
+	ExchangeRefresh: "fanout",
+}
+
 var WorkQ2RoutingKey = map[string]string{
 	IndexerWorkQueue:   IndexerWorkRoutingKey,
 	IndexerWorkQueueIO: IndexerWorkRoutingKeyIO,
@@ -7,14 +7,33 @@ import (
 )
 
 type Message struct {
-	Address     string    `json:"address"`
-	Network     string    `json:"network"`
-	Timestamp   time.Time `json:"timestamp"`
-	BlockNumber int64     `json:"block_number"`
-	IgnoreNote  bool      `json:"ignore_note"`
-	Retry       int       `json:"retry"`
+	Address       string    `json:"address"`
+	Network       string    `json:"network"`
+	Timestamp     time.Time `json:"timestamp"`
+	BlockNumber   int64     `json:"block_number"`
+	BlockNumberTo int64     `json:"block_number_to,omitempty"`
+	IgnoreNote    bool      `json:"ignore_note"`
+	Refresh       bool      `json:"refresh"`
+	Retry         int       `json:"retry"`
+	// Reorg means the transactions of the address are rolled back, it's indexed again regardless of its nonce
+	Reorg bool `json:"reorg,omitempty"`
+	// Datasources are the failed datasources a retry gets the transactions from, with the Timestamp and BlockNumber
+	// of the failed attempt. It's empty to get from all the datasources.
+	Datasources []string `json:"datasources,omitempty"`
 }
 
+// DeadLetterMessage records a message which has exhausted its retries, RoutingKey is used to replay it
+type DeadLetterMessage struct {
+	Message    Message   `json:"message"`
+	RoutingKey string    `json:"routing_key"`
+	Error      string    `json:"error"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// RefreshMessage is broadcast to the hubs when the notes of an address are written,
+// Transactions are the newly indexed notes of Network with their actions
 type RefreshMessage struct {
-	Address model.Address `json:"result"`
+	Address      model.Address       `json:"result"`
+	Network      string              `json:"network,omitempty"`
+	Transactions []model.Transaction `json:"transactions,omitempty"`
 }
@@ -14,5 +14,7 @@ const (
 
 	SourceConflux = "conflux"
 
+	SourceCoinGecko = "coingecko"
+
 	DatasourceLimit = 1000
 )
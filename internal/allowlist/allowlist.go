@@ -24,6 +24,22 @@ var spamList = map[string]string{
 	"0x2953399124f0cbb46d2cbacd8a89cf0599974963": "Digi Mafia",
 }
 
+// spenderList is a list of well-known contracts which are approved to transfer tokens by their users
+var spenderList = map[string]string{
+	"0x000000000022D473030F116dDEE9F6B43aC78BA3": "Uniswap Permit2",
+	"0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D": "Uniswap V2 Router",
+	"0xE592427A0AEce92De3Edee1F18E0157C05861564": "Uniswap V3 Router",
+	"0x68b3465833fb72A70ecDF485E0e4C7bD8665Fc45": "Uniswap V3 Router 2",
+	"0xEf1c6E67703c7BD7107eed8303Fbe6EC2554BF6B": "Uniswap Universal Router",
+	"0x3fC91A3afd70395Cd496C647d5a6CC9D4B2b7FAD": "Uniswap Universal Router",
+	"0x1111111254EEB25477B68fb85Ed929f73A960582": "1inch Router V5",
+	"0xDef1C0ded9bec7F1a1670819833240f027b25EfF": "0x Exchange Proxy",
+	"0x1E0049783F008A0085193E00003D00cd54003c71": "OpenSea Conduit",
+}
+
+// riskList is a list of spenders reported to drain the tokens approved to them
+var riskList = map[string]string{}
+
 // ensSpecialList is a list of ENS names that need to be handled specially even though they are not properly registered
 var ensSpecialList = map[string]string{
 	"karl.floersch.eth": "floersch.eth",
@@ -48,6 +64,18 @@ func init() {
 		SpamList.Add(address, name)
 	}
 
+	SpenderList = New()
+
+	for address, name := range spenderList {
+		SpenderList.Add(address, name)
+	}
+
+	RiskList = New()
+
+	for address, name := range riskList {
+		RiskList.Add(address, name)
+	}
+
 	EnsSpecialList = New()
 
 	for address, name := range ensSpecialList {
@@ -64,6 +92,8 @@ func init() {
 var (
 	AllowList      *List
 	SpamList       *List
+	SpenderList    *List
+	RiskList       *List
 	EnsSpecialList *List
 	CrawlerList    *List
 )
@@ -8,6 +8,7 @@ type Config struct {
 	Mode          configx.Mode           `mapstructure:"mode"`
 	Postgres      *configx.Postgres      `mapstructure:"postgres"`
 	RabbitMQ      *configx.RabbitMQ      `mapstructure:"rabbitmq"`
+	MQ            *configx.MQ            `mapstructure:"mq"`
 	OpenTelemetry *configx.OpenTelemetry `mapstructure:"opentelemetry"`
 	Redis         *configx.Redis         `mapstructure:"redis"`
 	RPC           *configx.RPC           `mapstructure:"rpc"`
@@ -16,13 +16,13 @@ import (
 	"github.com/naturalselectionlabs/pregod/common/cache"
 	"github.com/naturalselectionlabs/pregod/common/database"
 	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/mq"
 	"github.com/naturalselectionlabs/pregod/common/protocol"
 	"github.com/naturalselectionlabs/pregod/common/utils/shedlock"
 	ens_common "github.com/naturalselectionlabs/pregod/common/worker/name_service/ens"
 	"github.com/naturalselectionlabs/pregod/service/crawler/internal/config"
 	"github.com/naturalselectionlabs/pregod/service/crawler/internal/crawler"
 	"github.com/naturalselectionlabs/pregod/service/crawler/internal/crawler/ens/contract"
-	rabbitmq "github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
 )
 
@@ -36,22 +36,22 @@ var (
 )
 
 type service struct {
-	config          *config.Config
-	ethClient       *ethclient.Client
-	abiClient       abi.ABI
-	rabbitmqChannel *rabbitmq.Channel
-	employer        *shedlock.Employer
+	config    *config.Config
+	ethClient *ethclient.Client
+	abiClient abi.ABI
+	queue     mq.Queue
+	employer  *shedlock.Employer
 }
 
 func New(
-	rabbitmqChannel *rabbitmq.Channel,
+	queue mq.Queue,
 	employer *shedlock.Employer,
 	config *config.Config,
 ) crawler.Crawler {
 	crawler := &service{
-		rabbitmqChannel: rabbitmqChannel,
-		employer:        employer,
-		config:          config,
+		queue:    queue,
+		employer: employer,
+		config:   config,
 	}
 
 	var err error
@@ -184,10 +184,7 @@ func (s *service) createRabbitmqJob(address string) error {
 			return err
 		}
 
-		if err := s.rabbitmqChannel.PublishWithContext(context.TODO(), protocol.ExchangeJob, protocol.IndexerWorkRoutingKey, false, false, rabbitmq.Publishing{
-			ContentType: protocol.ContentTypeJSON,
-			Body:        messageData,
-		}); err != nil {
+		if err := s.queue.Publish(context.TODO(), protocol.ExchangeJob, protocol.IndexerWorkRoutingKey, messageData); err != nil {
 			return err
 		}
 	}
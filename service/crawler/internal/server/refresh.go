@@ -0,0 +1,24 @@
+package server
+
+import (
+	"context"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/mq"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+// publishRefresh broadcasts the notes created by crawlers to the hubs by owner, failures are only logged
+func (s *Server) publishRefresh(ctx context.Context, transactions []model.Transaction) {
+	owners := lo.GroupBy(transactions, func(transaction model.Transaction) string {
+		return transaction.Owner
+	})
+
+	for owner, ownerTransactions := range owners {
+		if err := mq.PublishRefresh(ctx, s.queue, owner, "", ownerTransactions); err != nil {
+			loggerx.Global().Error("failed to publish refresh message", zap.Error(err), zap.String("address", owner))
+		}
+	}
+}
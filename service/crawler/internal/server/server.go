@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,6 +12,7 @@ import (
 	"github.com/naturalselectionlabs/pregod/common/database"
 	"github.com/naturalselectionlabs/pregod/common/ethclientx"
 	"github.com/naturalselectionlabs/pregod/common/metadata_url"
+	"github.com/naturalselectionlabs/pregod/common/mq"
 	"github.com/naturalselectionlabs/pregod/common/protocol"
 	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
 	"github.com/naturalselectionlabs/pregod/common/utils/opentelemetry"
@@ -32,7 +34,6 @@ import (
 	"github.com/naturalselectionlabs/pregod/service/crawler/internal/crawler/rara"
 	"github.com/naturalselectionlabs/pregod/service/crawler/internal/crawler/sound"
 	"github.com/naturalselectionlabs/pregod/service/crawler/internal/crawler/zora"
-	rabbitmq "github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
 
 	"go.opentelemetry.io/otel"
@@ -44,12 +45,10 @@ import (
 var _ command.Interface = &Server{}
 
 type Server struct {
-	config             *config.Config
-	rabbitmqConnection *rabbitmq.Connection
-	rabbitmqChannel    *rabbitmq.Channel
-	rabbitmqQueue      rabbitmq.Queue
-	crawlers           []crawler.Crawler
-	employer           *shedlock.Employer
+	config   *config.Config
+	queue    mq.Queue
+	crawlers []crawler.Crawler
+	employer *shedlock.Employer
 }
 
 func (s *Server) Initialize() (err error) {
@@ -113,33 +112,21 @@ func (s *Server) Initialize() (err error) {
 
 	metadata_url.New(s.config.RPC.IPFS.Internal)
 
-	s.rabbitmqConnection, err = rabbitmq.Dial(s.config.RabbitMQ.String())
-	if err != nil {
-		return err
-	}
-
-	s.rabbitmqChannel, err = s.rabbitmqConnection.Channel()
-	if err != nil {
+	if s.queue, err = mq.Dial(s.config.MQ, s.config.RabbitMQ); err != nil {
 		return err
 	}
 
-	if err := s.rabbitmqChannel.ExchangeDeclare(
-		protocol.ExchangeJob, "direct", true, false, false, false, nil,
-	); err != nil {
+	// Keep the jobs until the indexer is consuming
+	if err := s.queue.Declare(context.Background(), mq.Binding{
+		Exchange:   protocol.ExchangeJob,
+		RoutingKey: protocol.IndexerWorkRoutingKey,
+		Queue:      protocol.IndexerWorkQueue,
+	}); err != nil {
 		return err
 	}
 
-	if s.rabbitmqQueue, err = s.rabbitmqChannel.QueueDeclare(
-		protocol.IndexerWorkQueue, false, false, false, false, nil,
-	); err != nil {
-		return err
-	}
-
-	if err := s.rabbitmqChannel.QueueBind(
-		s.rabbitmqQueue.Name, protocol.IndexerWorkRoutingKey, protocol.ExchangeJob, false, nil,
-	); err != nil {
-		return err
-	}
+	// Notify the hubs of the notes written by crawlers
+	database.ReplaceUpsertHook(s.publishRefresh)
 
 	s.employer = shedlock.New()
 
@@ -154,7 +141,7 @@ func (s *Server) Initialize() (err error) {
 	}
 
 	s.crawlers = []crawler.Crawler{
-		ens.New(s.rabbitmqChannel, s.employer, s.config),
+		ens.New(s.queue, s.employer, s.config),
 		lens,
 		mirror.New(s.config),
 		eip1577.New(s.config, s.employer),
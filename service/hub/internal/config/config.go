@@ -20,6 +20,7 @@ type Config struct {
 	Mode          configx.Mode           `json:"mode"`
 	HTTP          *configx.HTTP          `mapstructure:"http"`
 	RabbitMQ      *configx.RabbitMQ      `mapstructure:"rabbitmq"`
+	MQ            *configx.MQ            `mapstructure:"mq"`
 	OpenTelemetry *configx.OpenTelemetry `mapstructure:"opentelemetry"`
 	Postgres      *configx.Postgres      `mapstructure:"postgres"`
 	EthereumEtl   *configx.PostgresEtl   `mapstructure:"ethereumetl"`
@@ -28,6 +29,8 @@ type Config struct {
 	RPC           *configx.RPC           `mapstructure:"rpc"`
 	Kurora        *configx.Kurora        `mapstructure:"kurora"`
 	Mastodon      *configx.Mastodon      `mapstructure:"mastodon"`
+	Webhook       *configx.Webhook       `mapstructure:"webhook"`
+	APIKey        *configx.APIKey        `mapstructure:"apikey"`
 }
 
 var ConfigHub Config
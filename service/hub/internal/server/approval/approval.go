@@ -0,0 +1,196 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/database/model/metadata"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/naturalselectionlabs/pregod/internal/allowlist"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// RiskUnlimited is an ERC-20 allowance no less than UnlimitedAllowance, or an approval for all the NFTs of a collection
+	RiskUnlimited = "unlimited"
+	// RiskUnverifiedSpender is a spender which isn't a well-known contract
+	RiskUnverifiedSpender = "unverified_spender"
+	// RiskListedSpender is a spender on the risk list
+	RiskListedSpender = "risk_list"
+	// RiskEOASpender is a spender without code, approving an account is a common way of phishing
+	RiskEOASpender = "eoa_spender"
+
+	LevelHigh   = "high"
+	LevelMedium = "medium"
+	LevelLow    = "low"
+)
+
+// UnlimitedAllowance is the least allowance taken as unlimited, the wallets and Permit2 approve the max of uint256 or uint160
+var UnlimitedAllowance = new(big.Int).Lsh(big.NewInt(1), 128)
+
+// Approval is a live approval of a token from an owner to a spender
+type Approval struct {
+	Network         string `json:"network"`
+	Standard        string `json:"standard"`
+	ContractAddress string `json:"contract_address"`
+	// ID is the NFT approved, it's empty for the ERC-20 allowances and the approvals for all
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals uint8  `json:"decimals,omitempty"`
+
+	Spender     string `json:"spender"`
+	SpenderName string `json:"spender_name,omitempty"`
+	// SpenderContract is nil if the code of the spender isn't verified on chain
+	SpenderContract *bool `json:"spender_contract,omitempty"`
+
+	// Value and Allowance are the ERC-20 amount the spender can transfer, in the smallest and the display unit
+	Value     *decimal.Decimal `json:"value,omitempty"`
+	Allowance *decimal.Decimal `json:"allowance,omitempty"`
+	// All means the spender can transfer all the NFTs of the collection
+	All       bool `json:"all,omitempty"`
+	Unlimited bool `json:"unlimited"`
+
+	Risks []string `json:"risks"`
+	Level string   `json:"level"`
+
+	// TransactionHash and Timestamp are of the latest approval
+	TransactionHash string    `json:"transaction_hash"`
+	Timestamp       time.Time `json:"timestamp"`
+
+	// Verified means the approval is confirmed on chain, Error is why it can't be
+	Verified bool   `json:"verified"`
+	Error    string `json:"error,omitempty"`
+}
+
+type key struct {
+	network         string
+	contractAddress string
+	id              string
+	spender         string
+}
+
+// Fold replays the approval transfers of owner from the oldest, it returns the approvals which aren't revoked.
+// An NFT has a single approved spender, so a later approval of it replaces the earlier one.
+func Fold(owner string, transfers []dbModel.Transfer) ([]Approval, error) {
+	approvals := make(map[key]Approval)
+
+	for _, transfer := range transfers {
+		if transfer.Type != filter.TransactionApproval || !strings.EqualFold(transfer.AddressFrom, owner) {
+			continue
+		}
+
+		var token metadata.Token
+
+		if err := json.Unmarshal(transfer.Metadata, &token); err != nil {
+			return nil, fmt.Errorf("unmarshal metadata of transfer %s %d: %w", transfer.TransactionHash, transfer.Index, err)
+		}
+
+		approval := Approval{
+			Network:         transfer.Network,
+			Standard:        token.Standard,
+			ContractAddress: strings.ToLower(token.ContractAddress),
+			Name:            token.Name,
+			Symbol:          token.Symbol,
+			Decimals:        token.Decimals,
+			Spender:         strings.ToLower(transfer.AddressTo),
+			TransactionHash: transfer.TransactionHash,
+			Timestamp:       transfer.Timestamp,
+		}
+
+		// The name of an item isn't the name of its collection
+		if token.Collection != "" {
+			approval.Name = token.Collection
+		}
+
+		approvalKey := key{network: approval.Network, contractAddress: approval.ContractAddress, spender: approval.Spender}
+
+		switch {
+		case approval.Standard == protocol.TokenStandardERC20:
+			approval.Value = token.Value
+			approval.Allowance = token.ValueDisplay
+		case token.ID != "":
+			approval.ID = token.ID
+			approvalKey.id, approvalKey.spender = token.ID, ""
+		default:
+			approval.All = true
+		}
+
+		if token.Action == filter.ActionRevoke || approval.Spender == strings.ToLower(common.Address{}.String()) {
+			delete(approvals, approvalKey)
+
+			continue
+		}
+
+		approvals[approvalKey] = approval
+	}
+
+	result := make([]Approval, 0, len(approvals))
+
+	for _, approval := range approvals {
+		result = append(result, approval)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+
+		switch {
+		case a.Network != b.Network:
+			return a.Network < b.Network
+		case a.ContractAddress != b.ContractAddress:
+			return a.ContractAddress < b.ContractAddress
+		case a.Spender != b.Spender:
+			return a.Spender < b.Spender
+		default:
+			return a.ID < b.ID
+		}
+	})
+
+	return result, nil
+}
+
+// Assess sets the risks and the risk level of approval.
+// A spender on the risk list or without code is of high risk, and an unverified spender approved without limit is of medium risk.
+func Assess(approval *Approval) {
+	approval.Risks = make([]string, 0)
+	approval.Unlimited = approval.All || (approval.Value != nil && approval.Value.BigInt().Cmp(UnlimitedAllowance) >= 0)
+
+	if approval.Unlimited {
+		approval.Risks = append(approval.Risks, RiskUnlimited)
+	}
+
+	switch {
+	case allowlist.SpenderList.Contains(approval.Spender):
+		approval.SpenderName = allowlist.SpenderList.Get(approval.Spender)
+	case allowlist.AllowList.Contains(approval.Spender):
+		approval.SpenderName = allowlist.AllowList.Get(approval.Spender)
+	default:
+		approval.Risks = append(approval.Risks, RiskUnverifiedSpender)
+	}
+
+	listed := allowlist.RiskList.Contains(approval.Spender)
+	if listed {
+		approval.Risks = append(approval.Risks, RiskListedSpender)
+	}
+
+	eoa := approval.SpenderContract != nil && !*approval.SpenderContract
+	if eoa {
+		approval.Risks = append(approval.Risks, RiskEOASpender)
+	}
+
+	switch {
+	case listed || eoa:
+		approval.Level = LevelHigh
+	case approval.Unlimited && approval.SpenderName == "":
+		approval.Level = LevelMedium
+	default:
+		approval.Level = LevelLow
+	}
+}
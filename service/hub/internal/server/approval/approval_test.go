@@ -0,0 +1,103 @@
+package approval
+
+import (
+	"testing"
+	"time"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	permit2 = "0x000000000022d473030f116ddee9f6b43ac78ba3"
+
+	maxUint256 = "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+)
+
+func approval(tag, spender string, timestamp int64, metadata string) dbModel.Transfer {
+	transfer := testutil.Transfer(tag, filter.TransactionApproval, testutil.Alice, spender, metadata)
+	transfer.Timestamp = time.Unix(timestamp, 0)
+
+	return transfer
+}
+
+func TestFold(t *testing.T) {
+	approvals, err := Fold(testutil.Alice, []dbModel.Transfer{
+		approval(filter.TagTransaction, permit2, 100, `{"action":"approve","symbol":"USDC","standard":"ERC-20","contract_address":"`+testutil.USDC+`","decimals":6,"value":"`+maxUint256+`"}`),
+		approval(filter.TagTransaction, testutil.Bob, 100, `{"action":"approve","symbol":"USDC","standard":"ERC-20","contract_address":"`+testutil.USDC+`","decimals":6,"value":"1000000","value_display":"1"}`),
+		approval(filter.TagCollectible, testutil.Bob, 100, `{"action":"approve","collection":"Punks","standard":"ERC-721","contract_address":"`+testutil.NFT+`"}`),
+		// The allowance of bob is revoked and the approval for all is renewed
+		approval(filter.TagTransaction, testutil.Bob, 200, `{"action":"revoke","standard":"ERC-20","contract_address":"`+testutil.USDC+`","value":"0"}`),
+		approval(filter.TagCollectible, testutil.Bob, 300, `{"action":"approve","collection":"Punks","standard":"ERC-721","contract_address":"`+testutil.NFT+`"}`),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, approvals, 2)
+
+	assert.True(t, approvals[0].All)
+	assert.Equal(t, "Punks", approvals[0].Name)
+	assert.Equal(t, time.Unix(300, 0), approvals[0].Timestamp)
+
+	assert.Equal(t, permit2, approvals[1].Spender)
+	assert.Equal(t, maxUint256, approvals[1].Value.String())
+
+	approvals, err = Fold(testutil.Alice, []dbModel.Transfer{
+		approval(filter.TagCollectible, testutil.Bob, 100, `{"action":"approve","standard":"ERC-721","contract_address":"`+testutil.NFT+`","id":"7"}`),
+		approval(filter.TagCollectible, permit2, 200, `{"action":"approve","standard":"ERC-721","contract_address":"`+testutil.NFT+`","id":"7"}`),
+	})
+	assert.NoError(t, err)
+
+	// An NFT has a single approved spender
+	assert.Len(t, approvals, 1)
+	assert.Equal(t, permit2, approvals[0].Spender)
+}
+
+func TestAssess(t *testing.T) {
+	var (
+		unlimited = decimal.RequireFromString(maxUint256)
+		limited   = decimal.NewFromInt(1000000)
+		yes, no   = true, false
+	)
+
+	testcases := []struct {
+		name     string
+		approval Approval
+		risks    []string
+		level    string
+	}{
+		{
+			name:     "known spender",
+			approval: Approval{Spender: permit2, Value: &unlimited, SpenderContract: &yes},
+			risks:    []string{RiskUnlimited},
+			level:    LevelLow,
+		},
+		{
+			name:     "unknown spender",
+			approval: Approval{Spender: testutil.Bob, All: true},
+			risks:    []string{RiskUnlimited, RiskUnverifiedSpender},
+			level:    LevelMedium,
+		},
+		{
+			name:     "limited",
+			approval: Approval{Spender: testutil.Bob, Value: &limited, SpenderContract: &yes},
+			risks:    []string{RiskUnverifiedSpender},
+			level:    LevelLow,
+		},
+		{
+			name:     "account",
+			approval: Approval{Spender: testutil.Bob, Value: &limited, SpenderContract: &no},
+			risks:    []string{RiskUnverifiedSpender, RiskEOASpender},
+			level:    LevelHigh,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			Assess(&testcase.approval)
+			assert.Equal(t, testcase.risks, testcase.approval.Risks)
+			assert.Equal(t, testcase.level, testcase.approval.Level)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/shopspring/decimal"
+)
+
+// The generated bindings have no approval getters, isApprovedForAll is the same for ERC-721 and ERC-1155
+var approvalABI, _ = abi.JSON(strings.NewReader(`[
+	{"inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"name":"allowance","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"name":"owner","type":"address"},{"name":"operator","type":"address"}],"name":"isApprovedForAll","outputs":[{"name":"","type":"bool"}],"stateMutability":"view","type":"function"},
+	{"inputs":[{"name":"tokenId","type":"uint256"}],"name":"getApproved","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"}
+]`))
+
+// Verify checks the approvals of owner on chain, it returns the approvals which are still live with the current allowances.
+// The approvals which can't be checked, such as the ones of the contracts not following the standards, are kept unverified.
+func Verify(ctx context.Context, client bind.ContractCaller, owner string, approvals []Approval) []Approval {
+	var (
+		result    = make([]Approval, 0, len(approvals))
+		contracts = make(map[string]*bool)
+	)
+
+	for _, approval := range approvals {
+		contract, exists := contracts[approval.Spender]
+		if !exists {
+			if code, err := client.CodeAt(ctx, common.HexToAddress(approval.Spender), nil); err == nil {
+				isContract := len(code) > 0
+				contract = &isContract
+			}
+
+			contracts[approval.Spender] = contract
+		}
+
+		approval.SpenderContract = contract
+
+		live, err := verify(ctx, client, owner, &approval)
+		if err != nil {
+			approval.Error = err.Error()
+		} else if !live {
+			// It's revoked or spent outside the indexed transactions
+			continue
+		} else {
+			approval.Verified = true
+		}
+
+		result = append(result, approval)
+	}
+
+	return result
+}
+
+// verify reports whether approval is live on chain, it updates the allowance of the ERC-20 tokens
+func verify(ctx context.Context, client bind.ContractCaller, owner string, approval *Approval) (bool, error) {
+	var (
+		contract = bind.NewBoundContract(common.HexToAddress(approval.ContractAddress), approvalABI, client, nil, nil)
+		opts     = &bind.CallOpts{Context: ctx}
+		outputs  []any
+	)
+
+	switch {
+	case approval.Standard == protocol.TokenStandardERC20:
+		if err := contract.Call(opts, &outputs, "allowance", common.HexToAddress(owner), common.HexToAddress(approval.Spender)); err != nil {
+			return false, err
+		}
+
+		allowance := *abi.ConvertType(outputs[0], new(*big.Int)).(**big.Int)
+		if allowance.Sign() == 0 {
+			return false, nil
+		}
+
+		value := decimal.NewFromBigInt(allowance, 0)
+		display := decimal.NewFromBigInt(allowance, -int32(approval.Decimals))
+
+		approval.Value, approval.Allowance = &value, &display
+
+		return true, nil
+	case approval.All:
+		if err := contract.Call(opts, &outputs, "isApprovedForAll", common.HexToAddress(owner), common.HexToAddress(approval.Spender)); err != nil {
+			return false, err
+		}
+
+		return *abi.ConvertType(outputs[0], new(bool)).(*bool), nil
+	default:
+		id, ok := new(big.Int).SetString(approval.ID, 10)
+		if !ok {
+			return false, fmt.Errorf("invalid token id %s", approval.ID)
+		}
+
+		if err := contract.Call(opts, &outputs, "getApproved", id); err != nil {
+			return false, err
+		}
+
+		spender := *abi.ConvertType(outputs[0], new(common.Address)).(*common.Address)
+
+		return strings.EqualFold(spender.String(), approval.Spender), nil
+	}
+}
@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"context"
+	"strings"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"go.opentelemetry.io/otel"
+)
+
+// GetAPIKeys get the API keys of address, the keys applied before the addresses were lowercased are matched too
+func GetAPIKeys(ctx context.Context, address string) ([]dbModel.APIKey, error) {
+	tracer := otel.Tracer("getAPIKeys")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	apiKeys := make([]dbModel.APIKey, 0)
+
+	if err := database.Global().WithContext(ctx).
+		Where("LOWER(address) = ?", strings.ToLower(address)).
+		Order("created_at DESC").
+		Find(&apiKeys).Error; err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func CreateAPIKey(ctx context.Context, apiKey *dbModel.APIKey) error {
+	tracer := otel.Tracer("createAPIKey")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	return database.Global().WithContext(ctx).Create(apiKey).Error
+}
+
+// UpdateAPIKey sets the key and the status of an API key
+func UpdateAPIKey(ctx context.Context, apiKey *dbModel.APIKey) error {
+	tracer := otel.Tracer("updateAPIKey")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	return database.Global().WithContext(ctx).
+		Model(apiKey).
+		Select("uuid", "status", "updated_at").
+		Updates(apiKey).Error
+}
@@ -0,0 +1,49 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
+	"go.opentelemetry.io/otel"
+)
+
+// GetExportTransactions returns a page of the transactions of the addresses after cursor, from the oldest.
+// The failed transactions are included for their fees.
+func GetExportTransactions(ctx context.Context, request model.ExportNotesRequest, cursor *pagination.Cursor, limit int) ([]dbModel.Transaction, error) {
+	tracer := otel.Tracer("getExportTransactions")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	transactions := make([]dbModel.Transaction, 0)
+
+	sql := database.Global().
+		WithContext(ctx).
+		Model(&dbModel.Transaction{}).
+		Where("owner IN ?", request.Address)
+
+	if len(request.Network) > 0 {
+		sql = sql.Where("network IN ?", request.Network)
+	}
+
+	if !request.Since.IsZero() {
+		sql = sql.Where("timestamp >= ?", request.Since)
+	}
+
+	if !request.Until.IsZero() {
+		sql = sql.Where("timestamp < ?", request.Until)
+	}
+
+	if cursor != nil {
+		sql = sql.Where(pagination.After, cursor.Values()...)
+	}
+
+	if err := sql.Limit(limit).Order(pagination.OrderAscending).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
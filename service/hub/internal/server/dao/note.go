@@ -9,36 +9,37 @@ import (
 	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
 	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
 	"go.opentelemetry.io/otel"
 )
 
 // getTransactions get transaction data from database
-func GetTransactions(ctx context.Context, request model.GetRequest) ([]dbModel.Transaction, int64, error) {
+func GetTransactions(ctx context.Context, request model.GetRequest) ([]dbModel.Transaction, model.Pagination, error) {
 	tracer := otel.Tracer("getTransactions")
 	_, postgresSnap := tracer.Start(ctx, "postgres")
 
 	defer postgresSnap.End()
 
-	transactions := make([]dbModel.Transaction, 0)
-	total := int64(0)
+	var (
+		owners []string
+		cursor *pagination.Cursor
+		err    error
+	)
+
 	sql := database.Global().
 		WithContext(ctx).
 		Model(&dbModel.Transaction{}).
 		Where("success IS TRUE") // Hide failed transactions
 
 	if len(request.TokenId) == 0 {
+		owners = []string{request.Address}
 		sql.Where("owner = ?", request.Address) // address was already converted to lowercase
 	}
 
 	if len(request.Cursor) > 0 {
-		var lastItem dbModel.Transaction
-
-		// no need to lowercase
-		if err := database.Global().Where("hash = ?", request.Cursor).First(&lastItem).Error; err != nil {
-			return nil, 0, err
+		if cursor, err = parseCursor(ctx, database.Global(), request.Cursor, owners); err != nil {
+			return nil, model.Pagination{}, err
 		}
-
-		sql = sql.Where("timestamp < ? OR (timestamp = ? AND index < ?)", lastItem.Timestamp, lastItem.Timestamp, lastItem.Index)
 	}
 
 	if len(request.Hash) > 0 {
@@ -80,21 +81,22 @@ func GetTransactions(ctx context.Context, request model.GetRequest) ([]dbModel.T
 		sql = sql.Where("hash IN?", request.HashList)
 	}
 
-	if err := sql.Count(&total).Limit(request.Limit).Offset(request.Page * request.Limit).Order("timestamp DESC, index DESC").Find(&transactions).Error; err != nil {
-		return nil, 0, err
+	if request.FinalizedOnly {
+		sql = sql.Where("finality = ?", dbModel.FinalityFinalized)
 	}
 
-	return transactions, total, nil
+	return paginate(sql, cursor, request.Limit, request.WithTotal || request.CountOnly)
 }
 
 // getTransactions get transaction data from database
-func GetTransactionsByPlatform(ctx context.Context, request model.GetNotesByPlatformRequest) ([]dbModel.Transaction, error) {
+func GetTransactionsByPlatform(ctx context.Context, request model.GetNotesByPlatformRequest) ([]dbModel.Transaction, model.Pagination, error) {
 	tracer := otel.Tracer("getTransactions")
 	_, postgresSnap := tracer.Start(ctx, "postgres")
 
 	defer postgresSnap.End()
 
-	transactions := make([]dbModel.Transaction, 0)
+	var cursor *pagination.Cursor
+
 	sql := database.Global().
 		WithContext(ctx).
 		Model(&dbModel.Transaction{}).
@@ -102,31 +104,23 @@ func GetTransactionsByPlatform(ctx context.Context, request model.GetNotesByPlat
 		Where("platform = ?", request.Platform)
 
 	if len(request.Cursor) > 0 {
-		var lastItem dbModel.Transaction
+		var err error
 
-		// no need to lowercase
-		if err := database.Global().Where("hash = ?", request.Cursor).First(&lastItem).Error; err != nil {
-			return nil, err
+		if cursor, err = parseCursor(ctx, database.Global(), request.Cursor, nil); err != nil {
+			return nil, model.Pagination{}, err
 		}
-
-		sql = sql.Where("timestamp < ? OR (timestamp = ? AND index < ?)", lastItem.Timestamp, lastItem.Timestamp, lastItem.Index)
 	}
 
-	if err := sql.Limit(request.Limit).Order("timestamp DESC, index DESC").Find(&transactions).Error; err != nil {
-		return nil, err
-	}
-
-	return transactions, nil
+	return paginate(sql, cursor, request.Limit, request.WithTotal)
 }
 
-func BatchGetTransactions(ctx context.Context, request model.BatchGetNotesRequest) ([]dbModel.Transaction, int64, error) {
+func BatchGetTransactions(ctx context.Context, request model.BatchGetNotesRequest) ([]dbModel.Transaction, model.Pagination, error) {
 	tracer := otel.Tracer("batchGetTransactions")
 	_, postgresSnap := tracer.Start(ctx, "postgres")
 
 	defer postgresSnap.End()
 
-	transactions := make([]dbModel.Transaction, 0)
-	total := int64(0)
+	var cursor *pagination.Cursor
 
 	sql := database.Global().
 		WithContext(ctx).
@@ -135,14 +129,11 @@ func BatchGetTransactions(ctx context.Context, request model.BatchGetNotesReques
 		Where("success IS TRUE") // Hide failed transactions
 
 	if len(request.Cursor) > 0 {
-		var lastItem dbModel.Transaction
+		var err error
 
-		// no need to lowercase
-		if err := database.Global().Where("hash = ?", request.Cursor).First(&lastItem).Error; err != nil {
-			return nil, 0, err
+		if cursor, err = parseCursor(ctx, database.Global(), request.Cursor, request.Address); err != nil {
+			return nil, model.Pagination{}, err
 		}
-
-		sql = sql.Where("timestamp < ? OR (timestamp = ? AND index < ?)", lastItem.Timestamp, lastItem.Timestamp, lastItem.Index)
 	}
 
 	if len(request.Tag) > 0 {
@@ -176,11 +167,55 @@ func BatchGetTransactions(ctx context.Context, request model.BatchGetNotesReques
 		sql = sql.Where("timestamp > ?", request.Timestamp)
 	}
 
-	if err := sql.Count(&total).Limit(request.Limit).Offset(request.Page * request.Limit).Order("timestamp DESC, index DESC").Find(&transactions).Error; err != nil {
-		return nil, 0, err
+	return paginate(sql, cursor, request.Limit, request.WithTotal || request.CountOnly)
+}
+
+// GetTransactionsAfter get the transactions newer than the cursor from the oldest, it's used to resume subscriptions
+func GetTransactionsAfter(ctx context.Context, request model.BatchGetNotesRequest) ([]dbModel.Transaction, error) {
+	tracer := otel.Tracer("getTransactionsAfter")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	transactions := make([]dbModel.Transaction, 0)
+
+	cursor, err := parseCursor(ctx, database.Global(), request.Cursor, request.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	sql := database.Global().
+		WithContext(ctx).
+		Model(&dbModel.Transaction{}).
+		Where("owner IN ?", request.Address).
+		Where("success IS TRUE"). // Hide failed transactions
+		Where(pagination.After, cursor.Values()...)
+
+	if len(request.Tag) > 0 {
+		sql = sql.Where("tag IN ?", request.Tag)
+	}
+
+	if len(request.Type) > 0 {
+		sql = sql.Where("\"type\" IN ?", request.Type)
+	}
+
+	if !request.IncludePoap {
+		sql = sql.Where("\"type\" != ?", filter.CollectiblePoap)
 	}
 
-	return transactions, total, nil
+	if len(request.Network) > 0 {
+		sql = sql.Where("LOWER(network) IN ?", request.Network)
+	}
+
+	if len(request.Platform) > 0 {
+		sql = sql.Where("LOWER(platform) IN ?", request.Platform)
+	}
+
+	if err := sql.Limit(request.Limit).Order(pagination.OrderAscending).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
 }
 
 // getTransfers get transfer data from database
@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
+	"gorm.io/gorm"
+)
+
+// parseCursor decodes a cursor, the transaction hashes which were the cursors before are looked up among owners
+func parseCursor(ctx context.Context, db *gorm.DB, value string, owners []string) (*pagination.Cursor, error) {
+	if cursor, err := pagination.Parse(value); err == nil {
+		return cursor, nil
+	}
+
+	var lastItem dbModel.Transaction
+
+	sql := db.WithContext(ctx).Where("hash = ?", value)
+
+	if len(owners) > 0 {
+		sql = sql.Where("owner IN ?", owners)
+	}
+
+	if err := sql.Order(pagination.Order).First(&lastItem).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pagination.ErrInvalidCursor
+		}
+
+		return nil, err
+	}
+
+	cursor := pagination.New(lastItem)
+
+	return &cursor, nil
+}
+
+// paginate queries the page of sql after cursor in pagination.Order, the total of sql is only counted if withTotal
+func paginate(sql *gorm.DB, cursor *pagination.Cursor, limit int, withTotal bool) ([]dbModel.Transaction, model.Pagination, error) {
+	var page model.Pagination
+
+	sql = sql.Session(&gorm.Session{})
+
+	if withTotal {
+		var total int64
+
+		if err := sql.Count(&total).Error; err != nil {
+			return nil, page, err
+		}
+
+		page.Total = &total
+	}
+
+	if cursor != nil {
+		sql = sql.Where(pagination.Before, cursor.Values()...)
+	}
+
+	// One more transaction tells whether there is a next page
+	transactions := make([]dbModel.Transaction, 0, limit+1)
+
+	if err := sql.Order(pagination.Order).Limit(limit + 1).Find(&transactions).Error; err != nil {
+		return nil, page, err
+	}
+
+	transactions, page.Cursor = pagination.Next(transactions, limit)
+
+	return transactions, page, nil
+}
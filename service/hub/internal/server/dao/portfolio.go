@@ -0,0 +1,93 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"go.opentelemetry.io/otel"
+)
+
+// GetTokenTransfers returns the transfers sent or received by address which may move tokens, from the oldest
+func GetTokenTransfers(ctx context.Context, address string, networks []string) ([]dbModel.Transfer, error) {
+	tracer := otel.Tracer("getTokenTransfers")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	transfers := make([]dbModel.Transfer, 0)
+
+	sql := database.Global().
+		WithContext(ctx).
+		Select("transaction_hash", "timestamp", "tag", "type", "index", "address_from", "address_to", "metadata", "network", "platform").
+		Where("address_from = ? OR address_to = ?", address, address).
+		Where("tag <> ?", filter.TagSocial).
+		Where("type <> ?", filter.TransactionApproval)
+
+	if len(networks) > 0 {
+		sql = sql.Where("network IN ?", networks)
+	}
+
+	if err := sql.Order("timestamp, transaction_hash, index").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// GetFeeTransactions returns the transactions whose fees are paid by address, from the oldest.
+// A transaction is stored for each of its owners, so it's only returned once.
+func GetFeeTransactions(ctx context.Context, address string, networks []string) ([]dbModel.Transaction, error) {
+	tracer := otel.Tracer("getFeeTransactions")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	transactions := make([]dbModel.Transaction, 0)
+
+	sql := database.Global().
+		WithContext(ctx).
+		Model(&dbModel.Transaction{}).
+		Select("DISTINCT ON (hash, network) hash, network, timestamp, fee, fee_value_usd").
+		Where("address_from = ?", address).
+		Where("fee IS NOT NULL")
+
+	if len(networks) > 0 {
+		sql = sql.Where("network IN ?", networks)
+	}
+
+	if err := sql.Order("hash, network").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// GetApprovalTransfers returns the approvals of tokens by address, from the oldest
+func GetApprovalTransfers(ctx context.Context, address string, networks []string) ([]dbModel.Transfer, error) {
+	tracer := otel.Tracer("getApprovalTransfers")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	transfers := make([]dbModel.Transfer, 0)
+
+	// The approvals of ERC-20 and NFTs have the same type
+	sql := database.Global().
+		WithContext(ctx).
+		Select("transaction_hash", "timestamp", "tag", "type", "index", "address_from", "address_to", "metadata", "network").
+		Where("address_from = ?", address).
+		Where("tag IN ?", []string{filter.TagTransaction, filter.TagCollectible}).
+		Where("type = ?", filter.TransactionApproval)
+
+	if len(networks) > 0 {
+		sql = sql.Where("network IN ?", networks)
+	}
+
+	if err := sql.Order("timestamp, transaction_hash, index").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
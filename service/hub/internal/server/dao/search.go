@@ -0,0 +1,135 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+)
+
+const (
+	// searchConfig doesn't stem the words, since the posts are in many languages
+	searchConfig = "'simple'"
+	// searchContent is the text of the posts highlighted in snippets
+	searchContent   = "concat_ws(' ', metadata->>'title', metadata->>'summary', metadata->>'body')"
+	searchHeadlines = "MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" ... \""
+)
+
+type searchResult struct {
+	dbModel.Transfer
+
+	Rank    float32 `gorm:"column:rank"`
+	Snippet string  `gorm:"column:snippet"`
+}
+
+// SearchSocialTransfers searches the posts of social transfers by the full-text document of them,
+// the transfers are ranked by relevance, with the snippets of the matched content
+func SearchSocialTransfers(ctx context.Context, request model.SearchNotesRequest) ([]model.SearchNoteResult, model.Pagination, error) {
+	tracer := otel.Tracer("searchSocialTransfers")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	var (
+		page   model.Pagination
+		cursor *pagination.SearchCursor
+		err    error
+	)
+
+	if len(request.Cursor) > 0 {
+		if cursor, err = pagination.ParseSearch(request.Cursor); err != nil {
+			return nil, page, err
+		}
+	}
+
+	sql := database.Social().
+		WithContext(ctx).
+		Table("transfers, websearch_to_tsquery("+searchConfig+", ?) AS query", request.Keyword).
+		Where("tag = ?", filter.TagSocial).
+		Where(dbModel.TransferDocument + " @@ query")
+
+	if len(request.Address) > 0 {
+		// address was already converted to lowercase
+		sql = sql.Where("address_from IN ?", request.Address)
+	}
+
+	if len(request.Network) > 0 {
+		sql = sql.Where("network IN ?", request.Network)
+	}
+
+	if len(request.Platform) > 0 {
+		sql = sql.Where("LOWER(platform) IN ?", request.Platform)
+	}
+
+	if !request.Since.IsZero() {
+		sql = sql.Where("timestamp >= ?", request.Since)
+	}
+
+	if !request.Until.IsZero() {
+		sql = sql.Where("timestamp < ?", request.Until)
+	}
+
+	sql = sql.Session(&gorm.Session{})
+
+	if request.WithTotal {
+		var total int64
+
+		if err := sql.Count(&total).Error; err != nil {
+			return nil, page, err
+		}
+
+		page.Total = &total
+	}
+
+	ranked := sql.Select("transfers.*, ts_rank_cd(" + dbModel.TransferDocument + ", query, 1) AS rank, query")
+
+	// The snippets are only made of the transfers in the page
+	result := database.Social().
+		WithContext(ctx).
+		Table("(?) AS result", ranked).
+		Select("result.*, ts_headline("+searchConfig+", "+searchContent+", query, ?) AS snippet", searchHeadlines)
+
+	if cursor != nil {
+		result = result.Where(pagination.SearchBefore, cursor.Values()...)
+	}
+
+	// One more transfer tells whether there is a next page
+	internalResults := make([]searchResult, 0, request.Limit+1)
+
+	if err := result.Order(pagination.SearchOrder).Limit(request.Limit + 1).Find(&internalResults).Error; err != nil {
+		return nil, page, err
+	}
+
+	if len(internalResults) > request.Limit {
+		internalResults = internalResults[:request.Limit]
+		last := internalResults[len(internalResults)-1]
+
+		page.Cursor = pagination.SearchCursor{
+			Rank:      last.Rank,
+			Timestamp: last.Timestamp,
+			Hash:      last.TransactionHash,
+			Network:   last.Network,
+			Index:     last.Index,
+		}.String()
+	}
+
+	results := make([]model.SearchNoteResult, 0, len(internalResults))
+
+	for _, internalResult := range internalResults {
+		results = append(results, model.SearchNoteResult{
+			Hash:      internalResult.TransactionHash,
+			Network:   internalResult.Network,
+			Timestamp: internalResult.Timestamp,
+			Rank:      internalResult.Rank,
+			Snippet:   internalResult.Snippet,
+			Action:    internalResult.Transfer,
+		})
+	}
+
+	return results, page, nil
+}
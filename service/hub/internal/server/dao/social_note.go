@@ -8,17 +8,17 @@ import (
 	"github.com/naturalselectionlabs/pregod/common/database"
 	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
 	"go.opentelemetry.io/otel"
 )
 
-func BatchGetSocialTransactions(ctx context.Context, request model.BatchGetSocialNotesRequest) ([]dbModel.Transaction, int64, error) {
+func BatchGetSocialTransactions(ctx context.Context, request model.BatchGetSocialNotesRequest) ([]dbModel.Transaction, model.Pagination, error) {
 	tracer := otel.Tracer("batchGetSocialTransactions")
 	_, postgresSnap := tracer.Start(ctx, "postgres")
 
 	defer postgresSnap.End()
 
-	transactions := make([]dbModel.Transaction, 0)
-	total := int64(0)
+	var cursor *pagination.Cursor
 
 	sql := database.Social().
 		Model(&dbModel.Transaction{}).
@@ -26,14 +26,11 @@ func BatchGetSocialTransactions(ctx context.Context, request model.BatchGetSocia
 		Where("success IS TRUE") // Hide failed transactions
 
 	if len(request.Cursor) > 0 {
-		var lastItem dbModel.Transaction
+		var err error
 
-		// no need to lowercase
-		if err := database.Social().Where("hash = ?", request.Cursor).First(&lastItem).Error; err != nil {
-			return nil, 0, err
+		if cursor, err = parseCursor(ctx, database.Social(), request.Cursor, request.Address); err != nil {
+			return nil, model.Pagination{}, err
 		}
-
-		sql = sql.Where("timestamp < ? OR (timestamp = ? AND index < ?)", lastItem.Timestamp, lastItem.Timestamp, lastItem.Index)
 	}
 
 	if len(request.Tag) > 0 && len(request.Type) > 0 {
@@ -60,11 +57,7 @@ func BatchGetSocialTransactions(ctx context.Context, request model.BatchGetSocia
 		sql = sql.Where("timestamp > ?", request.Timestamp)
 	}
 
-	if err := sql.Count(&total).Limit(request.Limit).Offset(request.Page * request.Limit).Order("timestamp DESC, index DESC").Find(&transactions).Error; err != nil {
-		return nil, 0, err
-	}
-
-	return transactions, total, nil
+	return paginate(sql, cursor, request.Limit, request.WithTotal || request.CountOnly)
 }
 
 func GetSocialTransfers(c context.Context, transactionHashes []string) ([]dbModel.Transfer, error) {
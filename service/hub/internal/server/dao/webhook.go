@@ -0,0 +1,103 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"go.opentelemetry.io/otel"
+)
+
+func CreateWebhook(ctx context.Context, webhook *dbModel.Webhook) error {
+	tracer := otel.Tracer("createWebhook")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	return database.Global().WithContext(ctx).Create(webhook).Error
+}
+
+// GetWebhooks get the webhooks registered by owner from the latest
+func GetWebhooks(ctx context.Context, owner string) ([]dbModel.Webhook, error) {
+	tracer := otel.Tracer("getWebhooks")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	webhooks := make([]dbModel.Webhook, 0)
+
+	if err := database.Global().WithContext(ctx).
+		Where("owner = ?", owner).
+		Order("created_at DESC").
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func GetWebhook(ctx context.Context, owner, id string) (dbModel.Webhook, error) {
+	tracer := otel.Tracer("getWebhook")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	var webhook dbModel.Webhook
+
+	if err := database.Global().WithContext(ctx).
+		Where("owner = ? AND id = ?", owner, id).
+		First(&webhook).Error; err != nil {
+		return dbModel.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook deletes a webhook of owner, its deliveries are kept in the log
+func DeleteWebhook(ctx context.Context, owner, id string) (int64, error) {
+	tracer := otel.Tracer("deleteWebhook")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	result := database.Global().WithContext(ctx).
+		Where("owner = ? AND id = ?", owner, id).
+		Delete(&dbModel.Webhook{})
+
+	return result.RowsAffected, result.Error
+}
+
+// GetWebhookDeliveries get the deliveries of a webhook from the latest
+func GetWebhookDeliveries(ctx context.Context, request model.GetWebhookDeliveriesRequest) ([]dbModel.WebhookDelivery, error) {
+	tracer := otel.Tracer("getWebhookDeliveries")
+	_, postgresSnap := tracer.Start(ctx, "postgres")
+
+	defer postgresSnap.End()
+
+	deliveries := make([]dbModel.WebhookDelivery, 0)
+
+	sql := database.Global().WithContext(ctx).
+		Model(&dbModel.WebhookDelivery{}).
+		Where("webhook_id = ?", request.ID)
+
+	if len(request.Cursor) > 0 {
+		var lastItem dbModel.WebhookDelivery
+
+		if err := database.Global().WithContext(ctx).Where("id = ?", request.Cursor).First(&lastItem).Error; err != nil {
+			return nil, err
+		}
+
+		sql = sql.Where("created_at < ? OR (created_at = ? AND id < ?)", lastItem.CreatedAt, lastItem.CreatedAt, lastItem.ID)
+	}
+
+	if len(request.Status) > 0 {
+		sql = sql.Where("status = ?", request.Status)
+	}
+
+	if err := sql.Limit(request.Limit).Order("created_at DESC, id DESC").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
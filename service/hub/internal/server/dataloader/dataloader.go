@@ -0,0 +1,191 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWait     = 2 * time.Millisecond
+	DefaultMaxBatch = 500
+)
+
+// BatchFunc loads the values of keys at once, the keys missing from the result have the zero value
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Errors fails the keys in it only, a batch function returns it when some of the keys can't be loaded,
+// and the other keys resolve to their values
+type Errors[K comparable] map[K]error
+
+func (e Errors[K]) Error() string {
+	messages := make([]string, 0, len(e))
+
+	for key, err := range e {
+		messages = append(messages, fmt.Sprintf("%v: %v", key, err))
+	}
+
+	sort.Strings(messages)
+
+	return strings.Join(messages, "; ")
+}
+
+// Loader collects the keys loaded within a short wait into one call of its batch function,
+// and caches the results, so it's created for each request rather than shared
+type Loader[K comparable, V any] struct {
+	batch    BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	locker  sync.Mutex
+	cache   map[K]*result[V]
+	pending *pendingBatch[K, V]
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type pendingBatch[K comparable, V any] struct {
+	keys    []K
+	results []*result[V]
+}
+
+// Load returns the value of key, it blocks until the batch of key is loaded or the context is done
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.locker.Lock()
+
+	r, exists := l.cache[key]
+	if !exists {
+		r = &result[V]{
+			done: make(chan struct{}),
+		}
+
+		l.cache[key] = r
+		l.enqueue(ctx, key, r)
+	}
+
+	l.locker.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany returns the values of keys in the same order
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, error) {
+	var (
+		wg       sync.WaitGroup
+		values   = make([]V, len(keys))
+		errs     = make([]error, len(keys))
+		firstErr error
+	)
+
+	for index, key := range keys {
+		wg.Add(1)
+
+		go func(index int, key K) {
+			defer wg.Done()
+
+			values[index], errs[index] = l.Load(ctx, key)
+		}(index, key)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			firstErr = err
+
+			break
+		}
+	}
+
+	return values, firstErr
+}
+
+// enqueue adds key to the pending batch, the batch is dispatched when it's full or the wait is over.
+// The lock must be held.
+func (l *Loader[K, V]) enqueue(ctx context.Context, key K, r *result[V]) {
+	if l.pending == nil {
+		batch := &pendingBatch[K, V]{}
+		l.pending = batch
+
+		time.AfterFunc(l.wait, func() {
+			l.locker.Lock()
+
+			// The batch has been dispatched because it was full
+			if l.pending != batch {
+				l.locker.Unlock()
+
+				return
+			}
+
+			l.pending = nil
+			l.locker.Unlock()
+
+			l.dispatch(ctx, batch)
+		})
+	}
+
+	l.pending.keys = append(l.pending.keys, key)
+	l.pending.results = append(l.pending.results, r)
+
+	if len(l.pending.keys) >= l.maxBatch {
+		batch := l.pending
+		l.pending = nil
+
+		go l.dispatch(ctx, batch)
+	}
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context, batch *pendingBatch[K, V]) {
+	values, err := l.batch(ctx, batch.keys)
+
+	var keyErrors Errors[K]
+	if errors.As(err, &keyErrors) {
+		err = nil
+	}
+
+	for index, key := range batch.keys {
+		r := batch.results[index]
+
+		if keyErr, failed := keyErrors[key]; failed {
+			r.err = keyErr
+		} else if err != nil {
+			r.err = err
+		} else {
+			r.value = values[key]
+		}
+
+		close(r.done)
+	}
+}
+
+func New[K comparable, V any](batch BatchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	if wait <= 0 {
+		wait = DefaultWait
+	}
+
+	if maxBatch <= 0 {
+		maxBatch = DefaultMaxBatch
+	}
+
+	return &Loader[K, V]{
+		batch:    batch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    make(map[K]*result[V]),
+	}
+}
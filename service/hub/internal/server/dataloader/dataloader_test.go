@@ -0,0 +1,130 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader(t *testing.T) {
+	var (
+		locker  sync.Mutex
+		batches [][]string
+	)
+
+	loader := New(func(ctx context.Context, keys []string) (map[string]int, error) {
+		locker.Lock()
+		defer locker.Unlock()
+
+		sorted := append([]string(nil), keys...)
+		sort.Strings(sorted)
+		batches = append(batches, sorted)
+
+		values := make(map[string]int, len(keys))
+
+		for _, key := range keys {
+			// A missing key has the zero value
+			if key != "missing" {
+				values[key] = len(key)
+			}
+		}
+
+		return values, nil
+	}, 10*time.Millisecond, 0)
+
+	ctx := context.Background()
+
+	values, err := loader.LoadMany(ctx, []string{"a", "bb", "a", "ccc", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 1, 3, 0}, values)
+	assert.Equal(t, [][]string{{"a", "bb", "ccc", "missing"}}, batches, "one batch without duplicates")
+
+	// The loaded keys are cached
+	value, err := loader.Load(ctx, "bb")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, value)
+	assert.Len(t, batches, 1)
+}
+
+func TestLoaderMaxBatch(t *testing.T) {
+	var (
+		locker sync.Mutex
+		sizes  []int
+	)
+
+	loader := New(func(ctx context.Context, keys []int) (map[int]int, error) {
+		locker.Lock()
+		defer locker.Unlock()
+
+		sizes = append(sizes, len(keys))
+
+		values := make(map[int]int, len(keys))
+
+		for _, key := range keys {
+			values[key] = key * 2
+		}
+
+		return values, nil
+	}, 10*time.Millisecond, 3)
+
+	values, err := loader.LoadMany(context.Background(), []int{1, 2, 3, 4, 5, 6, 7})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 4, 6, 8, 10, 12, 14}, values)
+
+	sort.Ints(sizes)
+	assert.Equal(t, []int{1, 3, 3}, sizes)
+}
+
+func TestLoaderError(t *testing.T) {
+	loader := New(func(ctx context.Context, keys []string) (map[string]string, error) {
+		return nil, errors.New("unavailable")
+	}, time.Millisecond, 0)
+
+	_, err := loader.LoadMany(context.Background(), []string{"a", "b"})
+	assert.EqualError(t, err, "unavailable")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	blocked := New(func(ctx context.Context, keys []string) (map[string]string, error) {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}, time.Millisecond, 0)
+
+	_, err = blocked.Load(ctx, "a")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLoaderKeyErrors(t *testing.T) {
+	loader := New(func(ctx context.Context, keys []string) (map[string]int, error) {
+		values := make(map[string]int, len(keys))
+		errs := make(Errors[string])
+
+		for _, key := range keys {
+			if key == "broken" {
+				errs[key] = errors.New("unavailable")
+
+				continue
+			}
+
+			values[key] = len(key)
+		}
+
+		return values, errs
+	}, 10*time.Millisecond, 0)
+
+	ctx := context.Background()
+
+	values, err := loader.LoadMany(ctx, []string{"a", "broken", "ccc"})
+	assert.EqualError(t, err, "unavailable")
+	assert.Equal(t, []int{1, 0, 3}, values, "the other keys are loaded")
+
+	_, err = loader.Load(ctx, "broken")
+	assert.EqualError(t, err, "unavailable")
+}
@@ -0,0 +1,391 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/portfolio"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	FormatCSV         = "csv"
+	FormatKoinly      = "koinly"
+	FormatCoinTracker = "cointracker"
+
+	currencyUSD = "USD"
+)
+
+// Amount is a leg of a row, the currencies of NFTs are their symbols with the token IDs
+type Amount struct {
+	Value           decimal.Decimal
+	Currency        string
+	ContractAddress string
+	ValueUSD        *decimal.Decimal
+}
+
+// Row is a movement of the history of an address, a transfer sending and receiving several tokens is split into rows
+type Row struct {
+	Address         string
+	Network         string
+	TransactionHash string
+	Index           int64
+	Timestamp       time.Time
+	Tag             string
+	Type            string
+	Action          string
+	Platform        string
+
+	Sent     *Amount
+	Received *Amount
+	Fee      *Amount
+}
+
+// NativeSymbolFunc returns the symbol of the native token of a network
+type NativeSymbolFunc func(network string) string
+
+// Rows returns the rows of transaction from the view of address, the fee is in the first row if address paid it.
+// A failed transaction only has its fee.
+func Rows(address string, transaction dbModel.Transaction, nativeSymbol NativeSymbolFunc) ([]Row, error) {
+	rows := make([]Row, 0, len(transaction.Transfers))
+
+	var transfers []dbModel.Transfer
+
+	if transaction.Success == nil || *transaction.Success {
+		transfers = append(transfers, transaction.Transfers...)
+	}
+
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].Index < transfers[j].Index
+	})
+
+	for _, transfer := range transfers {
+		movements, err := portfolio.Movements(address, transfer)
+		if err != nil {
+			return nil, err
+		}
+
+		var sent, received []portfolio.Movement
+
+		for _, movement := range movements {
+			if movement.Amount.IsNegative() {
+				sent = append(sent, movement)
+			} else {
+				received = append(received, movement)
+			}
+		}
+
+		var action struct {
+			Action string `json:"action"`
+		}
+
+		// Only the staking and liquidity transfers have actions
+		_ = json.Unmarshal(transfer.Metadata, &action)
+
+		for index := 0; index < len(sent) || index < len(received); index++ {
+			row := Row{
+				Address:         address,
+				Network:         transfer.Network,
+				TransactionHash: transaction.Hash,
+				Index:           transfer.Index,
+				Timestamp:       transaction.Timestamp,
+				Tag:             transfer.Tag,
+				Type:            transfer.Type,
+				Action:          action.Action,
+				Platform:        transfer.Platform,
+			}
+
+			if index < len(sent) {
+				row.Sent = newAmount(sent[index], nativeSymbol)
+			}
+
+			if index < len(received) {
+				row.Received = newAmount(received[index], nativeSymbol)
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	fee := portfolio.FeeMovement(transaction)
+	if fee == nil || !strings.EqualFold(transaction.AddressFrom, address) {
+		return rows, nil
+	}
+
+	if len(rows) == 0 {
+		rows = append(rows, Row{
+			Address:         address,
+			Network:         transaction.Network,
+			TransactionHash: transaction.Hash,
+			Timestamp:       transaction.Timestamp,
+			Tag:             transaction.Tag,
+			Type:            transaction.Type,
+			Platform:        transaction.Platform,
+		})
+	}
+
+	rows[0].Fee = newAmount(*fee, nativeSymbol)
+
+	return rows, nil
+}
+
+func newAmount(movement portfolio.Movement, nativeSymbol NativeSymbolFunc) *Amount {
+	amount := Amount{
+		Value:           movement.Amount.Abs(),
+		Currency:        movement.Symbol,
+		ContractAddress: movement.ContractAddress,
+		ValueUSD:        movement.ValueUSD,
+	}
+
+	switch {
+	case movement.Standard == protocol.TokenStandardNative && amount.Currency == "":
+		amount.Currency = nativeSymbol(movement.Network)
+	case amount.Currency == "":
+		amount.Currency = movement.ContractAddress
+	}
+
+	if !movement.Fungible() && movement.ID != "" {
+		amount.Currency = fmt.Sprintf("%s #%s", amount.Currency, movement.ID)
+	}
+
+	return &amount
+}
+
+// Writer writes the rows in a format, the header is written on creation
+type Writer struct {
+	format string
+	writer io.Writer
+	csv    *csv.Writer
+}
+
+func (w *Writer) Write(row Row) error {
+	var record []string
+
+	switch w.format {
+	case FormatKoinly:
+		record = koinlyRecord(row)
+	case FormatCoinTracker:
+		record = coinTrackerRecord(row)
+	default:
+		record = csvRecord(row)
+	}
+
+	return w.csv.Write(record)
+}
+
+// Flush writes the buffered rows to the underlying writer, and sends them to the client if it is an HTTP response
+func (w *Writer) Flush() error {
+	w.csv.Flush()
+
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+
+	if flusher, ok := w.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+// ContentType and Filename are the HTTP headers of the file downloaded
+func ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func Filename(format string, now time.Time) string {
+	return fmt.Sprintf("rss3-%s-%s.csv", format, now.Format("20060102150405"))
+}
+
+var headers = map[string][]string{
+	FormatCSV: {
+		"timestamp", "address", "network", "transaction_hash", "index", "tag", "type", "platform",
+		"sent_amount", "sent_currency", "sent_contract_address", "sent_value_usd",
+		"received_amount", "received_currency", "received_contract_address", "received_value_usd",
+		"fee_amount", "fee_currency", "fee_value_usd",
+	},
+	// https://support.koinly.io/en/articles/9489976-how-to-create-a-custom-csv-file-with-your-data
+	FormatKoinly: {
+		"Date", "Sent Amount", "Sent Currency", "Received Amount", "Received Currency", "Fee Amount", "Fee Currency",
+		"Net Worth Amount", "Net Worth Currency", "Label", "Description", "TxHash",
+	},
+	// https://support.cointracker.io/hc/en-us/articles/4413071299729-Convert-your-transaction-history-to-CoinTracker-CSV
+	FormatCoinTracker: {
+		"Date", "Received Quantity", "Received Currency", "Sent Quantity", "Sent Currency", "Fee Amount", "Fee Currency", "Tag",
+	},
+}
+
+func csvRecord(row Row) []string {
+	record := []string{
+		row.Timestamp.UTC().Format(time.RFC3339), row.Address, row.Network, row.TransactionHash,
+		strconv.FormatInt(row.Index, 10), row.Tag, row.Type, row.Platform,
+	}
+
+	for _, amount := range []*Amount{row.Sent, row.Received} {
+		if amount == nil {
+			record = append(record, "", "", "", "")
+
+			continue
+		}
+
+		record = append(record, amount.Value.String(), amount.Currency, amount.ContractAddress, formatValue(amount.ValueUSD))
+	}
+
+	if row.Fee == nil {
+		return append(record, "", "", "")
+	}
+
+	return append(record, row.Fee.Value.String(), row.Fee.Currency, formatValue(row.Fee.ValueUSD))
+}
+
+func koinlyRecord(row Row) []string {
+	sent, received, fee := row.Sent, row.Received, row.Fee
+	label := KoinlyLabel(row)
+
+	// A fee without transfers is a cost paid
+	if sent == nil && received == nil && fee != nil {
+		sent, fee = fee, nil
+	}
+
+	var netWorth *decimal.Decimal
+
+	for _, amount := range []*Amount{received, sent} {
+		if amount != nil && amount.ValueUSD != nil {
+			netWorth = amount.ValueUSD
+
+			break
+		}
+	}
+
+	record := []string{row.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC")}
+	record = append(record, amountColumns(sent)...)
+	record = append(record, amountColumns(received)...)
+	record = append(record, amountColumns(fee)...)
+
+	netWorthCurrency := ""
+	if netWorth != nil {
+		netWorthCurrency = currencyUSD
+	}
+
+	return append(record, formatValue(netWorth), netWorthCurrency, label, description(row), row.TransactionHash)
+}
+
+func coinTrackerRecord(row Row) []string {
+	record := []string{row.Timestamp.UTC().Format("01/02/2006 15:04:05")}
+	record = append(record, amountColumns(row.Received)...)
+	record = append(record, amountColumns(row.Sent)...)
+	record = append(record, amountColumns(row.Fee)...)
+
+	return append(record, CoinTrackerTag(row))
+}
+
+// KoinlyLabel maps the tag and type of a row to the labels of Koinly, the trades and transfers have no labels
+func KoinlyLabel(row Row) string {
+	switch {
+	case row.Sent == nil && row.Received == nil:
+		return "cost"
+	case row.Tag == filter.TagExchange && row.Type == filter.ExchangeLiquidity:
+		if row.Sent != nil {
+			return "liquidity in"
+		}
+
+		return "liquidity out"
+	case row.Tag == filter.TagExchange && row.Type == filter.ExchangeStaking:
+		switch row.Action {
+		case filter.ActionStakingStake:
+			return "stake"
+		case filter.ActionStakingUnstake:
+			return "unstake"
+		case filter.ActionStakingClaim:
+			return "reward"
+		}
+	case row.Tag == filter.TagDonation:
+		return "donation"
+	case isBurn(row) && row.Received == nil:
+		return "lost"
+	case isAirdrop(row):
+		return "airdrop"
+	}
+
+	return ""
+}
+
+// CoinTrackerTag maps the tag and type of a row to the tags of CoinTracker, the trades and transfers have no tags
+func CoinTrackerTag(row Row) string {
+	switch {
+	case row.Tag == filter.TagExchange && row.Type == filter.ExchangeStaking && row.Action == filter.ActionStakingClaim:
+		return "staked"
+	case row.Tag == filter.TagDonation && row.Sent != nil:
+		return "donation"
+	case isBurn(row) && row.Received == nil:
+		return "lost"
+	case isAirdrop(row):
+		return "airdrop"
+	}
+
+	return ""
+}
+
+func isBurn(row Row) bool {
+	return row.Type == filter.TransactionBurn || row.Type == filter.CollectibleBurn
+}
+
+// isAirdrop reports whether the row receives a token minted for free, such as a POAP
+func isAirdrop(row Row) bool {
+	return row.Sent == nil && row.Received != nil &&
+		(row.Type == filter.TransactionMint || row.Type == filter.CollectibleMint || row.Type == filter.CollectiblePoap)
+}
+
+func description(row Row) string {
+	if row.Platform == "" {
+		return fmt.Sprintf("%s %s on %s", row.Tag, row.Type, row.Network)
+	}
+
+	return fmt.Sprintf("%s %s on %s via %s", row.Tag, row.Type, row.Network, row.Platform)
+}
+
+func amountColumns(amount *Amount) []string {
+	if amount == nil {
+		return []string{"", ""}
+	}
+
+	return []string{amount.Value.String(), amount.Currency}
+}
+
+func formatValue(value *decimal.Decimal) string {
+	if value == nil {
+		return ""
+	}
+
+	return value.StringFixed(2)
+}
+
+// NewWriter returns a Writer of format, which is one of csv, koinly and cointracker
+func NewWriter(format string, writer io.Writer) (*Writer, error) {
+	header, exists := headers[format]
+	if !exists {
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+
+	result := Writer{
+		format: format,
+		writer: writer,
+		csv:    csv.NewWriter(writer),
+	}
+
+	if err := result.csv.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func nativeSymbol(network string) string {
+	return map[string]string{protocol.NetworkEthereum: "ETH"}[network]
+}
+
+func records(t *testing.T, format string, rows []Row) [][]string {
+	var buffer bytes.Buffer
+
+	writer, err := NewWriter(format, &buffer)
+	assert.NoError(t, err)
+
+	for _, row := range rows {
+		assert.NoError(t, writer.Write(row))
+	}
+
+	assert.NoError(t, writer.Flush())
+
+	result, err := csv.NewReader(&buffer).ReadAll()
+	assert.NoError(t, err)
+
+	return result
+}
+
+func TestRows(t *testing.T) {
+	swap := testutil.Transaction(true, testutil.Transfer(filter.TagExchange, filter.ExchangeSwap, testutil.Alice, testutil.Alice, `{"from":{"symbol":"USDC","standard":"ERC-20","contract_address":"`+testutil.USDC+`","value_display":"100","value_usd":"100"},"to":{"standard":"Native","value_display":"0.05","value_usd":"99.5"}}`))
+
+	rows, err := Rows(testutil.Alice, swap, nativeSymbol)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "100", rows[0].Sent.Value.String())
+	assert.Equal(t, "USDC", rows[0].Sent.Currency)
+	assert.Equal(t, "0.05", rows[0].Received.Value.String())
+	assert.Equal(t, "ETH", rows[0].Received.Currency)
+	assert.Equal(t, "0.002", rows[0].Fee.Value.String())
+
+	// The recipient doesn't pay the fee
+	receive := testutil.Transaction(true, testutil.Transfer(filter.TagCollectible, filter.CollectibleTrade, testutil.Alice, testutil.Bob, `{"symbol":"PUNK","standard":"ERC-721","contract_address":"0x01","id":"7","cost":{"standard":"Native","value_display":"1"}}`))
+
+	rows, err = Rows(testutil.Bob, receive, nativeSymbol)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "PUNK #7", rows[0].Received.Currency)
+	assert.Equal(t, "ETH", rows[0].Sent.Currency)
+	assert.Nil(t, rows[0].Fee)
+
+	// A failed transaction only costs the fee
+	failed := testutil.Transaction(false, testutil.Transfer(filter.TagTransaction, filter.TransactionTransfer, testutil.Alice, testutil.Bob, `{"standard":"Native","value_display":"1"}`))
+
+	rows, err = Rows(testutil.Alice, failed, nativeSymbol)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Nil(t, rows[0].Sent)
+	assert.Equal(t, "ETH", rows[0].Fee.Currency)
+}
+
+func TestWriter(t *testing.T) {
+	value := decimal.RequireFromString("12.345")
+
+	rows := []Row{
+		{
+			Timestamp: testutil.Timestamp,
+			Tag:       filter.TagExchange,
+			Type:      filter.ExchangeStaking,
+			Action:    filter.ActionStakingClaim,
+			Network:   protocol.NetworkEthereum,
+			Received:  &Amount{Value: decimal.NewFromInt(3), Currency: "RSS3", ValueUSD: &value},
+		},
+		{
+			Timestamp: testutil.Timestamp,
+			Tag:       filter.TagTransaction,
+			Type:      filter.TransactionTransfer,
+			Network:   protocol.NetworkEthereum,
+			Fee:       &Amount{Value: decimal.RequireFromString("0.002"), Currency: "ETH"},
+		},
+	}
+
+	koinly := records(t, FormatKoinly, rows)
+	assert.Len(t, koinly, 3)
+	assert.Equal(t, []string{"2023-11-14 22:13:20 UTC", "", "", "3", "RSS3", "", "", "12.35", "USD", "reward", "exchange staking on ethereum", ""}, koinly[1])
+	// The fee is sent as a cost
+	assert.Equal(t, []string{"0.002", "ETH", "", "", "", ""}, koinly[2][1:7])
+	assert.Equal(t, "cost", koinly[2][9])
+
+	coinTracker := records(t, FormatCoinTracker, rows)
+	assert.Equal(t, []string{"11/14/2023 22:13:20", "3", "RSS3", "", "", "", "", "staked"}, coinTracker[1])
+	assert.Equal(t, []string{"11/14/2023 22:13:20", "", "", "", "", "0.002", "ETH", ""}, coinTracker[2])
+
+	generic := records(t, FormatCSV, rows)
+	assert.Len(t, generic[0], len(generic[1]))
+	assert.Equal(t, "12.35", generic[1][15])
+
+	_, err := NewWriter("unknown", &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestLabels(t *testing.T) {
+	amount := &Amount{Value: decimal.NewFromInt(1), Currency: "ETH"}
+
+	testcases := []struct {
+		name        string
+		row         Row
+		koinly      string
+		coinTracker string
+	}{
+		{name: "trade", row: Row{Tag: filter.TagExchange, Type: filter.ExchangeSwap, Sent: amount, Received: amount}},
+		{name: "liquidity add", row: Row{Tag: filter.TagExchange, Type: filter.ExchangeLiquidity, Sent: amount}, koinly: "liquidity in"},
+		{name: "liquidity remove", row: Row{Tag: filter.TagExchange, Type: filter.ExchangeLiquidity, Received: amount}, koinly: "liquidity out"},
+		{name: "stake", row: Row{Tag: filter.TagExchange, Type: filter.ExchangeStaking, Action: filter.ActionStakingStake, Sent: amount}, koinly: "stake"},
+		{name: "donation", row: Row{Tag: filter.TagDonation, Type: filter.DonationDonate, Sent: amount}, koinly: "donation", coinTracker: "donation"},
+		{name: "mint", row: Row{Tag: filter.TagCollectible, Type: filter.CollectibleMint, Received: amount}, koinly: "airdrop", coinTracker: "airdrop"},
+		{name: "paid mint", row: Row{Tag: filter.TagCollectible, Type: filter.CollectibleMint, Sent: amount, Received: amount}},
+		{name: "burn", row: Row{Tag: filter.TagTransaction, Type: filter.TransactionBurn, Sent: amount}, koinly: "lost", coinTracker: "lost"},
+		{name: "bridge", row: Row{Tag: filter.TagTransaction, Type: filter.TransactionBridge, Sent: amount}},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			assert.Equal(t, testcase.koinly, KoinlyLabel(testcase.row))
+			assert.Equal(t, testcase.coinTracker, CoinTrackerTag(testcase.row))
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/database/model/social"
+	"github.com/naturalselectionlabs/pregod/common/worker/name_service"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/service"
+	"github.com/samber/lo"
+)
+
+var ErrInvalidAddress = errors.New("invalid address")
+
+type queryResolver struct {
+	service *service.Service
+}
+
+func (r *queryResolver) Account(ctx context.Context, args struct{ Address string }) (*accountResolver, error) {
+	return r.account(ctx, args.Address)
+}
+
+func (r *queryResolver) Accounts(ctx context.Context, args struct{ Address []string }) ([]*accountResolver, error) {
+	if len(args.Address) > MaxAccounts {
+		return nil, fmt.Errorf("at most %d accounts are queried at once", MaxAccounts)
+	}
+
+	accounts := make([]*accountResolver, 0, len(args.Address))
+
+	for _, address := range args.Address {
+		account, err := r.account(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+// account resolves the address of a name
+func (r *queryResolver) account(ctx context.Context, input string) (*accountResolver, error) {
+	result := name_service.ReverseResolveAll(ctx, strings.ToLower(input), false)
+	if len(result.Address) == 0 {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		return nil, ErrInvalidAddress
+	}
+
+	return &accountResolver{
+		service: r.service,
+		address: strings.ToLower(result.Address),
+	}, nil
+}
+
+type accountResolver struct {
+	service *service.Service
+	address string
+}
+
+func (r *accountResolver) Address() string {
+	return r.address
+}
+
+func (r *accountResolver) NS(ctx context.Context) *nameServiceResolver {
+	return &nameServiceResolver{result: name_service.ReverseResolveAll(ctx, r.address, true)}
+}
+
+func (r *accountResolver) Profiles(ctx context.Context) ([]*profileResolver, error) {
+	profiles, err := loadersFrom(ctx).profiles.Load(ctx, r.address)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(profiles, func(profile *social.Profile, _ int) *profileResolver {
+		return &profileResolver{profile: profile}
+	}), nil
+}
+
+type notesArgs struct {
+	Limit         *int32
+	Cursor        *string
+	Tag           *[]string
+	Type          *[]string
+	Network       *[]string
+	Platform      *[]string
+	IncludePoap   *bool
+	FinalizedOnly *bool
+	WithTotal     *bool
+}
+
+func (r *accountResolver) Notes(ctx context.Context, args notesArgs) (*noteConnectionResolver, error) {
+	request := model.GetRequest{
+		Address:       r.address,
+		Limit:         limit(args.Limit),
+		Cursor:        lo.FromPtr(args.Cursor),
+		Tag:           lo.FromPtr(args.Tag),
+		Type:          lo.FromPtr(args.Type),
+		Network:       lo.FromPtr(args.Network),
+		Platform:      lo.FromPtr(args.Platform),
+		IncludePoap:   lo.FromPtr(args.IncludePoap),
+		FinalizedOnly: lo.FromPtr(args.FinalizedOnly),
+		WithTotal:     lo.FromPtr(args.WithTotal),
+	}
+
+	if len(request.Type) > 0 && len(request.Tag) == 0 {
+		return nil, errors.New("type requires tag")
+	}
+
+	transactions, page, err := r.service.ListNotes(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := noteConnectionResolver{
+		total: page.Total,
+		list: lo.Map(transactions, func(transaction dbModel.Transaction, _ int) *noteResolver {
+			return &noteResolver{transaction: transaction}
+		}),
+	}
+
+	if len(page.Cursor) > 0 {
+		connection.cursor = &page.Cursor
+	}
+
+	return &connection, nil
+}
+
+type assetsArgs struct {
+	Limit   *int32
+	Cursor  *string
+	Network *[]string
+}
+
+func (r *accountResolver) Assets(ctx context.Context, args assetsArgs) (*assetConnectionResolver, error) {
+	request := model.GetAssetRequest{
+		Address: r.address,
+		Limit:   limit(args.Limit),
+		Cursor:  lo.FromPtr(args.Cursor),
+		Network: lo.FromPtr(args.Network),
+	}
+
+	assets, total, err := r.service.GetAssets(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := assetConnectionResolver{
+		total: total,
+		list: lo.Map(assets, func(asset dbModel.Asset, _ int) *assetResolver {
+			return &assetResolver{asset: asset}
+		}),
+	}
+
+	if total > int64(request.Limit) && len(assets) > 0 {
+		last := assets[len(assets)-1]
+		cursor := fmt.Sprintf("%v:%v:%v", last.Network, last.TokenAddress, last.TokenID)
+		connection.cursor = &cursor
+	}
+
+	return &connection, nil
+}
+
+type nameServiceResolver struct {
+	result dbModel.NameServiceResult
+}
+
+func (r *nameServiceResolver) Address() string            { return r.result.Address }
+func (r *nameServiceResolver) ENS() string                { return r.result.ENS }
+func (r *nameServiceResolver) Crossbell() string          { return r.result.Crossbell }
+func (r *nameServiceResolver) Lens() string               { return r.result.Lens }
+func (r *nameServiceResolver) SpaceID() string            { return r.result.SpaceID }
+func (r *nameServiceResolver) UnstoppableDomains() string { return r.result.UnstoppableDomains }
+func (r *nameServiceResolver) Bit() string                { return r.result.Bit }
+func (r *nameServiceResolver) Avvy() string               { return r.result.Avvy }
+func (r *nameServiceResolver) Arb() string                { return r.result.Arb }
+func (r *nameServiceResolver) Cyber() string              { return r.result.Cyber }
+
+type profileResolver struct {
+	profile *social.Profile
+}
+
+func (r *profileResolver) Address() string  { return r.profile.Address }
+func (r *profileResolver) Network() string  { return r.profile.Network }
+func (r *profileResolver) Platform() string { return r.profile.Platform }
+func (r *profileResolver) Source() string   { return r.profile.Source }
+func (r *profileResolver) Name() string     { return r.profile.Name }
+func (r *profileResolver) Handle() string   { return r.profile.Handle }
+func (r *profileResolver) Bio() string      { return r.profile.Bio }
+func (r *profileResolver) URL() string      { return r.profile.URL }
+func (r *profileResolver) ProfileURI() []string {
+	return nonNil(r.profile.ProfileUris)
+}
+func (r *profileResolver) BannerURI() []string {
+	return nonNil(r.profile.BannerUris)
+}
+func (r *profileResolver) SocialURI() []string {
+	return nonNil(r.profile.SocialUris)
+}
+
+func (r *profileResolver) ExpireAt() *graphqlgo.Time {
+	if r.profile.ExpireAt == nil {
+		return nil
+	}
+
+	return &graphqlgo.Time{Time: *r.profile.ExpireAt}
+}
+
+// limit returns the page size of a query, which defaults to defaultLimit and is at most model.DefaultLimit
+func limit(value *int32) int {
+	if value == nil || *value <= 0 {
+		return defaultLimit
+	}
+
+	if *value > model.DefaultLimit {
+		return model.DefaultLimit
+	}
+
+	return int(*value)
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return make([]string, 0)
+	}
+
+	return values
+}
@@ -0,0 +1,53 @@
+package graphql
+
+import (
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+)
+
+type assetConnectionResolver struct {
+	total  int64
+	cursor *string
+	list   []*assetResolver
+}
+
+func (r *assetConnectionResolver) Total() int32 {
+	return int32(r.total)
+}
+
+func (r *assetConnectionResolver) Cursor() *string {
+	return r.cursor
+}
+
+func (r *assetConnectionResolver) List() []*assetResolver {
+	return r.list
+}
+
+type assetResolver struct {
+	asset dbModel.Asset
+}
+
+func (r *assetResolver) Network() string       { return r.asset.Network }
+func (r *assetResolver) TokenAddress() string  { return r.asset.TokenAddress }
+func (r *assetResolver) TokenID() string       { return r.asset.TokenID }
+func (r *assetResolver) TokenStandard() string { return r.asset.TokenStandard }
+func (r *assetResolver) Owner() string         { return r.asset.Owner }
+func (r *assetResolver) Title() string         { return r.asset.Title }
+func (r *assetResolver) Description() string   { return r.asset.Description }
+func (r *assetResolver) Image() string         { return r.asset.Image }
+
+func (r *assetResolver) Attributes() *JSON {
+	if len(r.asset.Attributes) == 0 {
+		return nil
+	}
+
+	return &JSON{RawMessage: r.asset.Attributes}
+}
+
+func (r *assetResolver) RelatedUrls() []string {
+	return nonNil(r.asset.RelatedUrls)
+}
+
+func (r *assetResolver) Timestamp() graphqlgo.Time {
+	return graphqlgo.Time{Time: r.asset.Timestamp}
+}
@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/service"
+)
+
+const (
+	MaxAccounts = 20
+
+	defaultLimit = 100
+	maxDepth     = 10
+)
+
+//go:embed schema.graphql
+var schemaString string
+
+// Schema executes the GraphQL queries over the notes, profiles, assets and names of accounts
+type Schema struct {
+	schema  *graphqlgo.Schema
+	service *service.Service
+}
+
+// Exec executes a query with the loaders of a request, the errors are returned in the response
+func (s *Schema) Exec(ctx context.Context, query, operationName string, variables map[string]any) *graphqlgo.Response {
+	ctx = withLoaders(ctx, s.service)
+
+	return s.schema.Exec(ctx, query, operationName, variables)
+}
+
+// JSON is a scalar of any JSON value
+type JSON struct {
+	json.RawMessage
+}
+
+func (JSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+func (j *JSON) UnmarshalGraphQL(input any) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("wrong type for JSON: %T", input)
+	}
+
+	j.RawMessage = data
+
+	return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j.RawMessage) == 0 {
+		return []byte("null"), nil
+	}
+
+	return j.RawMessage, nil
+}
+
+func New(svc *service.Service) *Schema {
+	return &Schema{
+		schema:  graphqlgo.MustParseSchema(schemaString, &queryResolver{service: svc}, graphqlgo.MaxDepth(maxDepth)),
+		service: svc,
+	}
+}
@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/database/model/social"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dataloader"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/service"
+)
+
+type loadersKey struct{}
+
+// loaders batch the queries of a request, the transfers of all the notes are queried at once
+type loaders struct {
+	transfers *dataloader.Loader[string, []dbModel.Transfer]
+	profiles  *dataloader.Loader[string, []*social.Profile]
+}
+
+func withLoaders(ctx context.Context, svc *service.Service) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		transfers: dataloader.New(svc.GetTransfers, 0, 0),
+		profiles: dataloader.New(func(ctx context.Context, addresses []string) (map[string][]*social.Profile, error) {
+			var (
+				wg       sync.WaitGroup
+				locker   sync.Mutex
+				profiles = make(map[string][]*social.Profile, len(addresses))
+				errs     = make(dataloader.Errors[string])
+			)
+
+			// The profiles of an address are looked up on the platforms without them
+			for _, address := range addresses {
+				wg.Add(1)
+
+				go func(address string) {
+					defer wg.Done()
+
+					result, err := svc.GetProfiles(ctx, model.GetRequest{Address: address})
+
+					locker.Lock()
+					defer locker.Unlock()
+
+					if err != nil {
+						errs[address] = err
+
+						return
+					}
+
+					profiles[address] = result
+				}(address)
+			}
+
+			wg.Wait()
+
+			// Only the addresses failed to look up resolve to errors
+			if len(errs) > 0 {
+				return profiles, errs
+			}
+
+			return profiles, nil
+		}, 0, 0),
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,108 @@
+package graphql
+
+import (
+	"context"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/samber/lo"
+)
+
+type noteConnectionResolver struct {
+	total  *int64
+	cursor *string
+	list   []*noteResolver
+}
+
+func (r *noteConnectionResolver) Total() *int32 {
+	if r.total == nil {
+		return nil
+	}
+
+	total := int32(*r.total)
+
+	return &total
+}
+
+func (r *noteConnectionResolver) Cursor() *string {
+	return r.cursor
+}
+
+func (r *noteConnectionResolver) List() []*noteResolver {
+	return r.list
+}
+
+type noteResolver struct {
+	transaction dbModel.Transaction
+}
+
+func (r *noteResolver) Hash() string        { return r.transaction.Hash }
+func (r *noteResolver) Owner() string       { return r.transaction.Owner }
+func (r *noteResolver) AddressFrom() string { return r.transaction.AddressFrom }
+func (r *noteResolver) AddressTo() string   { return r.transaction.AddressTo }
+func (r *noteResolver) Network() string     { return r.transaction.Network }
+func (r *noteResolver) Platform() string    { return r.transaction.Platform }
+func (r *noteResolver) Tag() string         { return r.transaction.Tag }
+func (r *noteResolver) Type() string        { return r.transaction.Type }
+func (r *noteResolver) Success() *bool      { return r.transaction.Success }
+func (r *noteResolver) Finality() string    { return r.transaction.Finality }
+
+func (r *noteResolver) Timestamp() graphqlgo.Time {
+	return graphqlgo.Time{Time: r.transaction.Timestamp}
+}
+
+func (r *noteResolver) Fee() *string {
+	if r.transaction.Fee == nil {
+		return nil
+	}
+
+	return lo.ToPtr(r.transaction.Fee.String())
+}
+
+func (r *noteResolver) Confirmations() int32 {
+	return int32(r.transaction.Confirmations)
+}
+
+// Actions loads the transfers of the note in a batch with the other notes of the request
+func (r *noteResolver) Actions(ctx context.Context, args struct{ Limit *int32 }) ([]*actionResolver, error) {
+	transfers, err := loadersFrom(ctx).transfers.Load(ctx, r.transaction.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	actionLimit := model.DefaultActionLimit
+	if args.Limit != nil && *args.Limit > 0 {
+		actionLimit = int(*args.Limit)
+	}
+
+	if len(transfers) > actionLimit {
+		transfers = transfers[:actionLimit]
+	}
+
+	return lo.Map(transfers, func(transfer dbModel.Transfer, _ int) *actionResolver {
+		return &actionResolver{transfer: transfer}
+	}), nil
+}
+
+type actionResolver struct {
+	transfer dbModel.Transfer
+}
+
+func (r *actionResolver) Tag() string         { return r.transfer.Tag }
+func (r *actionResolver) Type() string        { return r.transfer.Type }
+func (r *actionResolver) AddressFrom() string { return r.transfer.AddressFrom }
+func (r *actionResolver) AddressTo() string   { return r.transfer.AddressTo }
+func (r *actionResolver) Platform() string    { return r.transfer.Platform }
+
+func (r *actionResolver) Index() int32 {
+	return int32(r.transfer.Index)
+}
+
+func (r *actionResolver) Metadata() JSON {
+	return JSON{RawMessage: r.transfer.Metadata}
+}
+
+func (r *actionResolver) RelatedUrls() []string {
+	return nonNil(r.transfer.RelatedUrls)
+}
@@ -1,28 +1,32 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/google/uuid"
-	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
-	"github.com/naturalselectionlabs/pregod/common/database"
-	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/constant"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/config"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/middlewarex"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
-	"github.com/sirupsen/logrus"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/ratelimit"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/service"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/siwe"
 	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
 )
 
-func (h *Handler) PostAPIKeyFunc(c echo.Context) error {
-	// tracer
-	tracer := otel.Tracer("PostAPIKeyFunc")
-	_, httpSnap := tracer.Start(c.Request().Context(), "http")
+// GetAPIKeyNonceFunc issues a nonce for an action on the API keys of an address,
+// the returned message must be signed by the address to apply for, rotate, revoke or list the keys
+func (h *Handler) GetAPIKeyNonceFunc(c echo.Context) error {
+	tracer := otel.Tracer("GetAPIKeyNonceFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
 
 	defer httpSnap.End()
 
-	request := model.GetAPIKeyRequest{}
+	request := model.GetAPIKeyNonceRequest{}
 
 	if err := c.Bind(&request); err != nil {
 		return BadRequest(c)
@@ -32,35 +36,56 @@ func (h *Handler) PostAPIKeyFunc(c echo.Context) error {
 		return ValidateFailed(c)
 	}
 
-	// check if key already exists
-	item := dbModel.APIKey{}
-	if err := database.Global().
-		Where("address = ?").
-		First(&item).Error; err == nil && len(item.UUID) > 0 {
-		return ErrorResp(c, errors.New("this address has already applied for API-KEY"), http.StatusBadRequest, ErrorCodeKeyAlreadyExists)
+	result, err := h.service.GetAPIKeyNonce(ctx, signInDomain(c), request)
+	if err != nil {
+		return InternalError(c)
 	}
 
-	// generate api key
-	item = dbModel.APIKey{
-		Address: request.Address,
-		UUID:    uuid.New().String(),
-	}
-	if err := database.Global().Create(&item).Error; err != nil {
-		logrus.Errorf("PostAPIKeyFunc: create sql error, %v", err)
-		return ErrorResp(c, errors.New("PostAPIKeyFunc: create sql error"), http.StatusInternalServerError, ErrorCodeInternalError)
-	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// PostAPIKeyFunc issues an API key to the address which signed the message
+func (h *Handler) PostAPIKeyFunc(c echo.Context) error {
+	return h.signedAPIKeyFunc(c, "PostAPIKeyFunc", func(ctx context.Context, request model.SignedAPIKeyRequest) (any, error) {
+		return h.service.ApplyAPIKey(ctx, signInDomain(c), request)
+	})
+}
 
-	return c.JSON(http.StatusOK, &item)
+// RotateAPIKeyFunc replaces the API key of the address which signed the message
+func (h *Handler) RotateAPIKeyFunc(c echo.Context) error {
+	return h.signedAPIKeyFunc(c, "RotateAPIKeyFunc", func(ctx context.Context, request model.SignedAPIKeyRequest) (any, error) {
+		return h.service.RotateAPIKey(ctx, signInDomain(c), request)
+	})
 }
 
-func (h *Handler) GetAPIKeyFunc(c echo.Context) error {
-	// tracer
-	tracer := otel.Tracer("GetAPIKeyFunc")
-	_, httpSnap := tracer.Start(c.Request().Context(), "http")
+// RevokeAPIKeyFunc disables the API key of the address which signed the message
+func (h *Handler) RevokeAPIKeyFunc(c echo.Context) error {
+	return h.signedAPIKeyFunc(c, "RevokeAPIKeyFunc", func(ctx context.Context, request model.SignedAPIKeyRequest) (any, error) {
+		return h.service.RevokeAPIKey(ctx, signInDomain(c), request)
+	})
+}
+
+// ListAPIKeysFunc returns the API keys of the address which signed the message
+func (h *Handler) ListAPIKeysFunc(c echo.Context) error {
+	return h.signedAPIKeyFunc(c, "ListAPIKeysFunc", func(ctx context.Context, request model.SignedAPIKeyRequest) (any, error) {
+		apiKeys, err := h.service.ListAPIKeys(ctx, signInDomain(c), request)
+		if err != nil {
+			return nil, err
+		}
+
+		return &model.Response{
+			Result: apiKeys,
+		}, nil
+	})
+}
+
+func (h *Handler) signedAPIKeyFunc(c echo.Context, name string, handle func(ctx context.Context, request model.SignedAPIKeyRequest) (any, error)) error {
+	tracer := otel.Tracer(name)
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
 
 	defer httpSnap.End()
 
-	request := model.PostAPIKeyRequest{}
+	request := model.SignedAPIKeyRequest{}
 
 	if err := c.Bind(&request); err != nil {
 		return BadRequest(c)
@@ -70,28 +95,67 @@ func (h *Handler) GetAPIKeyFunc(c echo.Context) error {
 		return ValidateFailed(c)
 	}
 
-	wallet, err := session.Get("user_wallet", c)
+	result, err := handle(ctx, request)
 	if err != nil {
-		return BadRequest(c)
+		return apiKeyError(c, err)
 	}
 
-	hash := wallet.Values["hash"].(string)
-	signature := wallet.Values["signature"].(string)
+	return c.JSON(http.StatusOK, result)
+}
 
-	sigPublicKeyECDSA, err := crypto.SigToPub([]byte(hash), []byte(signature))
-	if err != nil {
-		logrus.Errorf("GetAPIKeyFunc: crypto.SigToPub error, %v", err)
-		return ErrorResp(c, errors.New("GetAPIKeyFunc: crypto.SigToPub error"), http.StatusBadRequest, ErrorCodeSigToPubError)
+// signInDomain is the domain of the sign-in messages, the host of the request unless it's configured
+func signInDomain(c echo.Context) string {
+	if apiKey := config.ConfigHub.APIKey; apiKey != nil && len(apiKey.Domain) > 0 {
+		return apiKey.Domain
 	}
 
-	address := crypto.PubkeyToAddress(*sigPublicKeyECDSA)
+	return c.Request().Host
+}
 
-	if address.String() != request.Address {
-		return ErrorResp(c, errors.New("GetAPIKeyFunc: address is not match"), http.StatusBadRequest, ErrorCodeAddressIsNotMatch)
+func apiKeyError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, siwe.ErrInvalidMessage),
+		errors.Is(err, siwe.ErrInvalidSignature),
+		errors.Is(err, siwe.ErrExpiredMessage),
+		errors.Is(err, siwe.ErrNotYetValid):
+		return ErrorResp(c, err, http.StatusBadRequest, ErrorCodeSigToPubError)
+	case errors.Is(err, service.ErrSignInMismatch):
+		return ErrorResp(c, err, http.StatusBadRequest, ErrorCodeAddressIsNotMatch)
+	case errors.Is(err, service.ErrAPIKeyExists):
+		return ErrorResp(c, err, http.StatusBadRequest, ErrorCodeKeyAlreadyExists)
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		return ErrorResp(c, err, http.StatusNotFound, ErrorCodeAPIKeyNotFound)
+	default:
+		loggerx.Global().Error("failed to handle signed api key request", zap.Error(err))
+
+		return InternalError(c)
 	}
+}
+
+// GetAPIKeyUsageFunc returns the quota usage of the API key and its daily usage history
+func (h *Handler) GetAPIKeyUsageFunc(c echo.Context) error {
+	tracer := otel.Tracer("GetAPIKeyUsageFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
 
-	item := dbModel.APIKey{}
-	database.Global().Where("address = ?").First(&item)
+	request := model.GetAPIKeyUsageRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	apiKey, err := middlewarex.GetAPIKey(c.Request().Header.Get(constant.API_KEY_HEADER))
+	if err != nil {
+		return headerAPIKeyError(c, err)
+	}
+
+	limiter := ratelimit.Global()
+
+	usage, err := limiter.Usage(ctx, ratelimit.KeySubject(apiKey.Address), limiter.Tier(apiKey.Type), request.Days)
+	if err != nil {
+		return InternalError(c)
+	}
 
-	return c.JSON(http.StatusOK, &item)
+	return c.JSON(http.StatusOK, usage)
 }
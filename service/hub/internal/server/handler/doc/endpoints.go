@@ -23,6 +23,9 @@ type Endpoint struct {
 var endpoints = []Endpoint{
 	{http.MethodGet, handler.PathGetNotes, model.GetRequest{}, []dbModel.Transaction{}},
 	{http.MethodGet, handler.PathGetAssets, model.GetAssetRequest{}, []dbModel.Asset{}},
+	{http.MethodGet, handler.PathGetPortfolio, model.GetPortfolioRequest{}, model.Portfolio{}},
+	{http.MethodGet, handler.PathGetPnL, model.GetPnLRequest{}, model.PnL{}},
+	{http.MethodGet, handler.PathGetApprovals, model.GetApprovalsRequest{}, model.Approvals{}},
 	{http.MethodGet, handler.PathGetExchanges, model.GetExchangeRequest{}, []ExchangeResult{}},
 	{http.MethodGet, handler.PathGetPlatformList, model.GetPlatformRequest{}, []model.PlatformResult{}},
 	{http.MethodGet, handler.PathGetProfiles, model.GetRequest{}, []social.Profile{}},
@@ -30,13 +33,18 @@ var endpoints = []Endpoint{
 	{http.MethodGet, handler.PathGetTransaction, model.GetTransactionRequest{}, dbModel.Transaction{}},
 	{http.MethodGet, handler.PathGetMastodon, model.GetRequest{}, []dbModel.Transaction{}},
 	{http.MethodGet, handler.PathGetNotesByPlatform, model.GetNotesByPlatformRequest{}, []dbModel.Transaction{}},
+	{http.MethodGet, handler.PathSearchNotes, model.SearchNotesRequest{}, []model.SearchNoteResult{}},
+	{http.MethodGet, handler.PathExportNotes, model.ExportNotesRequest{}, ""},
 
 	{http.MethodPost, handler.PathBatchGetSocialNotes, model.BatchGetSocialNotesRequest{}, []dbModel.Transaction{}},
 	{http.MethodPost, handler.PathBatchGetNotes, model.BatchGetNotesRequest{}, []dbModel.Transaction{}},
 	{http.MethodPost, handler.PathBatchGetProfiles, model.BatchGetProfilesRequest{}, []social.Profile{}},
 
-	{http.MethodPost, handler.PathPostAPIKey, model.PostAPIKeyRequest{}, dbModel.APIKey{}},
-	{http.MethodGet, handler.PathGetAPIKey, model.GetAPIKeyRequest{}, dbModel.APIKey{}},
+	{http.MethodGet, handler.PathGetAPIKeyNonce, model.GetAPIKeyNonceRequest{}, model.GetAPIKeyNonceResult{}},
+	{http.MethodPost, handler.PathPostAPIKey, model.SignedAPIKeyRequest{}, model.APIKeyResult{}},
+	{http.MethodPost, handler.PathRotateAPIKey, model.SignedAPIKeyRequest{}, model.APIKeyResult{}},
+	{http.MethodPost, handler.PathRevokeAPIKey, model.SignedAPIKeyRequest{}, model.APIKeyResult{}},
+	{http.MethodPost, handler.PathListAPIKeys, model.SignedAPIKeyRequest{}, []model.APIKeyResult{}},
 }
 
 func (d *Doc) endpoints() Obj {
@@ -130,17 +138,22 @@ func (d *Doc) reqBody(e Endpoint) Obj {
 
 func (d *Doc) resBody(e Endpoint) Obj {
 	res := d.schemas.Define(e.Result)
+	contentType := "application/json"
 
-	if reflect.TypeOf(e.Result).Kind() == reflect.Slice {
+	switch reflect.TypeOf(e.Result).Kind() {
+	case reflect.Slice:
 		res = d.schemas.PeakSchema(model.Response{}).Clone()
 		res.Properties["result"] = d.schemas.Define(e.Result)
+	case reflect.String:
+		// The exports are CSV files
+		contentType = "text/csv"
 	}
 
 	return Obj{
 		"200": Obj{
 			"description": "Response",
 			"content": Obj{
-				"application/json": Obj{
+				contentType: Obj{
 					"schema": res,
 				},
 			},
@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/middlewarex"
 )
 
 type ErrorResponse struct {
@@ -26,6 +28,14 @@ const (
 	ErrorCodeSigToPubError             = 1010
 	ErrorCodeAddressIsNotMatch         = 1011
 	ErrorCodeInvalidExchangeType       = 1012
+	ErrorCodeInvalidAPIKey             = 1013
+	ErrorCodeWebhookNotFound           = 1014
+	ErrorCodeWebhookLimitExceeded      = 1015
+	ErrorCodeAPIKeyRevoked             = 1016
+	ErrorCodeRateLimited               = 1017
+	ErrorCodeAPIKeyNotFound            = 1018
+	ErrorCodeInvalidCursor             = 1019
+	ErrorCodeInvalidWebhookURL         = 1020
 )
 
 func ErrorResp(c echo.Context, err error, httpCode, errorCode int) error {
@@ -70,6 +80,17 @@ func AddressIsInvalid(c echo.Context) error {
 	})
 }
 
+// headerAPIKeyError responds the failure of getting the API key of the request, only the missing, invalid and revoked keys
+// are unauthorized
+func headerAPIKeyError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, middlewarex.ErrMissingAPIKey), errors.Is(err, middlewarex.ErrInvalidAPIKey), errors.Is(err, middlewarex.ErrAPIKeyRevoked):
+		return ErrorResp(c, err, http.StatusUnauthorized, ErrorCodeInvalidAPIKey)
+	default:
+		return InternalError(c)
+	}
+}
+
 func InternalError(c echo.Context) error {
 	return c.JSON(http.StatusInternalServerError, &ErrorResponse{
 		Error:     "An internal error has occurred, please try again later.",
@@ -7,16 +7,21 @@ import (
 
 	"github.com/labstack/echo/v4"
 	utils "github.com/naturalselectionlabs/pregod/common/utils/interface"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/graphql"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/service"
 )
 
 type Handler struct {
 	service *service.Service
+	graphql *graphql.Schema
 }
 
 func New(svc *service.Service) *Handler {
-	return &Handler{service: svc}
+	return &Handler{
+		service: svc,
+		graphql: graphql.New(svc),
+	}
 }
 
 func (h *Handler) apiReport(path string, c echo.Context) {
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/export"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/middlewarex"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+// ExportNotesFunc streams the notes of addresses as a CSV file, in our columns or in the import formats of the tax tools
+func (h *Handler) ExportNotesFunc(c echo.Context) error {
+	go h.apiReport(model.ExportNotes, c)
+	tracer := otel.Tracer("ExportNotesFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	request := model.ExportNotesRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	if len(request.Format) == 0 {
+		request.Format = export.FormatCSV
+	}
+
+	if len(request.Address) > model.DefaultLimit {
+		request.Address = request.Address[:model.DefaultLimit]
+	}
+
+	for i, v := range request.Address {
+		address, err := middlewarex.ResolveAddress(c, v, true)
+		if err != nil {
+			return ErrorResp(c, err, http.StatusBadRequest, ErrorCodeAddressIsInvalid)
+		}
+		request.Address[i] = address
+	}
+
+	response := c.Response()
+
+	writer, err := export.NewWriter(request.Format, response)
+	if err != nil {
+		return ValidateFailed(c)
+	}
+
+	started := false
+
+	err = h.service.ExportNotes(ctx, request, writer, func() {
+		started = true
+
+		response.Header().Set(echo.HeaderContentType, export.ContentType())
+		response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", export.Filename(request.Format, time.Now())))
+		response.WriteHeader(http.StatusOK)
+	})
+
+	switch {
+	case err == nil:
+		return nil
+	case !started:
+		return InternalError(c)
+	default:
+		loggerx.Global().Error("failed to export notes", zap.Error(err), zap.Strings("address", request.Address))
+
+		// The status has been sent, abort the response so that the truncated file isn't taken as complete
+		panic(http.ErrAbortHandler)
+	}
+}
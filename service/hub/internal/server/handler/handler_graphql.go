@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"go.opentelemetry.io/otel"
+)
+
+// PostGraphQLFunc executes a GraphQL query over the notes, profiles, assets and names of accounts,
+// the errors of the query are returned in the response as GraphQL defines
+func (h *Handler) PostGraphQLFunc(c echo.Context) error {
+	go h.apiReport(model.PostGraphQL, c)
+	tracer := otel.Tracer("PostGraphQLFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	request := model.GraphQLRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	return c.JSON(http.StatusOK, h.graphql.Exec(ctx, request.Query, request.OperationName, request.Variables))
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dao"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/middlewarex"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
 	ws "github.com/naturalselectionlabs/pregod/service/hub/internal/server/websocket"
 
 	"go.opentelemetry.io/otel"
@@ -33,6 +35,10 @@ func (h *Handler) GetNotesFunc(c echo.Context) error {
 		return BadRequest(c)
 	}
 
+	if request.Page != 0 {
+		return notesError(c, pagination.ErrPageRemoved)
+	}
+
 	if err := c.Validate(&request); err != nil {
 		return ValidateFailed(c)
 	}
@@ -55,7 +61,7 @@ func (h *Handler) GetNotesFunc(c echo.Context) error {
 
 	var (
 		transactions []dbModel.Transaction
-		total        int64
+		page         model.Pagination
 		err          error
 	)
 	response := &model.Response{}
@@ -63,9 +69,9 @@ func (h *Handler) GetNotesFunc(c echo.Context) error {
 	// nft feed for rara
 	if strings.HasPrefix(request.Address, "nft:") {
 		request.Address = strings.Split(request.Address, "nft:")[1]
-		transactions, total, err = h.service.GetNftFeeds(ctx, request)
+		transactions, page, err = h.service.GetNftFeeds(ctx, request)
 	} else {
-		transactions, total, err = h.service.GetNotes(ctx, request)
+		transactions, page, err = h.service.GetNotes(ctx, request)
 
 		var addressStatus []dbModel.Address
 		if request.QueryStatus {
@@ -76,22 +82,17 @@ func (h *Handler) GetNotesFunc(c echo.Context) error {
 	}
 
 	if err != nil {
-		return ErrorResp(c, err, http.StatusInternalServerError, ErrorCodeInternalError)
+		return notesError(c, err)
 	}
 
 	if request.CountOnly {
 		return c.JSON(http.StatusOK, &model.Response{
-			Total: &total,
+			Total: page.Total,
 		})
 	}
 
-	var cursor string
-	if total > int64(request.Limit) {
-		cursor = transactions[len(transactions)-1].Hash
-	}
-
-	response.Total = &total
-	response.Cursor = cursor
+	response.Total = page.Total
+	response.Cursor = page.Cursor
 	response.Result = transactions
 
 	return c.JSON(http.StatusOK, response)
@@ -111,6 +112,10 @@ func (h *Handler) BatchGetNotesFunc(c echo.Context) error {
 		return BadRequest(c)
 	}
 
+	if request.Page != 0 {
+		return notesError(c, pagination.ErrPageRemoved)
+	}
+
 	if len(request.Cursor) == 0 {
 		go h.filterReport(model.PostNotes, request, c)
 	}
@@ -143,9 +148,9 @@ func (h *Handler) BatchGetNotesFunc(c echo.Context) error {
 	// header into ctx
 	ctx = context.WithValue(ctx, constant.HEADER_CTX_KEY, c.Request().Header)
 
-	transactions, total, err := h.service.BatchGetNotes(ctx, request)
+	transactions, page, err := h.service.BatchGetNotes(ctx, request)
 	if err != nil {
-		return ErrorResp(c, err, http.StatusInternalServerError, ErrorCodeInternalError)
+		return notesError(c, err)
 	}
 
 	var addressStatus []dbModel.Address
@@ -155,25 +160,13 @@ func (h *Handler) BatchGetNotesFunc(c echo.Context) error {
 
 	if request.CountOnly {
 		return c.JSON(http.StatusOK, &model.Response{
-			Total: &total,
-		})
-	}
-
-	var cursor string
-	if total > int64(request.Limit) {
-		cursor = transactions[len(transactions)-1].Hash
-	}
-
-	if total == 0 {
-		return c.JSON(http.StatusOK, &model.Response{
-			Result:        make([]dbModel.Transaction, 0),
-			AddressStatus: addressStatus,
+			Total: page.Total,
 		})
 	}
 
 	return c.JSON(http.StatusOK, &model.Response{
-		Total:         &total,
-		Cursor:        cursor,
+		Total:         page.Total,
+		Cursor:        page.Cursor,
 		Result:        transactions,
 		AddressStatus: addressStatus,
 	})
@@ -193,6 +186,10 @@ func (h *Handler) BatchGetSocialNotesFunc(c echo.Context) error {
 		return BadRequest(c)
 	}
 
+	if request.Page != 0 {
+		return notesError(c, pagination.ErrPageRemoved)
+	}
+
 	if len(request.Cursor) == 0 {
 		go h.filterReport(model.PostSocialNotes, request, c)
 	}
@@ -213,52 +210,34 @@ func (h *Handler) BatchGetSocialNotesFunc(c echo.Context) error {
 		request.Address[i] = address
 	}
 
-	transactions, total, err := h.service.BatchGetSocialNotes(ctx, request)
+	transactions, page, err := h.service.BatchGetSocialNotes(ctx, request)
 	if err != nil {
-		return ErrorResp(c, err, http.StatusInternalServerError, ErrorCodeInternalError)
+		return notesError(c, err)
 	}
 
 	if request.CountOnly {
 		return c.JSON(http.StatusOK, &model.Response{
-			Total: &total,
-		})
-	}
-
-	var cursor string
-	if total > int64(request.Limit) {
-		cursor = transactions[len(transactions)-1].Hash
-	}
-
-	if total == 0 {
-		return c.JSON(http.StatusOK, &model.Response{
-			Result: make([]dbModel.Transaction, 0),
+			Total: page.Total,
 		})
 	}
 
 	return c.JSON(http.StatusOK, &model.Response{
-		Total:  &total,
-		Cursor: cursor,
+		Total:  page.Total,
+		Cursor: page.Cursor,
 		Result: transactions,
 	})
 }
 
 func (h *Handler) GetNotesWsFunc(c echo.Context) error {
-	ws.GetClientMaps()
-
-	clientId := uuid.New().String()
-
 	conn, err := (&websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024, CheckOrigin: func(r *http.Request) bool { return true }}).Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		return err
 	}
 
-	client := &ws.WSClient{Hub: h.service.WsHub, Conn: conn, Send: make(chan []byte, 256), ClientId: []byte(clientId), AddressMap: make(map[string]struct{})}
+	client := ws.NewClient(h.service.WsHub, conn, uuid.New().String())
 
 	client.Hub.Register <- client
 
-	ws.ReplaceGlobal(clientId, client)
-
-	go h.service.SubscribeIndexerRefreshMessage(client)
 	go client.WriteMsg()
 	go client.ReadMsg()
 
@@ -321,25 +300,23 @@ func (h *Handler) GetNotesByPlatformFunc(c echo.Context) error {
 	// header into ctx
 	ctx = context.WithValue(ctx, constant.HEADER_CTX_KEY, c.Request().Header)
 
-	var (
-		transactions []dbModel.Transaction
-		err          error
-	)
-	response := &model.Response{}
-
-	transactions, err = h.service.GetNotesByPlatform(ctx, request)
-
+	transactions, page, err := h.service.GetNotesByPlatform(ctx, request)
 	if err != nil {
-		return ErrorResp(c, err, http.StatusInternalServerError, ErrorCodeInternalError)
+		return notesError(c, err)
 	}
 
-	var cursor string
-	if len(transactions) != 0 {
-		cursor = transactions[len(transactions)-1].Hash
-	}
+	return c.JSON(http.StatusOK, &model.Response{
+		Total:  page.Total,
+		Cursor: page.Cursor,
+		Result: transactions,
+	})
+}
 
-	response.Cursor = cursor
-	response.Result = transactions
+// notesError responds the invalid cursors and the removed page as bad requests
+func notesError(c echo.Context, err error) error {
+	if errors.Is(err, pagination.ErrInvalidCursor) || errors.Is(err, pagination.ErrPageRemoved) {
+		return ErrorResp(c, err, http.StatusBadRequest, ErrorCodeInvalidCursor)
+	}
 
-	return c.JSON(http.StatusOK, response)
+	return ErrorResp(c, err, http.StatusInternalServerError, ErrorCodeInternalError)
 }
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"go.opentelemetry.io/otel"
+)
+
+// GetPortfolioFunc HTTP handler for the token balances of an address
+func (h *Handler) GetPortfolioFunc(c echo.Context) error {
+	go h.apiReport(model.GetPortfolio, c)
+	tracer := otel.Tracer("GetPortfolioFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	request := model.GetPortfolioRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	result, err := h.service.GetPortfolio(ctx, request)
+	if err != nil {
+		return ErrorResp(c, err, http.StatusInternalServerError, ErrorCodeInternalError)
+	}
+
+	return c.JSON(http.StatusOK, &model.Response{
+		Result: result,
+	})
+}
+
+// GetPnLFunc HTTP handler for the cost basis and profit and loss of an address
+func (h *Handler) GetPnLFunc(c echo.Context) error {
+	go h.apiReport(model.GetPnL, c)
+	tracer := otel.Tracer("GetPnLFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	request := model.GetPnLRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	result, err := h.service.GetPnL(ctx, request)
+	if err != nil {
+		return ErrorResp(c, err, http.StatusInternalServerError, ErrorCodeInternalError)
+	}
+
+	return c.JSON(http.StatusOK, &model.Response{
+		Result: result,
+	})
+}
+
+// GetApprovalsFunc HTTP handler for the live token approvals of an address and their risks
+func (h *Handler) GetApprovalsFunc(c echo.Context) error {
+	go h.apiReport(model.GetApprovals, c)
+	tracer := otel.Tracer("GetApprovalsFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	request := model.GetApprovalsRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	result, err := h.service.GetApprovals(ctx, request)
+	if err != nil {
+		return ErrorResp(c, err, http.StatusInternalServerError, ErrorCodeInternalError)
+	}
+
+	return c.JSON(http.StatusOK, &model.Response{
+		Result: result,
+	})
+}
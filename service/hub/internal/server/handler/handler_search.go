@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/middlewarex"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"go.opentelemetry.io/otel"
+)
+
+// SearchNotesFunc HTTP handler for the full-text search of social notes
+func (h *Handler) SearchNotesFunc(c echo.Context) error {
+	go h.apiReport(model.SearchNotes, c)
+	tracer := otel.Tracer("SearchNotesFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	request := model.SearchNotesRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	if len(request.Address) > model.DefaultLimit {
+		request.Address = request.Address[:model.DefaultLimit]
+	}
+
+	for i, v := range request.Address {
+		address, err := middlewarex.ResolveAddress(c, v, true)
+		if err != nil {
+			return ErrorResp(c, err, http.StatusBadRequest, ErrorCodeAddressIsInvalid)
+		}
+		request.Address[i] = address
+	}
+
+	results, page, err := h.service.SearchNotes(ctx, request)
+	if err != nil {
+		return notesError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, &model.Response{
+		Total:  page.Total,
+		Cursor: page.Cursor,
+		Result: results,
+	})
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
+	ws "github.com/naturalselectionlabs/pregod/service/hub/internal/server/websocket"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderLastEventID = "Last-Event-ID"
+
+	// The milliseconds an EventSource waits before reconnecting
+	streamRetry = 3000
+)
+
+// GetNotesStreamFunc streams the new notes of an address as Server-Sent Events with the filters of GetNotesFunc,
+// the id of an event is the cursor of its latest note, so a reconnected EventSource resumes from its Last-Event-ID
+func (h *Handler) GetNotesStreamFunc(c echo.Context) error {
+	go h.apiReport(model.GetNotesStream, c)
+
+	request := model.GetRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	cursor := c.Request().Header.Get(HeaderLastEventID)
+	if len(cursor) == 0 {
+		cursor = request.Cursor
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	// Disable the buffering of nginx
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(response, "retry: %d\n\n", streamRetry); err != nil {
+		return nil
+	}
+
+	response.Flush()
+
+	subscription := h.service.NewSubscription(request)
+
+	// The client is registered before resuming, so that no note is missed in between
+	client := ws.NewClient(h.service.WsHub, nil, uuid.New().String())
+	client.Subscription = subscription
+	client.Hub.Register <- client
+
+	defer func() {
+		client.Hub.Unregister <- client
+	}()
+
+	ctx := c.Request().Context()
+
+	resumed := make(map[string]struct{})
+
+	if len(cursor) > 0 {
+		notes, err := h.service.GetNotesAfter(ctx, *subscription, cursor)
+		if err != nil {
+			loggerx.Global().Error("failed to resume notes stream", zap.Error(err), zap.String("address", request.Address), zap.String("cursor", cursor))
+
+			err = writeEvent(response, "", model.EventError, model.Response{Message: "failed to resume from cursor"})
+		} else if len(notes) > 0 {
+			for _, note := range notes {
+				resumed[note.Hash] = struct{}{}
+			}
+
+			last := pagination.New(notes[len(notes)-1]).String()
+
+			err = writeEvent(response, last, model.EventNotes, model.Response{Cursor: last, Result: notes})
+		}
+
+		if err != nil {
+			return nil
+		}
+
+		response.Flush()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case data, ok := <-client.Send:
+			// The hub drops the streams which don't keep up, EventSource reconnects with the last id
+			if !ok {
+				return nil
+			}
+
+			var notification model.WebsocketNotification
+			if err := json.Unmarshal(data, &notification); err != nil {
+				continue
+			}
+
+			var err error
+
+			switch notification.Event {
+			case model.EventNotes:
+				// The notes broadcast during resuming may have been sent already
+				notes := lo.Filter(notification.Result, func(note dbModel.Transaction, _ int) bool {
+					_, exists := resumed[note.Hash]
+
+					return !exists
+				})
+
+				if len(notes) == 0 {
+					continue
+				}
+
+				err = writeEvent(response, notification.Cursor, model.EventNotes, model.Response{Cursor: notification.Cursor, Result: notes})
+			case model.EventHeartbeat:
+				_, err = io.WriteString(response, ": heartbeat\n\n")
+			default:
+				continue
+			}
+
+			if err != nil {
+				return nil
+			}
+
+			response.Flush()
+		}
+	}
+}
+
+func writeEvent(writer io.Writer, id, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if len(id) > 0 {
+		if _, err := fmt.Fprintf(writer, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", event, payload)
+
+	return err
+}
@@ -2,7 +2,13 @@ package handler
 
 const (
 	PathGetNotes           = "/notes/:address"
+	PathGetNotesStream     = "/notes/:address/stream"
+	PathSearchNotes        = "/notes/search"
+	PathExportNotes        = "/notes/export"
 	PathGetAssets          = "/assets/:address"
+	PathGetPortfolio       = "/portfolio/:address"
+	PathGetPnL             = "/pnl/:address"
+	PathGetApprovals       = "/approvals/:address"
 	PathGetExchanges       = "/exchanges/:exchange_type"
 	PathGetPlatformList    = "/platforms/:platform_type"
 	PathGetProfiles        = "/profiles/:address"
@@ -10,11 +16,24 @@ const (
 	PathGetTransaction     = "/tx/:hash"
 	PathGetMastodon        = "/mastodon/:address"
 	PathGetNotesByPlatform = "/platforms/notes/:platform"
+	PathGetNotesWs         = "/ws/notes"
 
 	PathBatchGetSocialNotes = "/notes/social"
 	PathBatchGetNotes       = "/notes"
 	PathBatchGetProfiles    = "/profiles"
 
-	PathPostAPIKey = "/apikey/apply"
-	PathGetAPIKey  = "/apikey/:address"
+	PathGraphQL = "/graphql"
+
+	PathGetAPIKeyNonce = "/apikey/nonce"
+	PathPostAPIKey     = "/apikey/apply"
+	PathRotateAPIKey   = "/apikey/rotate"
+	PathRevokeAPIKey   = "/apikey/revoke"
+	PathListAPIKeys    = "/apikey/list"
+
+	PathGetAPIKeyUsage = "/apikey/usage"
+
+	PathWebhooks                = "/webhooks"
+	PathWebhook                 = "/webhooks/:id"
+	PathGetWebhookDeliveries    = "/webhooks/:id/deliveries"
+	PathReplayWebhookDeliveries = "/webhooks/:id/replay"
 )
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naturalselectionlabs/pregod/common/constant"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/middlewarex"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/service"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/webhook"
+	"go.opentelemetry.io/otel"
+)
+
+// PostWebhookFunc registers a webhook for the API key, the secret in the response signs its payloads
+func (h *Handler) PostWebhookFunc(c echo.Context) error {
+	tracer := otel.Tracer("PostWebhookFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	apiKey, err := webhookAPIKey(c)
+	if err != nil {
+		return headerAPIKeyError(c, err)
+	}
+
+	request := model.PostWebhookRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	webhook, err := h.service.CreateWebhook(ctx, apiKey.Address, request)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func (h *Handler) GetWebhooksFunc(c echo.Context) error {
+	tracer := otel.Tracer("GetWebhooksFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	apiKey, err := webhookAPIKey(c)
+	if err != nil {
+		return headerAPIKeyError(c, err)
+	}
+
+	webhooks, err := h.service.GetWebhooks(ctx, apiKey.Address)
+	if err != nil {
+		return InternalError(c)
+	}
+
+	return c.JSON(http.StatusOK, &model.Response{
+		Result: webhooks,
+	})
+}
+
+func (h *Handler) DeleteWebhookFunc(c echo.Context) error {
+	tracer := otel.Tracer("DeleteWebhookFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	apiKey, err := webhookAPIKey(c)
+	if err != nil {
+		return headerAPIKeyError(c, err)
+	}
+
+	request := model.WebhookRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	if err := h.service.DeleteWebhook(ctx, apiKey.Address, request.ID); err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetWebhookDeliveriesFunc returns the delivery log of a webhook from the latest
+func (h *Handler) GetWebhookDeliveriesFunc(c echo.Context) error {
+	tracer := otel.Tracer("GetWebhookDeliveriesFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	apiKey, err := webhookAPIKey(c)
+	if err != nil {
+		return headerAPIKeyError(c, err)
+	}
+
+	request := model.GetWebhookDeliveriesRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	deliveries, err := h.service.GetWebhookDeliveries(ctx, apiKey.Address, request)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	var cursor string
+	if len(deliveries) > 0 {
+		cursor = deliveries[len(deliveries)-1].ID
+	}
+
+	return c.JSON(http.StatusOK, &model.Response{
+		Cursor: cursor,
+		Result: deliveries,
+	})
+}
+
+// ReplayWebhookDeliveriesFunc attempts the failed deliveries of a webhook again
+func (h *Handler) ReplayWebhookDeliveriesFunc(c echo.Context) error {
+	tracer := otel.Tracer("ReplayWebhookDeliveriesFunc")
+	ctx, httpSnap := tracer.Start(c.Request().Context(), "http")
+
+	defer httpSnap.End()
+
+	apiKey, err := webhookAPIKey(c)
+	if err != nil {
+		return headerAPIKeyError(c, err)
+	}
+
+	request := model.ReplayWebhookRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return BadRequest(c)
+	}
+
+	if err := c.Validate(&request); err != nil {
+		return ValidateFailed(c)
+	}
+
+	total, err := h.service.ReplayWebhookDeliveries(ctx, apiKey.Address, request)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, &model.Response{
+		Total: &total,
+	})
+}
+
+// webhookAPIKey returns the enabled API key of the request, webhooks belong to API key holders
+func webhookAPIKey(c echo.Context) (*dbModel.APIKey, error) {
+	apiKey, err := middlewarex.GetAPIKey(c.Request().Header.Get(constant.API_KEY_HEADER))
+	if err != nil {
+		return nil, err
+	}
+
+	if !apiKey.Status {
+		return nil, middlewarex.ErrAPIKeyRevoked
+	}
+
+	return apiKey, nil
+}
+
+func webhookError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrWebhookNotFound):
+		return ErrorResp(c, err, http.StatusNotFound, ErrorCodeWebhookNotFound)
+	case errors.Is(err, service.ErrWebhookLimitExceeded):
+		return ErrorResp(c, err, http.StatusBadRequest, ErrorCodeWebhookLimitExceeded)
+	case errors.Is(err, webhook.ErrInvalidURL):
+		return ErrorResp(c, err, http.StatusBadRequest, ErrorCodeInvalidWebhookURL)
+	default:
+		return InternalError(c)
+	}
+}
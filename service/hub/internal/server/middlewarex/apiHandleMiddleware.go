@@ -7,12 +7,10 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/naturalselectionlabs/pregod/common/cache"
-	"github.com/naturalselectionlabs/pregod/common/constant"
-	"github.com/naturalselectionlabs/pregod/common/database"
-	"github.com/naturalselectionlabs/pregod/common/database/model"
 	"github.com/naturalselectionlabs/pregod/common/datasource/rara"
 	"github.com/naturalselectionlabs/pregod/common/protocol"
 	"github.com/naturalselectionlabs/pregod/common/worker/name_service"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/ratelimit"
 
 	"go.opentelemetry.io/otel"
 )
@@ -28,6 +26,10 @@ const (
 
 func APIMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		if ok, err := authorize(c, false); !ok {
+			return err
+		}
+
 		address := c.Param("address")
 		if address != "" {
 			if address, err := ResolveAddress(c, address, false); err != nil {
@@ -40,49 +42,36 @@ func APIMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			}
 		}
 
-		apiKey := c.Request().Header.Get(constant.API_KEY_HEADER)
-		c.Set("API-KEY", apiKey)
-
-		_ = CheckAPIKey(apiKey)
-
-		// err := CheckAPIKey(apiKey)
-		// if err != nil {
-		// 	return err
-		// }
-
 		return next(c)
 	}
 }
 
+// CheckAPIKeyMiddleware requires an enabled API key within its quota
 func CheckAPIKeyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		apiKey := c.Request().Header.Get(constant.API_KEY_HEADER)
-		c.Set("API-KEY", apiKey)
-
-		_ = CheckAPIKey(apiKey)
-		// err := CheckAPIKey(apiKey)
-		// if err != nil {
-		// 	return err
-		// }
+		if ok, err := authorize(c, true); !ok {
+			return err
+		}
 
 		return next(c)
 	}
 }
 
-func CheckAPIKey(apiKey string) error {
-	var item model.APIKey
+// SignInMiddleware limits the sign-in requests of each IP, since the nonces they issue are stored
+func SignInMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if ok, err := allow(c, ratelimit.SignInSubject(c.RealIP()), ratelimit.Global().SignIn()); !ok {
+			return err
+		}
 
-	if len(apiKey) == 0 {
-		return fmt.Errorf("miss X-API-KEY header")
+		return next(c)
 	}
+}
 
-	if err := database.Global().
-		Where("uuid = ?", apiKey).
-		First(&item).Error; err != nil {
-		return fmt.Errorf("X-API-KEY is invaild")
-	}
+func CheckAPIKey(apiKey string) error {
+	_, err := GetAPIKey(apiKey)
 
-	return nil
+	return err
 }
 
 // ResolveAddress resolve handles into an address
@@ -0,0 +1,193 @@
+package middlewarex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/naturalselectionlabs/pregod/common/cache"
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/constant"
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/ratelimit"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	ErrorCodeInternalError = 1006
+	ErrorCodeInvalidAPIKey = 1013
+	ErrorCodeAPIKeyRevoked = 1016
+	ErrorCodeRateLimited   = 1017
+
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+
+	// The API keys are cached for a short time, revoking a key invalidates its cache
+	apiKeyCacheTTL = time.Minute
+)
+
+var (
+	ErrMissingAPIKey = errors.New("miss X-API-KEY header")
+	ErrInvalidAPIKey = errors.New("X-API-KEY is invaild")
+	ErrAPIKeyRevoked = errors.New("X-API-KEY has been revoked")
+)
+
+// cachedAPIKey keeps the fields hidden from the JSON of APIKey
+type cachedAPIKey struct {
+	Address string `json:"address"`
+	UUID    string `json:"uuid"`
+	Type    int    `json:"type"`
+	Status  bool   `json:"status"`
+}
+
+// authorize checks the API key of the request and counts the request against the quota of its tier,
+// the requests without an API key share the anonymous quota of their IP unless the key is required.
+// The response has been written if it's not ok.
+func authorize(c echo.Context, required bool) (bool, error) {
+	apiKey := c.Request().Header.Get(constant.API_KEY_HEADER)
+	c.Set("API-KEY", apiKey)
+
+	limiter := ratelimit.Global()
+
+	var (
+		subject string
+		tier    = limiter.Anonymous()
+	)
+
+	if len(apiKey) == 0 {
+		if required {
+			return false, c.JSON(http.StatusUnauthorized, &ErrorResponse{
+				Error:     ErrMissingAPIKey.Error(),
+				ErrorCode: ErrorCodeInvalidAPIKey,
+			})
+		}
+
+		// The anonymous requests are only counted if they are limited
+		if ratelimit.Unlimited(tier) {
+			return true, nil
+		}
+
+		subject = ratelimit.IPSubject(c.RealIP())
+	} else if frontendSubject, frontendTier, ok := limiter.Frontend(apiKey); ok {
+		// The key of our own front-ends isn't stored
+		subject, tier = ratelimit.KeySubject(frontendSubject), frontendTier
+	} else {
+		item, err := GetAPIKey(apiKey)
+		if errors.Is(err, ErrInvalidAPIKey) {
+			return false, c.JSON(http.StatusUnauthorized, &ErrorResponse{
+				Error:     err.Error(),
+				ErrorCode: ErrorCodeInvalidAPIKey,
+			})
+		}
+
+		// A valid key isn't rejected because it can't be checked for now
+		if err != nil {
+			return false, c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Error:     "An internal error has occurred, please try again later.",
+				ErrorCode: ErrorCodeInternalError,
+			})
+		}
+
+		if !item.Status {
+			return false, c.JSON(http.StatusForbidden, &ErrorResponse{
+				Error:     ErrAPIKeyRevoked.Error(),
+				ErrorCode: ErrorCodeAPIKeyRevoked,
+			})
+		}
+
+		subject = ratelimit.KeySubject(item.Address)
+		tier = limiter.Tier(item.Type)
+	}
+
+	return allow(c, subject, tier)
+}
+
+// allow counts a request of subject against the quota of tier, the response has been written if it's not ok
+func allow(c echo.Context, subject string, tier configx.Tier) (bool, error) {
+	result, err := ratelimit.Global().Allow(c.Request().Context(), subject, tier)
+	if err != nil {
+		// The API is still served when the counters are unavailable
+		loggerx.Global().Error("failed to count request", zap.Error(err), zap.String("subject", subject))
+
+		return true, nil
+	}
+
+	if tier.PerMinute > 0 {
+		c.Response().Header().Set(HeaderRateLimitLimit, strconv.FormatInt(result.Minute.Limit, 10))
+		c.Response().Header().Set(HeaderRateLimitRemaining, strconv.FormatInt(result.Minute.Remaining, 10))
+		c.Response().Header().Set(HeaderRateLimitReset, strconv.FormatInt(result.Minute.Reset.Unix(), 10))
+	}
+
+	if !result.Allowed {
+		c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+
+		return false, c.JSON(http.StatusTooManyRequests, &ErrorResponse{
+			Error:     fmt.Sprintf("the quota of the %s tier is exceeded", tier.Name),
+			ErrorCode: ErrorCodeRateLimited,
+		})
+	}
+
+	return true, nil
+}
+
+// GetAPIKey returns the API key of the X-API-KEY header, it fails with ErrMissingAPIKey or ErrInvalidAPIKey
+// if the key is missing or doesn't exist, and with the other errors if the key can't be got
+func GetAPIKey(apiKey string) (*model.APIKey, error) {
+	if len(apiKey) == 0 {
+		return nil, ErrMissingAPIKey
+	}
+
+	ctx := context.Background()
+
+	var cached cachedAPIKey
+
+	if exists, err := cache.GetJson(ctx, apiKeyCacheKey(apiKey), &cached); err == nil && exists {
+		return &model.APIKey{
+			Address: cached.Address,
+			UUID:    cached.UUID,
+			Type:    cached.Type,
+			Status:  cached.Status,
+		}, nil
+	}
+
+	var item model.APIKey
+
+	if err := database.Global().
+		Where("uuid = ?", apiKey).
+		First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+
+		loggerx.Global().Error("failed to get api key", zap.Error(err))
+
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+
+	_ = cache.SetJson(ctx, apiKeyCacheKey(apiKey), cachedAPIKey{
+		Address: item.Address,
+		UUID:    item.UUID,
+		Type:    item.Type,
+		Status:  item.Status,
+	}, apiKeyCacheTTL)
+
+	return &item, nil
+}
+
+// InvalidateAPIKey drops the cache of an API key, it must be called after the key is changed
+func InvalidateAPIKey(ctx context.Context, apiKey string) error {
+	return cache.Global().Del(ctx, apiKeyCacheKey(apiKey)).Err()
+}
+
+func apiKeyCacheKey(apiKey string) string {
+	return "apikey:" + apiKey
+}
@@ -4,6 +4,9 @@ import (
 	"time"
 
 	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/approval"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/portfolio"
+	"github.com/shopspring/decimal"
 )
 
 const (
@@ -12,6 +15,7 @@ const (
 
 	// path
 	GetNotes             = "/notes/"
+	GetNotesStream       = "/notes/stream/"
 	GetNotesByPlatform   = "platform/notes/"
 	PostNotes            = "/notes"
 	PostSocialNotes      = "/notes/social"
@@ -20,12 +24,23 @@ const (
 	GetNS                = "/ns/"
 	GetTransactionByHash = "/tx/"
 	GetMastodon          = "/mastodon/"
+	PostGraphQL          = "/graphql"
+	SearchNotes          = "/notes/search"
+	GetPortfolio         = "/portfolio/"
+	GetPnL               = "/pnl/"
+	ExportNotes          = "/notes/export"
+	GetApprovals         = "/approvals/"
 
 	EsIndex = "pregod-v1-visit-path"
 
 	Subscribe   = "subscribe"
 	Unsubscribe = "unsubscribe"
 	Query       = "query"
+
+	// events pushed to subscribers
+	EventNotes     = "notes"
+	EventHeartbeat = "heartbeat"
+	EventError     = "error"
 )
 
 type Response struct {
@@ -40,6 +55,7 @@ type GetRequest struct {
 	Address   string    `param:"address" json:"address" validate:"required" description:"address to query"`
 	Limit     int       `query:"limit" json:"limit"`
 	Cursor    string    `query:"cursor" json:"cursor"`
+	Page      int       `query:"page" json:"page" description:"removed, pass the cursor of the previous response instead"`
 	Type      []string  `query:"type" json:"type"`
 	Tag       []string  `query:"tag" json:"tag" validate:"required_with=Type"`
 	Network   []string  `query:"network" json:"network"`
@@ -50,12 +66,15 @@ type GetRequest struct {
 	// includes POAP in the response
 	IncludePoap bool   `query:"include_poap" json:"include_poap"`
 	Refresh     bool   `query:"refresh" json:"refresh"`
-	Page        int    `query:"page" json:"page"`
 	QueryStatus bool   `query:"query_status" json:"query_status"`
 	TokenId     string `query:"token_id" json:"token_id"`
 	// returns a count of transactions only
 	CountOnly   bool `query:"count_only" json:"count_only"`
 	ActionLimit int  `query:"action_limit" json:"action_limit"`
+	// counts the total of the transactions, which is slow for the addresses with many transactions
+	WithTotal bool `query:"with_total" json:"with_total"`
+	// excludes the transactions which may still be reorganized
+	FinalizedOnly bool `query:"finalized_only" json:"finalized_only"`
 }
 
 type GetNameResolveRequest struct {
@@ -84,9 +103,10 @@ type BatchGetNotesRequest struct {
 	Cursor         string    `json:"cursor"`
 	Refresh        bool      `json:"refresh"`
 	IncludePoap    bool      `json:"include_poap"`
-	Page           int       `json:"page"`
+	Page           int       `json:"page" description:"removed, pass the cursor of the previous response instead"`
 	QueryStatus    bool      `json:"query_status"`
 	CountOnly      bool      `json:"count_only"`
+	WithTotal      bool      `json:"with_total"`
 	IgnoreContract bool      `json:"ignore_contract"`
 	ActionLimit    int       `query:"action_limit" json:"action_limit"`
 }
@@ -100,8 +120,9 @@ type BatchGetSocialNotesRequest struct {
 	Timestamp time.Time `json:"timestamp"`
 	Limit     int       `json:"limit"`
 	Cursor    string    `json:"cursor"`
-	Page      int       `json:"page"`
+	Page      int       `json:"page" description:"removed, pass the cursor of the previous response instead"`
 	CountOnly bool      `json:"count_only"`
+	WithTotal bool      `json:"with_total"`
 }
 
 type BatchGetProfilesRequest struct {
@@ -112,17 +133,170 @@ type BatchGetProfilesRequest struct {
 }
 
 type GetNotesByPlatformRequest struct {
-	Platform string `param:"platform" json:"platform" validate:"required" description:"platform to query"`
-	Limit    int    `query:"limit" json:"limit"`
-	Cursor   string `query:"cursor" json:"cursor"`
+	Platform  string `param:"platform" json:"platform" validate:"required" description:"platform to query"`
+	Limit     int    `query:"limit" json:"limit"`
+	Cursor    string `query:"cursor" json:"cursor"`
+	WithTotal bool   `query:"with_total" json:"with_total"`
+}
+
+type SearchNotesRequest struct {
+	// Keyword supports the web search syntax, such as "quoted phrases", OR and -excluded words
+	Keyword  string   `query:"keyword" json:"keyword" validate:"required" description:"keyword to search the posts for"`
+	Address  []string `query:"address" json:"address" description:"addresses of the authors"`
+	Network  []string `query:"network" json:"network"`
+	Platform []string `query:"platform" json:"platform"`
+	// Since and Until limit the timestamp of the notes, in RFC 3339
+	Since     time.Time `query:"since" json:"since"`
+	Until     time.Time `query:"until" json:"until"`
+	Limit     int       `query:"limit" json:"limit"`
+	Cursor    string    `query:"cursor" json:"cursor"`
+	WithTotal bool      `query:"with_total" json:"with_total"`
+}
+
+// SearchNoteResult is an action of a social note matching the keyword
+type SearchNoteResult struct {
+	Hash      string    `json:"hash"`
+	Network   string    `json:"network"`
+	Timestamp time.Time `json:"timestamp"`
+	Rank      float32   `json:"rank"`
+	// Snippet is the post content around the matched words, which are wrapped in <b> tags
+	Snippet string           `json:"snippet"`
+	Action  dbModel.Transfer `json:"action"`
+}
+
+type GetPortfolioRequest struct {
+	Address string   `param:"address" json:"address" validate:"required" description:"address to query"`
+	Network []string `query:"network" json:"network"`
+	// compares the balances with the ones on chain, which is slow for the addresses with many tokens
+	Reconcile bool  `query:"reconcile" json:"reconcile"`
+	BlockSpam *bool `query:"block_spam" json:"block_spam"` // Default true
+}
+
+// Portfolio is the tokens held by an address, computed from its indexed transfers and fees
+type Portfolio struct {
+	Address string `json:"address"`
+	// ValueUSD is the total value of the holdings with a known price
+	ValueUSD decimal.Decimal     `json:"value_usd"`
+	Holdings []portfolio.Holding `json:"holdings"`
+	// Drifts are the holdings whose balances differ from the ones on chain, only reconciled on request
+	Drifts []portfolio.Drift `json:"drifts,omitempty"`
+}
+
+type GetPnLRequest struct {
+	Address string   `param:"address" json:"address" validate:"required" description:"address to query"`
+	Network []string `query:"network" json:"network"`
+	// fifo or average, defaults to fifo
+	Method string `query:"method" json:"method" validate:"omitempty,oneof=fifo average" description:"fifo or average"`
+	// Since and Until limit the timestamp of the disposals realizing the profit and loss, in RFC 3339.
+	// The positions are valued at Until if it is set.
+	Since time.Time `query:"since" json:"since"`
+	Until time.Time `query:"until" json:"until"`
+}
+
+// PnL is the cost basis and profit and loss of the tokens and NFT collections traded by an address, in USD
+type PnL struct {
+	Address       string               `json:"address"`
+	Method        string               `json:"method"`
+	RealizedPnL   decimal.Decimal      `json:"realized_pnl"`
+	UnrealizedPnL decimal.Decimal      `json:"unrealized_pnl"`
+	Positions     []portfolio.Position `json:"positions"`
+}
+
+type GetApprovalsRequest struct {
+	Address string   `param:"address" json:"address" validate:"required" description:"address to query"`
+	Network []string `query:"network" json:"network"`
+	Verify  *bool    `query:"verify" json:"verify"` // Default true
+}
+
+// Approvals is the live approvals of the tokens held by an address, with their risks
+type Approvals struct {
+	Address   string              `json:"address"`
+	Approvals []approval.Approval `json:"approvals"`
+	// Levels is the number of the approvals at each risk level
+	Levels map[string]int `json:"levels"`
+}
+
+type ExportNotesRequest struct {
+	Address []string `query:"address" json:"address" validate:"required" description:"addresses to export"`
+	Network []string `query:"network" json:"network"`
+	// csv, koinly or cointracker, defaults to csv
+	Format string `query:"format" json:"format" validate:"omitempty,oneof=csv koinly cointracker" description:"csv, koinly or cointracker"`
+	// Since and Until limit the timestamp of the notes, in RFC 3339
+	Since time.Time `query:"since" json:"since"`
+	Until time.Time `query:"until" json:"until"`
 }
 
-type GetAPIKeyRequest struct {
-	Address string `param:"address" json:"address" validate:"required"`
+// Pagination is the position of a page of transactions
+type Pagination struct {
+	// Cursor is the cursor of the next page, it's empty on the last page
+	Cursor string
+	// Total is the total of the transactions, it's only counted on request
+	Total *int64
 }
 
-type PostAPIKeyRequest struct {
-	Address string `json:"address" validate:"required"`
+type GraphQLRequest struct {
+	Query         string         `json:"query" validate:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+type GetAPIKeyNonceRequest struct {
+	Address string `query:"address" json:"address" validate:"required,eth_addr" description:"the address signing the message"`
+	Action  string `query:"action" json:"action" validate:"required,oneof=apply rotate revoke list" description:"apply, rotate, revoke or list"`
+}
+
+type GetAPIKeyNonceResult struct {
+	Nonce string `json:"nonce"`
+	// Message is the Sign-In with Ethereum message to be signed with personal_sign
+	Message   string    `json:"message"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// SignedAPIKeyRequest is a Sign-In with Ethereum message over a nonce and its signature
+type SignedAPIKeyRequest struct {
+	Message   string `json:"message" validate:"required"`
+	Signature string `json:"signature" validate:"required"`
+}
+
+type APIKeyResult struct {
+	Address   string    `json:"address"`
+	Key       string    `json:"key"`
+	Tier      string    `json:"tier"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GetAPIKeyUsageRequest struct {
+	// the days of the daily usage history, 30 at most
+	Days int `query:"days"`
+}
+
+type PostWebhookRequest struct {
+	URL      string   `json:"url" validate:"required,url" description:"the https URL receiving the payloads, it must resolve to public addresses"`
+	Address  []string `json:"address" validate:"required,min=1" description:"addresses whose notes are delivered"`
+	Tag      []string `json:"tag"`
+	Type     []string `json:"type"`
+	Network  []string `json:"network"`
+	Platform []string `json:"platform"`
+}
+
+type WebhookRequest struct {
+	ID string `param:"id" validate:"required"`
+}
+
+type GetWebhookDeliveriesRequest struct {
+	ID     string `param:"id" validate:"required"`
+	Status string `query:"status" validate:"omitempty,oneof=pending succeeded failed"`
+	Limit  int    `query:"limit"`
+	// the id of the last delivery of the previous page
+	Cursor string `query:"cursor"`
+}
+
+type ReplayWebhookRequest struct {
+	ID string `param:"id" validate:"required"`
+	// the failed deliveries to replay, all of them if it is empty
+	DeliveryID []string `json:"delivery_id"`
 }
 
 type GetTransactionRequest struct {
@@ -168,6 +342,12 @@ type WebSocketRequest struct {
 	Action     string   `json:"action"`
 	AddressArr []string `json:"address"`
 	ClientId   string   `json:"client_id"`
+	Tag        []string `json:"tag"`
+	Type       []string `json:"type"`
+	Network    []string `json:"network"`
+	Platform   []string `json:"platform"`
+	// the hash of the last received note, the notes since it are sent on subscribing
+	Cursor string `json:"cursor"`
 }
 
 type WebsocketResponse struct {
@@ -176,6 +356,14 @@ type WebsocketResponse struct {
 	Result map[string]any `json:"result"`
 }
 
+type WebsocketNotification struct {
+	Event     string                `json:"event"`
+	Cursor    string                `json:"cursor,omitempty"`
+	Result    []dbModel.Transaction `json:"result,omitempty"`
+	Message   string                `json:"message,omitempty"`
+	Timestamp *time.Time            `json:"timestamp,omitempty"`
+}
+
 type Transactions []dbModel.Transaction
 
 // Len()
@@ -0,0 +1,121 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+)
+
+const (
+	// Order is the order of the notes from the latest, the hash, network and owner break the ties of timestamp and index
+	Order = "timestamp DESC, index DESC, hash DESC, network DESC, owner DESC"
+	// OrderAscending is the reverse of Order, from the oldest
+	OrderAscending = "timestamp ASC, index ASC, hash ASC, network ASC, owner ASC"
+
+	// Before and After compare the columns of Order as a row with the Values of a cursor
+	Before = "(timestamp, index, hash, network, owner) < (?, ?, ?, ?, ?)"
+	After  = "(timestamp, index, hash, network, owner) > (?, ?, ?, ?, ?)"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrPageRemoved rejects the requests paginating by page, which would get the first page forever
+	ErrPageRemoved = errors.New("page is no longer supported, pass the cursor of the previous response instead")
+)
+
+// Cursor is the position of a note in Order, the owner tells apart the same transaction of several addresses
+type Cursor struct {
+	Timestamp time.Time `json:"t"`
+	Index     int64     `json:"i"`
+	Hash      string    `json:"h"`
+	Network   string    `json:"n"`
+	Owner     string    `json:"o"`
+}
+
+// String encodes the cursor, clients should treat it as opaque
+func (c Cursor) String() string {
+	return encode(c)
+}
+
+// Values are the values of Columns
+func (c Cursor) Values() []any {
+	return []any{c.Timestamp, c.Index, c.Hash, c.Network, c.Owner}
+}
+
+// Compare returns -1 if c is before other in Order, 1 if it's after, and 0 if they're the same note
+func (c Cursor) Compare(other Cursor) int {
+	switch {
+	case !c.Timestamp.Equal(other.Timestamp):
+		return descending(c.Timestamp.After(other.Timestamp))
+	case c.Index != other.Index:
+		return descending(c.Index > other.Index)
+	case c.Hash != other.Hash:
+		return descending(c.Hash > other.Hash)
+	case c.Network != other.Network:
+		return descending(c.Network > other.Network)
+	case c.Owner != other.Owner:
+		return descending(c.Owner > other.Owner)
+	default:
+		return 0
+	}
+}
+
+// Parse decodes a cursor returned by String
+func Parse(value string) (*Cursor, error) {
+	var cursor Cursor
+
+	if err := decode(value, &cursor); err != nil || len(cursor.Hash) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// New returns the cursor of transaction
+func New(transaction dbModel.Transaction) Cursor {
+	return Cursor{
+		Timestamp: transaction.Timestamp,
+		Index:     transaction.Index,
+		Hash:      transaction.Hash,
+		Network:   transaction.Network,
+		Owner:     transaction.Owner,
+	}
+}
+
+// Next trims transactions queried with one more than limit to a page,
+// and returns the cursor of the next page, which is empty on the last page
+func Next(transactions []dbModel.Transaction, limit int) ([]dbModel.Transaction, string) {
+	if limit <= 0 || len(transactions) <= limit {
+		return transactions, ""
+	}
+
+	transactions = transactions[:limit]
+
+	return transactions, New(transactions[len(transactions)-1]).String()
+}
+
+func encode(cursor any) string {
+	data, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(value string, cursor any) error {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, cursor)
+}
+
+func descending(after bool) int {
+	if after {
+		return -1
+	}
+
+	return 1
+}
@@ -0,0 +1,133 @@
+package pagination
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cursor := Cursor{
+		Timestamp: time.Date(2023, 1, 1, 0, 0, 0, 123456000, time.UTC),
+		Index:     7,
+		Hash:      "0x1",
+		Network:   protocol.NetworkEthereum,
+		Owner:     "0xa",
+	}
+
+	parsed, err := Parse(cursor.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cursor.Compare(*parsed))
+	assert.True(t, cursor.Timestamp.Equal(parsed.Timestamp))
+
+	for _, value := range []string{"", "0x1", "not base64!", Cursor{}.String()} {
+		_, err := Parse(value)
+		assert.ErrorIs(t, err, ErrInvalidCursor, value)
+	}
+}
+
+func TestNext(t *testing.T) {
+	transactions := []dbModel.Transaction{{Hash: "0x3"}, {Hash: "0x2"}, {Hash: "0x1"}}
+
+	page, cursor := Next(transactions, 3)
+	assert.Len(t, page, 3)
+	assert.Empty(t, cursor, "last page")
+
+	page, cursor = Next(transactions, 2)
+	assert.Len(t, page, 2)
+
+	parsed, err := Parse(cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x2", parsed.Hash)
+}
+
+// TestTies pages through notes sharing timestamps, indexes, hashes and networks,
+// every note must be returned once and in order whatever the page size is
+func TestTies(t *testing.T) {
+	timestamp := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var notes []dbModel.Transaction
+
+	for _, hash := range []string{"0x1", "0x2"} {
+		for _, network := range []string{protocol.NetworkEthereum, protocol.NetworkPolygon} {
+			for _, owner := range []string{"0xa", "0xb"} {
+				for index := int64(0); index < 2; index++ {
+					notes = append(notes, dbModel.Transaction{
+						Timestamp: timestamp,
+						Index:     index,
+						Hash:      hash,
+						Network:   network,
+						Owner:     owner,
+					})
+				}
+			}
+		}
+	}
+
+	notes = append(notes,
+		dbModel.Transaction{Timestamp: timestamp.Add(time.Second), Hash: "0x0", Network: protocol.NetworkEthereum, Owner: "0xa"},
+		dbModel.Transaction{Timestamp: timestamp.Add(-time.Second), Hash: "0x9", Network: protocol.NetworkEthereum, Owner: "0xa"},
+	)
+
+	sorted := append([]dbModel.Transaction(nil), notes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return New(sorted[i]).Compare(New(sorted[j])) < 0
+	})
+
+	assert.Equal(t, "0x0", sorted[0].Hash, "latest first")
+	assert.Equal(t, "0x9", sorted[len(sorted)-1].Hash, "oldest last")
+
+	for limit := 1; limit <= len(notes)+1; limit++ {
+		var (
+			result []dbModel.Transaction
+			cursor string
+		)
+
+		for {
+			page, next := Next(query(notes, cursor, limit+1), limit)
+			result = append(result, page...)
+
+			if len(next) == 0 {
+				break
+			}
+
+			cursor = next
+		}
+
+		assert.Equal(t, sorted, result, "limit %d", limit)
+	}
+}
+
+// query mimics the keyset query of the notes after cursor
+func query(notes []dbModel.Transaction, cursor string, limit int) []dbModel.Transaction {
+	result := make([]dbModel.Transaction, 0)
+
+	for _, note := range notes {
+		if len(cursor) > 0 {
+			parsed, err := Parse(cursor)
+			if err != nil {
+				panic(err)
+			}
+
+			if New(note).Compare(*parsed) <= 0 {
+				continue
+			}
+		}
+
+		result = append(result, note)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return New(result[i]).Compare(New(result[j])) < 0
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
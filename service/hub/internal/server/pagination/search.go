@@ -0,0 +1,44 @@
+package pagination
+
+import (
+	"time"
+)
+
+const (
+	// SearchOrder is the order of the search results from the most relevant, the latest first on the same rank
+	SearchOrder = "rank DESC, timestamp DESC, transaction_hash DESC, network DESC, index DESC"
+
+	// SearchBefore compares the columns of SearchOrder as a row with the Values of a search cursor,
+	// rank is a real so that the encoded ranks compare equal
+	SearchBefore = "(rank, timestamp, transaction_hash, network, index) < (?::REAL, ?, ?, ?, ?)"
+)
+
+// SearchCursor is the position of an action in SearchOrder
+type SearchCursor struct {
+	Rank      float32   `json:"r"`
+	Timestamp time.Time `json:"t"`
+	Hash      string    `json:"h"`
+	Network   string    `json:"n"`
+	Index     int64     `json:"i"`
+}
+
+// String encodes the cursor, clients should treat it as opaque
+func (c SearchCursor) String() string {
+	return encode(c)
+}
+
+// Values are the values of the columns of SearchBefore
+func (c SearchCursor) Values() []any {
+	return []any{c.Rank, c.Timestamp, c.Hash, c.Network, c.Index}
+}
+
+// ParseSearch decodes a cursor returned by SearchCursor.String
+func ParseSearch(value string) (*SearchCursor, error) {
+	var cursor SearchCursor
+
+	if err := decode(value, &cursor); err != nil || len(cursor.Hash) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
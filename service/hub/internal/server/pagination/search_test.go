@@ -0,0 +1,29 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSearch(t *testing.T) {
+	cursor := SearchCursor{
+		Rank:      0.0607927,
+		Timestamp: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Hash:      "0x1",
+		Network:   protocol.NetworkPolygon,
+		Index:     2,
+	}
+
+	parsed, err := ParseSearch(cursor.String())
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, *parsed, "the rank is kept as is")
+
+	_, err = ParseSearch(Cursor{Hash: "0x1"}.String()[:4])
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = ParseSearch(SearchCursor{Rank: 1}.String())
+	assert.ErrorIs(t, err, ErrInvalidCursor, "without hash")
+}
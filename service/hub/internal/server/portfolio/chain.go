@@ -0,0 +1,149 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/naturalselectionlabs/pregod/common/datasource/ethereum/contract/erc20"
+	"github.com/naturalselectionlabs/pregod/common/datasource/ethereum/contract/erc721"
+	"github.com/naturalselectionlabs/pregod/common/datasource/ethereum/contract/friendtech"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/shopspring/decimal"
+)
+
+// The generated ERC-1155 binding has no balanceOf
+var erc1155BalanceOfABI, _ = abi.JSON(strings.NewReader(`[{"inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`))
+
+// Client is the node of a network, such as an *ethclient.Client
+type Client interface {
+	bind.ContractCaller
+
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// Drift is the difference between the balance computed from the movements and the one on chain.
+// The ERC-721 tokens are compared by collection, since the number of items is all the contracts report.
+type Drift struct {
+	Token
+
+	Balance decimal.Decimal `json:"balance"`
+	// OnChainBalance and Difference are nil if the balance can't be read from the contract
+	OnChainBalance *decimal.Decimal `json:"on_chain_balance,omitempty"`
+	// Difference is the on-chain balance minus the computed one
+	Difference *decimal.Decimal `json:"difference,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// Reconcile compares the holdings of address with the balances on chain, it returns the tokens whose balances differ
+// or can't be read, such as the ones of the contracts not following the standards
+func Reconcile(ctx context.Context, client Client, address string, holdings []Holding) []Drift {
+	var (
+		drifts      = make([]Drift, 0)
+		collections = make([]Token, 0)
+		counts      = make(map[Token]decimal.Decimal)
+	)
+
+	reconcile := func(token Token, balance decimal.Decimal, decimals uint8) {
+		onChainBalance, err := BalanceOf(ctx, client, address, token, decimals)
+		if err != nil {
+			drifts = append(drifts, Drift{Token: token, Balance: balance, Error: err.Error()})
+
+			return
+		}
+
+		if !balance.Equal(onChainBalance) {
+			difference := onChainBalance.Sub(balance)
+
+			drifts = append(drifts, Drift{Token: token, Balance: balance, OnChainBalance: &onChainBalance, Difference: &difference})
+		}
+	}
+
+	for _, holding := range holdings {
+		if holding.Standard == protocol.TokenStandardERC721 {
+			collection := Token{Network: holding.Network, Standard: holding.Standard, ContractAddress: holding.ContractAddress}
+			if _, exists := counts[collection]; !exists {
+				collections = append(collections, collection)
+			}
+
+			counts[collection] = counts[collection].Add(holding.Balance)
+
+			continue
+		}
+
+		reconcile(holding.Token, holding.Balance, holding.Decimals)
+	}
+
+	for _, collection := range collections {
+		reconcile(collection, counts[collection], 0)
+	}
+
+	return drifts
+}
+
+// BalanceOf returns the balance of token held by address on chain, in the display unit for the fungible tokens
+func BalanceOf(ctx context.Context, client Client, address string, token Token, decimals uint8) (decimal.Decimal, error) {
+	var (
+		owner   = common.HexToAddress(address)
+		opts    = &bind.CallOpts{Context: ctx}
+		balance *big.Int
+		err     error
+	)
+
+	switch token.Standard {
+	case protocol.TokenStandardNative:
+		if balance, err = client.BalanceAt(ctx, owner, nil); err != nil {
+			return decimal.Zero, err
+		}
+
+		return decimal.NewFromBigInt(balance, -NativeDecimals), nil
+	case protocol.TokenStandardERC20:
+		caller, err := erc20.NewERC20Caller(common.HexToAddress(token.ContractAddress), client)
+		if err != nil {
+			return decimal.Zero, err
+		}
+
+		if balance, err = caller.BalanceOf(opts, owner); err != nil {
+			return decimal.Zero, err
+		}
+
+		return decimal.NewFromBigInt(balance, -int32(decimals)), nil
+	case protocol.TokenStandardERC721:
+		var caller *erc721.ERC721Caller
+
+		if caller, err = erc721.NewERC721Caller(common.HexToAddress(token.ContractAddress), client); err == nil {
+			balance, err = caller.BalanceOf(opts, owner)
+		}
+	case protocol.TokenStandardERC1155:
+		id, ok := new(big.Int).SetString(token.ID, 10)
+		if !ok {
+			return decimal.Zero, fmt.Errorf("invalid token id %s", token.ID)
+		}
+
+		var outputs []any
+
+		contract := bind.NewBoundContract(common.HexToAddress(token.ContractAddress), erc1155BalanceOfABI, client, nil, nil)
+		if err = contract.Call(opts, &outputs, "balanceOf", owner, id); err == nil {
+			balance = *abi.ConvertType(outputs[0], new(*big.Int)).(**big.Int)
+		}
+	case protocol.TokenStandardFriendTechShare:
+		var caller *friendtech.FriendTechCaller
+
+		// The shares are identified by the address of their subject
+		if caller, err = friendtech.NewFriendTechCaller(friendtech.AddressFriendTech, client); err == nil {
+			balance, err = caller.SharesBalance(opts, common.HexToAddress(token.ContractAddress), owner)
+		}
+	default:
+		return decimal.Zero, fmt.Errorf("unsupported token standard %s", token.Standard)
+	}
+
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return decimal.NewFromBigInt(balance, 0), nil
+}
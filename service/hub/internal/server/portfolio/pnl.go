@@ -0,0 +1,291 @@
+package portfolio
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// MethodFIFO disposes the earliest acquired lots first
+	MethodFIFO = "fifo"
+	// MethodAverage disposes at the average cost of all the lots held
+	MethodAverage = "average"
+
+	// divisionPrecision is the decimal places of the costs apportioned to the disposed parts of lots
+	divisionPrecision = 18
+)
+
+// Position is the cost basis and profit and loss of a fungible token, or of an NFT collection
+type Position struct {
+	// Token has no ID for NFT collections
+	Token
+
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+
+	Balance decimal.Decimal `json:"balance"`
+	// CostBasis is the cost of the balance held
+	CostBasis decimal.Decimal `json:"cost_basis"`
+	// Proceeds and RealizedPnL are made by the disposals within the time range
+	Proceeds    decimal.Decimal `json:"proceeds"`
+	RealizedPnL decimal.Decimal `json:"realized_pnl"`
+	// ValueUSD and UnrealizedPnL are nil if the token has no price
+	ValueUSD      *decimal.Decimal `json:"value_usd,omitempty"`
+	UnrealizedPnL *decimal.Decimal `json:"unrealized_pnl,omitempty"`
+	// Incomplete means some movements have no USD value or some disposals exceed the acquisitions indexed,
+	// they're taken as zero cost
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// PriceFunc returns the USD price of a fungible token, it's nil if the price is unknown
+type PriceFunc func(token Token) *decimal.Decimal
+
+type lot struct {
+	amount decimal.Decimal
+	cost   decimal.Decimal
+}
+
+type position struct {
+	Position
+
+	// lots are held by the ID of items, the fungible tokens have a single ID
+	lots map[string][]lot
+}
+
+// Calculator computes the positions of an address by replaying its movements from the oldest.
+// A leg of a trade, such as a swap or an NFT bought with a token, is valued at the other legs,
+// the other movements are valued at their own USD values at the time of transaction, except fees which make no proceeds.
+type Calculator struct {
+	method    string
+	since     time.Time
+	until     time.Time
+	positions map[Token]*position
+}
+
+// Add replays the movements ordered from the oldest, the movements of a transfer must be adjacent
+func (c *Calculator) Add(movements []Movement) {
+	for start := 0; start < len(movements); {
+		end := start + 1
+
+		for end < len(movements) && sameTransfer(movements[start], movements[end]) {
+			end++
+		}
+
+		legs := movements[start:end]
+
+		for index, movement := range legs {
+			if !c.until.IsZero() && !movement.Timestamp.Before(c.until) {
+				return
+			}
+
+			c.add(movement, legValue(legs, index))
+		}
+
+		start = end
+	}
+}
+
+func (c *Calculator) add(movement Movement, value *decimal.Decimal) {
+	key := movement.Token
+	if !key.Fungible() {
+		key.ID = ""
+	}
+
+	holding, exists := c.positions[key]
+	if !exists {
+		holding = &position{
+			Position: Position{Token: key},
+			lots:     make(map[string][]lot),
+		}
+
+		c.positions[key] = holding
+	}
+
+	switch {
+	case key.Fungible() && movement.Symbol != "":
+		holding.Name, holding.Symbol = movement.Name, movement.Symbol
+	case !key.Fungible() && movement.Collection != "":
+		// The name of an item isn't the name of its collection
+		holding.Name, holding.Symbol = movement.Collection, movement.Symbol
+	}
+
+	cost := decimal.Zero
+
+	switch {
+	case movement.Fee:
+		// A fee is disposed for nothing, it realizes the loss of its cost basis
+	case value == nil:
+		holding.Incomplete = true
+	default:
+		cost = *value
+	}
+
+	if movement.Amount.IsPositive() {
+		c.acquire(holding, movement.ID, movement.Amount, cost)
+
+		return
+	}
+
+	amount := movement.Amount.Neg()
+	disposedCost := c.dispose(holding, movement.ID, amount)
+
+	if c.since.IsZero() || !movement.Timestamp.Before(c.since) {
+		holding.Proceeds = holding.Proceeds.Add(cost)
+		holding.RealizedPnL = holding.RealizedPnL.Add(cost.Sub(disposedCost))
+	}
+}
+
+func (c *Calculator) acquire(holding *position, id string, amount, cost decimal.Decimal) {
+	holding.Balance = holding.Balance.Add(amount)
+	holding.CostBasis = holding.CostBasis.Add(cost)
+
+	lots := holding.lots[id]
+
+	if c.method == MethodAverage && len(lots) > 0 {
+		lots[0].amount, lots[0].cost = lots[0].amount.Add(amount), lots[0].cost.Add(cost)
+
+		return
+	}
+
+	holding.lots[id] = append(lots, lot{amount: amount, cost: cost})
+}
+
+// dispose removes amount from the lots from the earliest one, it returns the cost of the amount removed
+func (c *Calculator) dispose(holding *position, id string, amount decimal.Decimal) decimal.Decimal {
+	var (
+		lots = holding.lots[id]
+		cost = decimal.Zero
+	)
+
+	for len(lots) > 0 && amount.IsPositive() {
+		if lots[0].amount.LessThanOrEqual(amount) {
+			amount = amount.Sub(lots[0].amount)
+			cost = cost.Add(lots[0].cost)
+			lots = lots[1:]
+
+			continue
+		}
+
+		partCost := lots[0].cost.Mul(amount).DivRound(lots[0].amount, divisionPrecision)
+
+		lots[0].amount, lots[0].cost = lots[0].amount.Sub(amount), lots[0].cost.Sub(partCost)
+		cost = cost.Add(partCost)
+		amount = decimal.Zero
+	}
+
+	// The history of the address isn't fully indexed
+	if amount.IsPositive() {
+		holding.Incomplete = true
+	}
+
+	if len(lots) == 0 {
+		delete(holding.lots, id)
+	} else {
+		holding.lots[id] = lots
+	}
+
+	holding.Balance, holding.CostBasis = decimal.Zero, decimal.Zero
+
+	for _, lots := range holding.lots {
+		for _, lot := range lots {
+			holding.Balance = holding.Balance.Add(lot.amount)
+			holding.CostBasis = holding.CostBasis.Add(lot.cost)
+		}
+	}
+
+	return cost
+}
+
+// Positions returns the positions ordered by network, standard and contract address,
+// the unrealized profit and loss of the fungible tokens held is valued by price
+func (c *Calculator) Positions(price PriceFunc) []Position {
+	positions := make([]Position, 0, len(c.positions))
+
+	for _, holding := range c.positions {
+		result := holding.Position
+
+		if result.Fungible() && result.Balance.IsPositive() && price != nil {
+			if tokenPrice := price(result.Token); tokenPrice != nil {
+				value := result.Balance.Mul(*tokenPrice).Round(2)
+				unrealizedPnL := value.Sub(result.CostBasis)
+
+				result.ValueUSD, result.UnrealizedPnL = &value, &unrealizedPnL
+			}
+		}
+
+		positions = append(positions, result)
+	}
+
+	sort.Slice(positions, func(i, j int) bool {
+		a, b := positions[i].Token, positions[j].Token
+
+		switch {
+		case a.Network != b.Network:
+			return a.Network < b.Network
+		case a.Standard != b.Standard:
+			return a.Standard < b.Standard
+		default:
+			return a.ContractAddress < b.ContractAddress
+		}
+	})
+
+	return positions
+}
+
+func sameTransfer(a, b Movement) bool {
+	return a.TransactionHash == b.TransactionHash && a.Network == b.Network && a.Index == b.Index && a.Fee == b.Fee
+}
+
+// legValue returns the USD value of a leg of a transfer, the sum of the other legs in the opposite direction
+// if all of them are valued, or its own value otherwise
+func legValue(legs []Movement, index int) *decimal.Decimal {
+	var (
+		leg      = legs[index]
+		value    = decimal.Zero
+		counters int
+	)
+
+	for counterIndex, counter := range legs {
+		if counterIndex == index || counter.Amount.IsPositive() == leg.Amount.IsPositive() {
+			continue
+		}
+
+		if counter.ValueUSD == nil {
+			return ownValue(leg)
+		}
+
+		value = value.Add(*counter.ValueUSD)
+		counters++
+	}
+
+	if counters == 0 {
+		return ownValue(leg)
+	}
+
+	return &value
+}
+
+func ownValue(movement Movement) *decimal.Decimal {
+	if movement.ValueUSD == nil {
+		return nil
+	}
+
+	value := movement.ValueUSD.Abs()
+
+	return &value
+}
+
+func NewCalculator(method string, since, until time.Time) *Calculator {
+	if method != MethodAverage {
+		method = MethodFIFO
+	}
+
+	return &Calculator{
+		method:    method,
+		since:     since,
+		until:     until,
+		positions: make(map[Token]*position),
+	}
+}
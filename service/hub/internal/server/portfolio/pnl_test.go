@@ -0,0 +1,130 @@
+package portfolio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ether = Token{Network: protocol.NetworkEthereum, Standard: protocol.TokenStandardNative}
+	usdc  = Token{Network: protocol.NetworkEthereum, Standard: protocol.TokenStandardERC20, ContractAddress: testutil.USDC}
+)
+
+func movement(token Token, hash string, index int64, timestamp int64, amount, valueUSD string) Movement {
+	result := Movement{
+		Token:           token,
+		Amount:          decimal.RequireFromString(amount),
+		TransactionHash: hash,
+		Index:           index,
+		Timestamp:       time.Unix(timestamp, 0),
+	}
+
+	if valueUSD != "" {
+		value := decimal.RequireFromString(valueUSD)
+		result.ValueUSD = &value
+	}
+
+	return result
+}
+
+func pnlMovements() []Movement {
+	return []Movement{
+		movement(ether, "0x01", 0, 100, "1", "1000"),
+		movement(ether, "0x02", 0, 200, "1", "2000"),
+		// Swap 1.5 ETH for 4500 USDC
+		movement(ether, "0x03", 0, 300, "-1.5", "4400"),
+		movement(usdc, "0x03", 0, 300, "4500", "4500"),
+	}
+}
+
+func prices(token Token) *decimal.Decimal {
+	price := map[Token]decimal.Decimal{
+		ether: decimal.NewFromInt(4000),
+		usdc:  decimal.NewFromInt(1),
+	}[token]
+
+	return &price
+}
+
+func TestCalculator(t *testing.T) {
+	testcases := []struct {
+		method     string
+		since      time.Time
+		realized   string
+		costBasis  string
+		unrealized string
+	}{
+		// 1 ETH at 1000 and 0.5 ETH at 2000 are disposed for 4500
+		{method: MethodFIFO, realized: "2500", costBasis: "1000", unrealized: "1000"},
+		// 1.5 ETH at the average cost of 1500 are disposed for 4500
+		{method: MethodAverage, realized: "2250", costBasis: "750", unrealized: "1250"},
+		// The swap is before the time range
+		{method: MethodFIFO, since: time.Unix(301, 0), realized: "0", costBasis: "1000", unrealized: "1000"},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.method, func(t *testing.T) {
+			calculator := NewCalculator(testcase.method, testcase.since, time.Time{})
+			calculator.Add(pnlMovements())
+
+			positions := calculator.Positions(prices)
+			assert.Len(t, positions, 2)
+
+			// Native is ordered before ERC-20
+			assert.Equal(t, ether, positions[1].Token)
+			assert.Equal(t, "0.5", positions[1].Balance.String())
+			assert.Equal(t, testcase.realized, positions[1].RealizedPnL.String())
+			assert.Equal(t, testcase.costBasis, positions[1].CostBasis.String())
+			assert.Equal(t, testcase.unrealized, positions[1].UnrealizedPnL.String())
+			assert.False(t, positions[1].Incomplete)
+
+			// USDC costs the value of the ETH sent for it
+			assert.Equal(t, usdc, positions[0].Token)
+			assert.Equal(t, "4400", positions[0].CostBasis.String())
+			assert.Equal(t, "100", positions[0].UnrealizedPnL.String())
+		})
+	}
+}
+
+func TestCalculatorIncomplete(t *testing.T) {
+	calculator := NewCalculator(MethodFIFO, time.Time{}, time.Unix(300, 0))
+	calculator.Add([]Movement{
+		movement(ether, "0x01", 0, 100, "1", ""),
+		movement(ether, "0x02", 0, 200, "-2", "3000"),
+		// After the time range
+		movement(ether, "0x03", 0, 300, "5", "5000"),
+	})
+
+	positions := calculator.Positions(nil)
+	assert.Len(t, positions, 1)
+	assert.True(t, positions[0].Incomplete)
+	assert.Equal(t, "0", positions[0].Balance.String())
+	assert.Equal(t, "3000", positions[0].RealizedPnL.String())
+	assert.Nil(t, positions[0].UnrealizedPnL)
+}
+
+func TestCalculatorFee(t *testing.T) {
+	fee := movement(ether, "0x02", 0, 200, "-0.1", "300")
+	fee.Fee = true
+
+	calculator := NewCalculator(MethodFIFO, time.Time{}, time.Time{})
+	calculator.Add([]Movement{
+		movement(ether, "0x01", 0, 100, "1", "1000"),
+		fee,
+	})
+
+	positions := calculator.Positions(nil)
+	assert.Len(t, positions, 1)
+
+	// The fee of 0.1 ETH at 1000 is a loss of its cost rather than a sale for its value
+	assert.Equal(t, "0.9", positions[0].Balance.String())
+	assert.Equal(t, "900", positions[0].CostBasis.String())
+	assert.Equal(t, "0", positions[0].Proceeds.String())
+	assert.Equal(t, "-100", positions[0].RealizedPnL.String())
+	assert.False(t, positions[0].Incomplete)
+}
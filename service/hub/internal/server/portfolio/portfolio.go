@@ -0,0 +1,293 @@
+package portfolio
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/database/model/metadata"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/shopspring/decimal"
+)
+
+// NativeDecimals is the decimals of the native tokens of the EVM networks
+const NativeDecimals = 18
+
+// Token identifies a holding, ID is empty for the fungible tokens and ContractAddress is empty for the native tokens.
+// The friend.tech shares are identified by the address of their subject.
+type Token struct {
+	Network         string `json:"network"`
+	Standard        string `json:"standard"`
+	ContractAddress string `json:"contract_address,omitempty"`
+	ID              string `json:"id,omitempty"`
+}
+
+// Fungible reports whether the balance of token is an amount rather than a number of items
+func (t Token) Fungible() bool {
+	return t.Standard == protocol.TokenStandardNative || t.Standard == protocol.TokenStandardERC20
+}
+
+// Movement is a change of the balance of a token held by an address
+type Movement struct {
+	Token
+
+	Name       string `json:"name"`
+	Symbol     string `json:"symbol"`
+	Collection string `json:"collection,omitempty"`
+	Decimals   uint8  `json:"decimals,omitempty"`
+	Image      string `json:"image,omitempty"`
+
+	// Amount is negative if the token is sent
+	Amount decimal.Decimal `json:"amount"`
+	// ValueUSD is the absolute value of Amount at the time of the transaction, it's nil if the price is unknown
+	ValueUSD *decimal.Decimal `json:"value_usd,omitempty"`
+
+	TransactionHash string    `json:"transaction_hash"`
+	Index           int64     `json:"index"`
+	Timestamp       time.Time `json:"timestamp"`
+	// Fee is the movement of the transaction fee, it has no index
+	Fee bool `json:"fee,omitempty"`
+}
+
+// transferMetadata covers the metadata of the transfers moving tokens, a token, a swap, the tokens of liquidity or a staked token
+type transferMetadata struct {
+	metadata.Token
+
+	From   *metadata.Token  `json:"from"`
+	To     *metadata.Token  `json:"to"`
+	Tokens []metadata.Token `json:"tokens"`
+	Staked *metadata.Token  `json:"token"`
+}
+
+// Movements returns the balance changes of address made by transfer.
+// The transfers which don't move tokens, such as approvals and posts, have no movements.
+func Movements(address string, transfer dbModel.Transfer) ([]Movement, error) {
+	if transfer.Tag == filter.TagSocial || transfer.Type == filter.TransactionApproval || transfer.Type == filter.CollectibleAuction {
+		return nil, nil
+	}
+
+	var value transferMetadata
+
+	if err := json.Unmarshal(transfer.Metadata, &value); err != nil {
+		return nil, fmt.Errorf("unmarshal metadata of transfer %s %d: %w", transfer.TransactionHash, transfer.Index, err)
+	}
+
+	var (
+		sender    = strings.EqualFold(transfer.AddressFrom, address)
+		recipient = strings.EqualFold(transfer.AddressTo, address)
+		builder   = movementBuilder{transfer: transfer}
+	)
+
+	switch {
+	case value.From != nil && value.To != nil:
+		// The trader of a swap sends a token and receives another one
+		if sender || recipient {
+			builder.add(*value.From, true)
+			builder.add(*value.To, false)
+		}
+	case value.Staked != nil:
+		// The staker sends the token staked and receives the token unstaked and the rewards
+		if sender {
+			builder.add(*value.Staked, value.Action == filter.ActionStakingStake)
+		}
+	case len(value.Tokens) > 0:
+		if sender {
+			sent := value.Action == filter.ExchangeLiquidityAdd || value.Action == filter.ExchangeLiquiditySupply || value.Action == filter.ExchangeLiquidityRepay
+
+			for _, token := range value.Tokens {
+				builder.add(token, sent)
+			}
+		}
+	default:
+		// A transfer to self doesn't change the balance
+		if sender != recipient {
+			builder.add(value.Token, sender)
+		}
+
+		// The cost of a trade is paid by the recipient of the item to the sender
+		if value.Cost != nil && sender != recipient {
+			builder.add(*value.Cost, recipient)
+		}
+	}
+
+	return builder.movements, nil
+}
+
+// FeeMovement returns the native token paid by the sender of transaction for the fee, it's nil if there is no fee
+func FeeMovement(transaction dbModel.Transaction) *Movement {
+	if transaction.Fee == nil || transaction.Fee.IsZero() {
+		return nil
+	}
+
+	movement := Movement{
+		Token: Token{
+			Network:  transaction.Network,
+			Standard: protocol.TokenStandardNative,
+		},
+		Decimals:        NativeDecimals,
+		Amount:          transaction.Fee.Neg(),
+		ValueUSD:        transaction.FeeValueUSD,
+		TransactionHash: transaction.Hash,
+		Timestamp:       transaction.Timestamp,
+		Fee:             true,
+	}
+
+	return &movement
+}
+
+type movementBuilder struct {
+	transfer  dbModel.Transfer
+	movements []Movement
+}
+
+func (b *movementBuilder) add(token metadata.Token, sent bool) {
+	amount, supported := tokenAmount(token)
+	if !supported || amount.IsZero() {
+		return
+	}
+
+	if sent {
+		amount = amount.Neg()
+	}
+
+	movement := Movement{
+		Token: Token{
+			Network:         b.transfer.Network,
+			Standard:        token.Standard,
+			ContractAddress: strings.ToLower(token.ContractAddress),
+			ID:              token.ID,
+		},
+		Name:            token.Name,
+		Symbol:          token.Symbol,
+		Collection:      token.Collection,
+		Decimals:        token.Decimals,
+		Image:           token.Image,
+		Amount:          amount,
+		ValueUSD:        token.ValueUSD,
+		TransactionHash: b.transfer.TransactionHash,
+		Index:           b.transfer.Index,
+		Timestamp:       b.transfer.Timestamp,
+	}
+
+	switch token.Standard {
+	case protocol.TokenStandardNative:
+		movement.ContractAddress = ""
+	case protocol.TokenStandardERC20:
+		movement.ID = ""
+	}
+
+	b.movements = append(b.movements, movement)
+}
+
+// tokenAmount returns the number of token moved, in the display unit for the fungible tokens
+func tokenAmount(token metadata.Token) (decimal.Decimal, bool) {
+	switch token.Standard {
+	case protocol.TokenStandardNative, protocol.TokenStandardERC20, protocol.TokenStandardFriendTechShare:
+		if token.ValueDisplay != nil {
+			return *token.ValueDisplay, true
+		}
+
+		if token.Value != nil {
+			return token.Value.Shift(-int32(token.Decimals)), true
+		}
+
+		return decimal.Zero, false
+	case protocol.TokenStandardERC721:
+		return decimal.NewFromInt(1), true
+	case protocol.TokenStandardERC1155:
+		// The ERC-1155 tokens have no decimals, and the amount is 1 if it's not recorded
+		if token.Value != nil {
+			return *token.Value, true
+		}
+
+		return decimal.NewFromInt(1), true
+	default:
+		return decimal.Zero, false
+	}
+}
+
+// Holding is the balance of a token computed from the movements of an address
+type Holding struct {
+	Token
+
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals uint8  `json:"decimals,omitempty"`
+	Image    string `json:"image,omitempty"`
+
+	Balance decimal.Decimal `json:"balance"`
+	// PriceUSD and ValueUSD are the current price and value of the fungible tokens, they're nil if the price is unknown
+	PriceUSD *decimal.Decimal `json:"price_usd,omitempty"`
+	ValueUSD *decimal.Decimal `json:"value_usd,omitempty"`
+	// UpdatedAt is the time of the latest movement
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Ledger sums the movements of an address by token
+type Ledger struct {
+	holdings map[Token]*Holding
+}
+
+func (l *Ledger) Add(movement Movement) {
+	holding, exists := l.holdings[movement.Token]
+	if !exists {
+		holding = &Holding{
+			Token: movement.Token,
+		}
+
+		l.holdings[movement.Token] = holding
+	}
+
+	holding.Balance = holding.Balance.Add(movement.Amount)
+
+	// Fees have no token details
+	if movement.Symbol != "" || movement.Name != "" {
+		holding.Name, holding.Symbol, holding.Decimals, holding.Image = movement.Name, movement.Symbol, movement.Decimals, movement.Image
+	}
+
+	if holding.Decimals == 0 {
+		holding.Decimals = movement.Decimals
+	}
+
+	if movement.Timestamp.After(holding.UpdatedAt) {
+		holding.UpdatedAt = movement.Timestamp
+	}
+}
+
+// Holdings returns the tokens with a positive balance, ordered by network, standard, contract address and ID
+func (l *Ledger) Holdings() []Holding {
+	holdings := make([]Holding, 0, len(l.holdings))
+
+	for _, holding := range l.holdings {
+		if holding.Balance.IsPositive() {
+			holdings = append(holdings, *holding)
+		}
+	}
+
+	sort.Slice(holdings, func(i, j int) bool {
+		a, b := holdings[i].Token, holdings[j].Token
+
+		switch {
+		case a.Network != b.Network:
+			return a.Network < b.Network
+		case a.Standard != b.Standard:
+			return a.Standard < b.Standard
+		case a.ContractAddress != b.ContractAddress:
+			return a.ContractAddress < b.ContractAddress
+		default:
+			return a.ID < b.ID
+		}
+	})
+
+	return holdings
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{
+		holdings: make(map[Token]*Holding),
+	}
+}
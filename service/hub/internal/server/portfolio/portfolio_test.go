@@ -0,0 +1,120 @@
+package portfolio
+
+import (
+	"testing"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func amounts(movements []Movement) map[string]string {
+	result := make(map[string]string)
+
+	for _, movement := range movements {
+		result[movement.Standard+":"+movement.ContractAddress+":"+movement.ID] = movement.Amount.String()
+	}
+
+	return result
+}
+
+func TestMovements(t *testing.T) {
+	testcases := []struct {
+		name     string
+		transfer dbModel.Transfer
+		expected map[string]string
+	}{
+		{
+			name:     "receive",
+			transfer: testutil.Transfer(filter.TagTransaction, filter.TransactionTransfer, testutil.Bob, testutil.Alice, `{"standard":"ERC-20","contract_address":"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48","decimals":6,"value":"1500000","value_display":"1.5"}`),
+			expected: map[string]string{"ERC-20:" + testutil.USDC + ":": "1.5"},
+		},
+		{
+			name:     "send",
+			transfer: testutil.Transfer(filter.TagTransaction, filter.TransactionTransfer, testutil.Alice, testutil.Bob, `{"standard":"Native","decimals":18,"value_display":"0.25"}`),
+			expected: map[string]string{"Native::": "-0.25"},
+		},
+		{
+			name:     "self",
+			transfer: testutil.Transfer(filter.TagTransaction, filter.TransactionTransfer, testutil.Alice, testutil.Alice, `{"standard":"Native","value_display":"1"}`),
+			expected: map[string]string{},
+		},
+		{
+			name:     "swap",
+			transfer: testutil.Transfer(filter.TagExchange, filter.ExchangeSwap, testutil.Alice, testutil.Alice, `{"protocol":"Uniswap","from":{"standard":"ERC-20","contract_address":"`+testutil.USDC+`","value_display":"100"},"to":{"standard":"ERC-20","contract_address":"0xweth","value_display":"0.05"}}`),
+			expected: map[string]string{"ERC-20:" + testutil.USDC + ":": "-100", "ERC-20:0xweth:": "0.05"},
+		},
+		{
+			name:     "trade",
+			transfer: testutil.Transfer(filter.TagCollectible, filter.CollectibleTrade, testutil.Bob, testutil.Alice, `{"standard":"ERC-721","contract_address":"`+testutil.NFT+`","id":"7","cost":{"standard":"Native","value_display":"0.3"}}`),
+			expected: map[string]string{"ERC-721:" + testutil.NFT + ":7": "1", "Native::": "-0.3"},
+		},
+		{
+			name:     "shares",
+			transfer: testutil.Transfer(filter.TagCollectible, filter.CollectibleTrade, testutil.Alice, testutil.Bob, `{"standard":"Friend.tech-Shares","contract_address":"`+testutil.Bob+`","id":"1","value":"2","value_display":"2","cost":{"standard":"Native","value_display":"0.01"}}`),
+			expected: map[string]string{"Friend.tech-Shares:" + testutil.Bob + ":1": "-2", "Native::": "0.01"},
+		},
+		{
+			name:     "liquidity",
+			transfer: testutil.Transfer(filter.TagExchange, filter.ExchangeLiquidity, testutil.Alice, testutil.Bob, `{"action":"add","tokens":[{"standard":"ERC-20","contract_address":"`+testutil.USDC+`","value_display":"10"}]}`),
+			expected: map[string]string{"ERC-20:" + testutil.USDC + ":": "-10"},
+		},
+		{
+			name:     "stake",
+			transfer: testutil.Transfer(filter.TagExchange, filter.ExchangeStaking, testutil.Alice, testutil.Bob, `{"action":"stake","token":{"standard":"ERC-20","contract_address":"`+testutil.USDC+`","value_display":"20"}}`),
+			expected: map[string]string{"ERC-20:" + testutil.USDC + ":": "-20"},
+		},
+		{
+			name:     "claim",
+			transfer: testutil.Transfer(filter.TagExchange, filter.ExchangeStaking, testutil.Alice, testutil.Bob, `{"action":"claim","token":{"standard":"ERC-20","contract_address":"`+testutil.USDC+`","value_display":"2"}}`),
+			expected: map[string]string{"ERC-20:" + testutil.USDC + ":": "2"},
+		},
+		{
+			name:     "approval",
+			transfer: testutil.Transfer(filter.TagTransaction, filter.TransactionApproval, testutil.Alice, testutil.Bob, `{"standard":"ERC-20","contract_address":"`+testutil.USDC+`","value_display":"1000"}`),
+			expected: map[string]string{},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			movements, err := Movements(testutil.Alice, testcase.transfer)
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, amounts(movements))
+		})
+	}
+}
+
+func TestLedger(t *testing.T) {
+	fee := decimal.RequireFromString("0.001")
+
+	ledger := NewLedger()
+
+	for _, transfer := range []dbModel.Transfer{
+		testutil.Transfer(filter.TagTransaction, filter.TransactionTransfer, testutil.Bob, testutil.Alice, `{"name":"Ether","symbol":"ETH","standard":"Native","decimals":18,"value_display":"1"}`),
+		testutil.Transfer(filter.TagTransaction, filter.TransactionTransfer, testutil.Bob, testutil.Alice, `{"standard":"ERC-20","contract_address":"`+testutil.USDC+`","symbol":"USDC","decimals":6,"value_display":"5"}`),
+		testutil.Transfer(filter.TagTransaction, filter.TransactionTransfer, testutil.Alice, testutil.Bob, `{"standard":"ERC-20","contract_address":"`+testutil.USDC+`","symbol":"USDC","decimals":6,"value_display":"5"}`),
+	} {
+		movements, err := Movements(testutil.Alice, transfer)
+		assert.NoError(t, err)
+
+		for _, movement := range movements {
+			ledger.Add(movement)
+		}
+	}
+
+	ledger.Add(*FeeMovement(dbModel.Transaction{Hash: "0x02", Network: protocol.NetworkEthereum, Fee: &fee}))
+
+	holdings := ledger.Holdings()
+
+	// The tokens sent away aren't held
+	assert.Len(t, holdings, 1)
+	assert.Equal(t, protocol.TokenStandardNative, holdings[0].Standard)
+	assert.Equal(t, "ETH", holdings[0].Symbol)
+	assert.Equal(t, "0.999", holdings[0].Balance.String())
+
+	assert.Nil(t, FeeMovement(dbModel.Transaction{Network: protocol.NetworkEthereum}))
+}
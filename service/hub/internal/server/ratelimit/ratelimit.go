@@ -0,0 +1,298 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+)
+
+const (
+	TypeFree       = 1
+	TypePro        = 2
+	TypeEnterprise = 3
+
+	// The days of daily usage kept for the usage endpoint
+	HistoryDays = 30
+
+	keyPrefix = "apikey:usage"
+
+	// The requests of our own front-ends are counted together
+	frontendSubject = "frontend"
+)
+
+// DefaultTiers are the quotas of the API key types, the unknown types are free
+var DefaultTiers = map[int]configx.Tier{
+	TypeFree:       {Type: TypeFree, Name: "free", PerMinute: 60, Daily: 10_000},
+	TypePro:        {Type: TypePro, Name: "pro", PerMinute: 600, Daily: 500_000},
+	TypeEnterprise: {Type: TypeEnterprise, Name: "enterprise"},
+}
+
+// DefaultAnonymous doesn't limit the requests without an API key, the anonymous quota is opt-in
+var DefaultAnonymous = configx.Tier{Name: "anonymous"}
+
+// DefaultSignIn limits the nonces of the sign-in messages issued to each IP
+var DefaultSignIn = configx.Tier{Name: "sign-in", PerMinute: 10, Daily: 200}
+
+// Quota is the usage of a window
+type Quota struct {
+	// Limit is zero if the window is unlimited
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// Result is the decision of a request
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+	Minute     Quota
+	Daily      Quota
+}
+
+type DailyUsage struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// Usage is the usage of a subject queried through the usage endpoint
+type Usage struct {
+	Tier    string       `json:"tier"`
+	Minute  Quota        `json:"minute"`
+	Daily   Quota        `json:"daily"`
+	History []DailyUsage `json:"history"`
+}
+
+// Limiter counts the requests of subjects in fixed windows of a minute and a UTC day,
+// the counters are shared by the hubs in redis
+type Limiter struct {
+	client    *redis.Client
+	tiers     map[int]configx.Tier
+	anonymous configx.Tier
+	signIn    configx.Tier
+	frontend  *configx.FrontendKey
+	now       func() time.Time
+}
+
+// Tier returns the quota of an API key type
+func (l *Limiter) Tier(keyType int) configx.Tier {
+	if tier, exists := l.tiers[keyType]; exists {
+		return tier
+	}
+
+	return l.tiers[TypeFree]
+}
+
+// Anonymous returns the quota of the requests without an API key
+func (l *Limiter) Anonymous() configx.Tier {
+	return l.anonymous
+}
+
+// SignIn returns the quota of the sign-in requests of an IP
+func (l *Limiter) SignIn() configx.Tier {
+	return l.signIn
+}
+
+// Unlimited reports whether tier has no quota
+func Unlimited(tier configx.Tier) bool {
+	return tier.PerMinute <= 0 && tier.Daily <= 0
+}
+
+// Frontend returns the subject and quota of the key of our own front-ends, it's false if apiKey isn't the configured one
+func (l *Limiter) Frontend(apiKey string) (string, configx.Tier, bool) {
+	if l.frontend == nil || l.frontend.Key == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(l.frontend.Key)) != 1 {
+		return "", configx.Tier{}, false
+	}
+
+	return frontendSubject, l.frontend.Tier, true
+}
+
+// Allow counts a request of subject and decides whether it's within the quota of tier,
+// the rejected requests are counted as well
+func (l *Limiter) Allow(ctx context.Context, subject string, tier configx.Tier) (*Result, error) {
+	now := l.now().UTC()
+
+	minuteKey, dayKey := MinuteKey(subject, now), DayKey(subject, now)
+
+	pipeline := l.client.TxPipeline()
+	minute := pipeline.Incr(ctx, minuteKey)
+	pipeline.Expire(ctx, minuteKey, 2*time.Minute)
+	day := pipeline.Incr(ctx, dayKey)
+	pipeline.Expire(ctx, dayKey, (HistoryDays+1)*24*time.Hour)
+
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("count request: %w", err)
+	}
+
+	return Evaluate(tier, minute.Val(), day.Val(), now), nil
+}
+
+// Usage returns the usage of subject in the current windows and the last days
+func (l *Limiter) Usage(ctx context.Context, subject string, tier configx.Tier, days int) (*Usage, error) {
+	now := l.now().UTC()
+
+	if days <= 0 || days > HistoryDays {
+		days = HistoryDays
+	}
+
+	keys := []string{MinuteKey(subject, now)}
+	for i := 0; i < days; i++ {
+		keys = append(keys, DayKey(subject, now.AddDate(0, 0, -i)))
+	}
+
+	values, err := l.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get usage: %w", err)
+	}
+
+	counts := make([]int64, len(values))
+	for index, value := range values {
+		if value, ok := value.(string); ok {
+			_, _ = fmt.Sscan(value, &counts[index])
+		}
+	}
+
+	result := Evaluate(tier, counts[0], counts[1], now)
+
+	usage := Usage{
+		Tier:   tier.Name,
+		Minute: result.Minute,
+		Daily:  result.Daily,
+	}
+
+	for i := 0; i < days; i++ {
+		usage.History = append(usage.History, DailyUsage{
+			Date:  now.AddDate(0, 0, -i).Format("2006-01-02"),
+			Count: counts[i+1],
+		})
+	}
+
+	return &usage, nil
+}
+
+// Evaluate decides a request by the counts of its windows including itself
+func Evaluate(tier configx.Tier, minuteCount, dailyCount int64, now time.Time) *Result {
+	now = now.UTC()
+
+	minuteReset := now.Truncate(time.Minute).Add(time.Minute)
+	dayReset := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+
+	result := Result{
+		Allowed: true,
+		Minute:  quota(tier.PerMinute, minuteCount, minuteReset),
+		Daily:   quota(tier.Daily, dailyCount, dayReset),
+	}
+
+	if tier.Daily > 0 && dailyCount > tier.Daily {
+		result.Allowed = false
+		result.RetryAfter = dayReset.Sub(now)
+	} else if tier.PerMinute > 0 && minuteCount > tier.PerMinute {
+		result.Allowed = false
+		result.RetryAfter = minuteReset.Sub(now)
+	}
+
+	return &result
+}
+
+func quota(limit, used int64, reset time.Time) Quota {
+	remaining := limit - used
+	if remaining < 0 || limit == 0 {
+		remaining = 0
+	}
+
+	return Quota{
+		Limit:     limit,
+		Used:      used,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+}
+
+// KeySubject is the subject of the requests with an API key, it's the address owning the key,
+// so that rotating the key keeps its usage
+func KeySubject(address string) string {
+	return "key:" + address
+}
+
+// IPSubject is the subject of the anonymous requests from an IP
+func IPSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// SignInSubject is the subject of the sign-in requests from an IP
+func SignInSubject(ip string) string {
+	return "signin:" + ip
+}
+
+func MinuteKey(subject string, now time.Time) string {
+	return fmt.Sprintf("%s:%s:minute:%d", keyPrefix, subject, now.Unix()/60)
+}
+
+func DayKey(subject string, now time.Time) string {
+	return fmt.Sprintf("%s:%s:day:%s", keyPrefix, subject, now.UTC().Format("20060102"))
+}
+
+var (
+	globalLocker  sync.RWMutex
+	globalLimiter *Limiter
+)
+
+func Global() *Limiter {
+	globalLocker.RLock()
+
+	defer globalLocker.RUnlock()
+
+	return globalLimiter
+}
+
+func ReplaceGlobal(limiter *Limiter) {
+	globalLocker.Lock()
+
+	defer globalLocker.Unlock()
+
+	globalLimiter = limiter
+}
+
+func New(config *configx.APIKey, client *redis.Client) *Limiter {
+	limiter := Limiter{
+		client:    client,
+		tiers:     make(map[int]configx.Tier),
+		anonymous: DefaultAnonymous,
+		signIn:    DefaultSignIn,
+		now:       time.Now,
+	}
+
+	for keyType, tier := range DefaultTiers {
+		limiter.tiers[keyType] = tier
+	}
+
+	if config != nil {
+		for _, tier := range config.Tiers {
+			limiter.tiers[tier.Type] = tier
+		}
+
+		if config.Anonymous != nil {
+			limiter.anonymous = *config.Anonymous
+		}
+
+		if config.SignIn != nil {
+			limiter.signIn = *config.SignIn
+		}
+
+		if config.Frontend != nil {
+			frontend := *config.Frontend
+			if frontend.Tier.Name == "" {
+				frontend.Tier.Name = frontendSubject
+			}
+
+			limiter.frontend = &frontend
+		}
+	}
+
+	return &limiter
+}
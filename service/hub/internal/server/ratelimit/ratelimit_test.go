@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 30, 15, 0, time.UTC)
+	tier := configx.Tier{Name: "free", PerMinute: 60, Daily: 1000}
+
+	result := Evaluate(tier, 60, 100, now)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Minute.Remaining)
+	assert.Equal(t, int64(900), result.Daily.Remaining)
+	assert.Equal(t, time.Date(2023, 5, 1, 12, 31, 0, 0, time.UTC), result.Minute.Reset)
+	assert.Equal(t, time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC), result.Daily.Reset)
+
+	result = Evaluate(tier, 61, 100, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 45*time.Second, result.RetryAfter)
+
+	// The daily quota waits longer
+	result = Evaluate(tier, 61, 1001, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 11*time.Hour+29*time.Minute+45*time.Second, result.RetryAfter)
+
+	unlimited := Evaluate(configx.Tier{Name: "enterprise"}, 1_000_000, 1_000_000, now)
+	assert.True(t, unlimited.Allowed)
+	assert.Equal(t, int64(0), unlimited.Minute.Limit)
+}
+
+func TestTier(t *testing.T) {
+	limiter := New(&configx.APIKey{
+		Tiers: []configx.Tier{
+			{Type: TypePro, Name: "pro", PerMinute: 1200},
+			{Type: 9, Name: "partner", Daily: 1},
+		},
+	}, nil)
+
+	assert.Equal(t, int64(1200), limiter.Tier(TypePro).PerMinute)
+	assert.Equal(t, "partner", limiter.Tier(9).Name)
+	assert.Equal(t, DefaultTiers[TypeFree], limiter.Tier(0), "unknown types are free")
+	assert.Equal(t, DefaultTiers[TypeEnterprise], limiter.Tier(TypeEnterprise))
+	assert.Equal(t, DefaultAnonymous, limiter.Anonymous())
+	assert.True(t, Unlimited(limiter.Anonymous()), "the anonymous requests are unlimited unless configured")
+	assert.False(t, Unlimited(limiter.Tier(TypeFree)))
+	assert.Equal(t, DefaultSignIn, limiter.SignIn())
+	assert.False(t, Unlimited(limiter.SignIn()), "the nonces of the sign-in messages are limited anyway")
+
+	_, _, ok := limiter.Frontend("")
+	assert.False(t, ok, "no front-end key unless configured")
+}
+
+func TestFrontend(t *testing.T) {
+	limiter := New(&configx.APIKey{
+		Frontend: &configx.FrontendKey{Key: "secret", Tier: configx.Tier{PerMinute: 6000}},
+	}, nil)
+
+	subject, tier, ok := limiter.Frontend("secret")
+	assert.True(t, ok)
+	assert.Equal(t, "frontend", subject)
+	assert.Equal(t, configx.Tier{Name: "frontend", PerMinute: 6000}, tier)
+
+	_, _, ok = limiter.Frontend("public")
+	assert.False(t, ok)
+}
+
+func TestKeys(t *testing.T) {
+	now := time.Date(2023, 5, 1, 23, 59, 59, 0, time.FixedZone("UTC+8", 8*3600))
+
+	assert.Equal(t, "apikey:usage:key:1:day:20230501", DayKey("key:1", now))
+	assert.Equal(t, MinuteKey("key:1", now), MinuteKey("key:1", now.Add(-59*time.Second).Truncate(time.Minute)))
+}
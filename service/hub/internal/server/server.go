@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
@@ -20,6 +21,7 @@ import (
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/handler"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/handler/doc"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/middlewarex"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/ratelimit"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/service"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/validatorx"
 	"go.uber.org/zap"
@@ -67,6 +69,8 @@ func (s *Server) Initialize() (err error) {
 
 	cache.ReplaceGlobal(redisClient)
 
+	ratelimit.ReplaceGlobal(ratelimit.New(config.ConfigHub.APIKey, redisClient))
+
 	metadata_url.New(config.ConfigHub.RPC.IPFS.IO)
 
 	ethereumClientMap, err := ethclientx.Dial(config.ConfigHub.RPC, protocol.EthclientNetworks)
@@ -87,6 +91,20 @@ func (s *Server) Initialize() (err error) {
 	s.httpServer.HTTPErrorHandler = handler.ErrorFunc
 	s.httpServer.Validator = validatorx.Default
 
+	// The anonymous requests are limited by IP, which is taken from X-Forwarded-For only behind the trusted proxies
+	trustOptions := make([]echo.TrustOption, 0, len(config.ConfigHub.HTTP.TrustedProxies))
+
+	for _, proxy := range config.ConfigHub.HTTP.TrustedProxies {
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %s: %w", proxy, err)
+		}
+
+		trustOptions = append(trustOptions, echo.TrustIPRange(ipRange))
+	}
+
+	s.httpServer.IPExtractor = echo.ExtractIPFromXFFHeader(trustOptions...)
+
 	s.httpServer.Use(middleware.CORSWithConfig(middleware.DefaultCORSConfig))
 	s.httpServer.Use(middlewarex.ZapLogger(s.logger))
 	s.httpServer.Use(middlewarex.PathUnescapeMiddleware)
@@ -105,7 +123,13 @@ func (s *Server) Initialize() (err error) {
 
 	// GET
 	s.httpServer.GET(handler.PathGetNotes, s.httpHandler.GetNotesFunc, middlewarex.APIMiddleware)
+	s.httpServer.GET(handler.PathGetNotesStream, s.httpHandler.GetNotesStreamFunc, middlewarex.APIMiddleware)
+	s.httpServer.GET(handler.PathSearchNotes, s.httpHandler.SearchNotesFunc, middlewarex.CheckAPIKeyMiddleware)
+	s.httpServer.GET(handler.PathExportNotes, s.httpHandler.ExportNotesFunc, middlewarex.CheckAPIKeyMiddleware)
 	s.httpServer.GET(handler.PathGetAssets, s.httpHandler.GetAssetsFunc, middlewarex.APIMiddleware)
+	s.httpServer.GET(handler.PathGetPortfolio, s.httpHandler.GetPortfolioFunc, middlewarex.APIMiddleware)
+	s.httpServer.GET(handler.PathGetPnL, s.httpHandler.GetPnLFunc, middlewarex.APIMiddleware)
+	s.httpServer.GET(handler.PathGetApprovals, s.httpHandler.GetApprovalsFunc, middlewarex.APIMiddleware)
 	s.httpServer.GET(handler.PathGetExchanges, s.httpHandler.GetExchangeListFunc)
 	s.httpServer.GET(handler.PathGetPlatformList, s.httpHandler.GetPlatformListFunc)
 	s.httpServer.GET(handler.PathGetProfiles, s.httpHandler.GetProfilesFunc2)
@@ -121,16 +145,39 @@ func (s *Server) Initialize() (err error) {
 	s.httpServer.POST(handler.PathBatchGetNotes, s.httpHandler.BatchGetNotesFunc, middlewarex.CheckAPIKeyMiddleware)
 	s.httpServer.POST(handler.PathBatchGetProfiles, s.httpHandler.BatchGetProfilesFunc2, middlewarex.CheckAPIKeyMiddleware)
 
+	// GraphQL
+	s.httpServer.POST(handler.PathGraphQL, s.httpHandler.PostGraphQLFunc, middlewarex.APIMiddleware)
+
 	// End of year Wrapped
 	s.httpServer.GET("/wrapped/:address", s.httpHandler.GetWrappedFunc)
 
 	// API KEY
+	s.httpServer.GET(handler.PathGetAPIKeyNonce, s.httpHandler.GetAPIKeyNonceFunc, middlewarex.SignInMiddleware)
 	s.httpServer.POST(handler.PathPostAPIKey, s.httpHandler.PostAPIKeyFunc)
-	s.httpServer.GET(handler.PathGetAPIKey, s.httpHandler.GetAPIKeyFunc)
+	s.httpServer.POST(handler.PathRotateAPIKey, s.httpHandler.RotateAPIKeyFunc)
+	s.httpServer.POST(handler.PathRevokeAPIKey, s.httpHandler.RevokeAPIKeyFunc)
+	s.httpServer.POST(handler.PathListAPIKeys, s.httpHandler.ListAPIKeysFunc)
+	s.httpServer.GET(handler.PathGetAPIKeyUsage, s.httpHandler.GetAPIKeyUsageFunc, middlewarex.CheckAPIKeyMiddleware)
+
+	// Webhooks
+	s.httpServer.POST(handler.PathWebhooks, s.httpHandler.PostWebhookFunc)
+	s.httpServer.GET(handler.PathWebhooks, s.httpHandler.GetWebhooksFunc)
+	s.httpServer.DELETE(handler.PathWebhook, s.httpHandler.DeleteWebhookFunc)
+	s.httpServer.GET(handler.PathGetWebhookDeliveries, s.httpHandler.GetWebhookDeliveriesFunc)
+	s.httpServer.POST(handler.PathReplayWebhookDeliveries, s.httpHandler.ReplayWebhookDeliveriesFunc)
+
+	if config.ConfigHub.Webhook != nil && config.ConfigHub.Webhook.Enabled {
+		go func() {
+			if err := svc.Webhook.Run(context.Background()); err != nil {
+				s.logger.Error("webhook stopped", zap.Error(err))
+			}
+		}()
+	}
 
 	// WS Initialize
-	// go svc.WsHub.Run()
-	// s.httpServer.GET("/ws/notes", s.httpHandler.GetNotesWsFunc)
+	go svc.WsHub.Run()
+	go svc.SubscribeIndexerRefreshMessage()
+	s.httpServer.GET(handler.PathGetNotesWs, s.httpHandler.GetNotesWsFunc)
 
 	return nil
 }
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/naturalselectionlabs/pregod/common/cache"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dao"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/middlewarex"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/ratelimit"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/siwe"
+	"go.uber.org/zap"
+)
+
+const (
+	APIKeyActionApply  = "apply"
+	APIKeyActionRotate = "rotate"
+	APIKeyActionRevoke = "revoke"
+	APIKeyActionList   = "list"
+
+	// A nonce is valid for one signed request within this time
+	apiKeyNonceTTL = 10 * time.Minute
+)
+
+var (
+	ErrAPIKeyExists   = errors.New("this address has already applied for API-KEY")
+	ErrAPIKeyNotFound = errors.New("this address has not applied for API-KEY")
+	ErrSignInMismatch = errors.New("the sign-in message doesn't match the nonce issued to the address")
+)
+
+var apiKeyStatements = map[string]string{
+	APIKeyActionApply:  "Apply for an RSS3 API key.",
+	APIKeyActionRotate: "Rotate the RSS3 API key, the current key stops working.",
+	APIKeyActionRevoke: "Revoke the RSS3 API key.",
+	APIKeyActionList:   "List the RSS3 API keys.",
+}
+
+// apiKeyNonce is the request a nonce is issued for
+type apiKeyNonce struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+// GetAPIKeyNonce issues a nonce for an action on the API keys of an address,
+// and returns the Sign-In with Ethereum message to be signed by the address
+func (s *Service) GetAPIKeyNonce(ctx context.Context, domain string, request model.GetAPIKeyNonceRequest) (*model.GetAPIKeyNonceResult, error) {
+	nonce, err := siwe.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expiredAt := now.Add(apiKeyNonceTTL)
+
+	message := siwe.Message{
+		Domain:         domain,
+		Address:        common.HexToAddress(request.Address),
+		Statement:      apiKeyStatements[request.Action],
+		URI:            fmt.Sprintf("https://%s/apikey/%s", domain, request.Action),
+		Version:        siwe.Version,
+		ChainID:        "1",
+		Nonce:          nonce,
+		IssuedAt:       now,
+		ExpirationTime: &expiredAt,
+	}
+
+	if err := cache.SetJson(ctx, apiKeyNonceKey(nonce), apiKeyNonce{
+		Address: strings.ToLower(request.Address),
+		Action:  request.Action,
+	}, apiKeyNonceTTL); err != nil {
+		return nil, fmt.Errorf("save nonce: %w", err)
+	}
+
+	return &model.GetAPIKeyNonceResult{
+		Nonce:     nonce,
+		Message:   message.String(),
+		ExpiredAt: expiredAt,
+	}, nil
+}
+
+// ApplyAPIKey issues an API key to the address which signed the request
+func (s *Service) ApplyAPIKey(ctx context.Context, domain string, request model.SignedAPIKeyRequest) (*model.APIKeyResult, error) {
+	address, err := verifyAPIKeySignIn(ctx, domain, APIKeyActionApply, request)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := dao.GetAPIKeys(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(apiKeys) > 0 {
+		return nil, ErrAPIKeyExists
+	}
+
+	item := dbModel.APIKey{
+		Address: address,
+		UUID:    uuid.New().String(),
+		Type:    ratelimit.TypeFree,
+		Status:  true,
+	}
+
+	if err := dao.CreateAPIKey(ctx, &item); err != nil {
+		return nil, err
+	}
+
+	return apiKeyResult(item), nil
+}
+
+// RotateAPIKey replaces the API key of the address which signed the request, a revoked key is activated again
+func (s *Service) RotateAPIKey(ctx context.Context, domain string, request model.SignedAPIKeyRequest) (*model.APIKeyResult, error) {
+	return updateAPIKey(ctx, domain, APIKeyActionRotate, request, func(apiKey *dbModel.APIKey) {
+		apiKey.UUID = uuid.New().String()
+		apiKey.Status = true
+	})
+}
+
+// RevokeAPIKey disables the API key of the address which signed the request
+func (s *Service) RevokeAPIKey(ctx context.Context, domain string, request model.SignedAPIKeyRequest) (*model.APIKeyResult, error) {
+	return updateAPIKey(ctx, domain, APIKeyActionRevoke, request, func(apiKey *dbModel.APIKey) {
+		apiKey.Status = false
+	})
+}
+
+// ListAPIKeys returns the API keys of the address which signed the request
+func (s *Service) ListAPIKeys(ctx context.Context, domain string, request model.SignedAPIKeyRequest) ([]*model.APIKeyResult, error) {
+	address, err := verifyAPIKeySignIn(ctx, domain, APIKeyActionList, request)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := dao.GetAPIKeys(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.APIKeyResult, 0, len(apiKeys))
+
+	for _, apiKey := range apiKeys {
+		result = append(result, apiKeyResult(apiKey))
+	}
+
+	return result, nil
+}
+
+func updateAPIKey(ctx context.Context, domain, action string, request model.SignedAPIKeyRequest, update func(apiKey *dbModel.APIKey)) (*model.APIKeyResult, error) {
+	address, err := verifyAPIKeySignIn(ctx, domain, action, request)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := dao.GetAPIKeys(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(apiKeys) == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	item := apiKeys[0]
+	previous := item.UUID
+
+	update(&item)
+
+	if err := dao.UpdateAPIKey(ctx, &item); err != nil {
+		return nil, err
+	}
+
+	// The cached key would be accepted until it expires
+	if err := middlewarex.InvalidateAPIKey(ctx, previous); err != nil {
+		loggerx.Global().Error("failed to invalidate api key", zap.Error(err), zap.String("address", address))
+	}
+
+	return apiKeyResult(item), nil
+}
+
+// verifyAPIKeySignIn checks the signed message and consumes its nonce, then returns the lowercase signing address
+func verifyAPIKeySignIn(ctx context.Context, domain, action string, request model.SignedAPIKeyRequest) (string, error) {
+	message, err := siwe.Verify(request.Message, request.Signature, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	if message.Domain != domain {
+		return "", ErrSignInMismatch
+	}
+
+	// A nonce can't be used twice
+	pipeline := cache.Global().TxPipeline()
+	get := pipeline.Get(ctx, apiKeyNonceKey(message.Nonce))
+	pipeline.Del(ctx, apiKeyNonceKey(message.Nonce))
+
+	if _, err := pipeline.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrSignInMismatch
+		}
+
+		return "", fmt.Errorf("consume nonce: %w", err)
+	}
+
+	var nonce apiKeyNonce
+
+	if err := json.Unmarshal([]byte(get.Val()), &nonce); err != nil {
+		return "", fmt.Errorf("unmarshal nonce: %w", err)
+	}
+
+	address := strings.ToLower(message.Address.String())
+
+	if nonce.Address != address || nonce.Action != action {
+		return "", ErrSignInMismatch
+	}
+
+	return address, nil
+}
+
+func apiKeyResult(apiKey dbModel.APIKey) *model.APIKeyResult {
+	return &model.APIKeyResult{
+		Address:   apiKey.Address,
+		Key:       apiKey.UUID,
+		Tier:      ratelimit.Global().Tier(apiKey.Type).Name,
+		Active:    apiKey.Status,
+		CreatedAt: apiKey.CreatedAt,
+		UpdatedAt: apiKey.UpdatedAt,
+	}
+}
+
+func apiKeyNonceKey(nonce string) string {
+	return "apikey:nonce:" + nonce
+}
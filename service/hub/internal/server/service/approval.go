@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/naturalselectionlabs/pregod/common/ethclientx"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/approval"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dao"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"go.uber.org/zap"
+)
+
+// GetApprovals folds the approval notes of an address into its live approvals, verifies them on chain unless
+// it's disabled, and assesses their risks
+func (s *Service) GetApprovals(ctx context.Context, request model.GetApprovalsRequest) (*model.Approvals, error) {
+	request.Address = strings.ToLower(request.Address)
+	request.Network = lower(request.Network)
+
+	transfers, err := dao.GetApprovalTransfers(ctx, request.Address, request.Network)
+	if err != nil {
+		loggerx.Global().Error("GetApprovals: get approvals failed", zap.Error(err), zap.String("address", request.Address))
+
+		return nil, err
+	}
+
+	approvals, err := approval.Fold(request.Address, transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Verify == nil || *request.Verify {
+		approvals = s.verifyApprovals(ctx, request.Address, approvals)
+	}
+
+	result := model.Approvals{
+		Address:   request.Address,
+		Approvals: approvals,
+		Levels:    make(map[string]int),
+	}
+
+	for index := range result.Approvals {
+		approval.Assess(&result.Approvals[index])
+
+		result.Levels[result.Approvals[index].Level]++
+	}
+
+	return &result, nil
+}
+
+// verifyApprovals checks the approvals on the nodes of their networks, the ones of the networks without a node
+// are kept unverified
+func (s *Service) verifyApprovals(ctx context.Context, address string, approvals []approval.Approval) []approval.Approval {
+	var (
+		result   = make([]approval.Approval, 0, len(approvals))
+		networks = make([]string, 0)
+		grouped  = make(map[string][]approval.Approval)
+	)
+
+	for _, value := range approvals {
+		if _, exists := grouped[value.Network]; !exists {
+			networks = append(networks, value.Network)
+		}
+
+		grouped[value.Network] = append(grouped[value.Network], value)
+	}
+
+	// The approvals are ordered by network
+	for _, network := range networks {
+		client, err := ethclientx.Global(network)
+		if err != nil {
+			result = append(result, grouped[network]...)
+
+			continue
+		}
+
+		result = append(result, approval.Verify(ctx, client, address, grouped[network])...)
+	}
+
+	return result
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/internal/token"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dao"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/export"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
+	"go.uber.org/zap"
+)
+
+// exportPageSize is the number of transactions written at a time
+const exportPageSize = 500
+
+// ExportNotes writes the notes of the addresses to writer from the oldest, a page at a time,
+// the rows are from the view of the owners of the notes. start is called once the first page is read,
+// before anything is written, so that the failures of reading it can still be responded as errors.
+func (s *Service) ExportNotes(ctx context.Context, request model.ExportNotesRequest, writer *export.Writer, start func()) error {
+	request.Address = lower(request.Address)
+	request.Network = lower(request.Network)
+
+	tokenClient := token.New()
+
+	nativeSymbol := func(network string) string {
+		native, err := tokenClient.Native(ctx, network)
+		if err != nil {
+			return ""
+		}
+
+		return native.Symbol
+	}
+
+	var cursor *pagination.Cursor
+
+	for {
+		transactions, err := dao.GetExportTransactions(ctx, request, cursor, exportPageSize)
+		if err != nil {
+			loggerx.Global().Error("ExportNotes: get transactions failed", zap.Error(err), zap.Strings("address", request.Address))
+
+			return err
+		}
+
+		var transfers []dbModel.Transfer
+
+		if len(transactions) > 0 {
+			hashes := make([]string, 0, len(transactions))
+
+			for _, transaction := range transactions {
+				hashes = append(hashes, transaction.Hash)
+			}
+
+			if transfers, err = dao.GetTransfers(ctx, hashes); err != nil {
+				loggerx.Global().Error("ExportNotes: get transfers failed", zap.Error(err))
+
+				return err
+			}
+		}
+
+		if cursor == nil {
+			start()
+		}
+
+		// The header is written even if there is no note
+		if len(transactions) == 0 {
+			return writer.Flush()
+		}
+
+		// A hash may be used on several networks
+		transferMap := make(map[string][]dbModel.Transfer)
+
+		for _, transfer := range transfers {
+			key := transfer.TransactionHash + ":" + strings.ToLower(transfer.Network)
+			transferMap[key] = append(transferMap[key], transfer)
+		}
+
+		for _, transaction := range transactions {
+			transaction.Transfers = transferMap[transaction.Hash+":"+strings.ToLower(transaction.Network)]
+
+			rows, err := export.Rows(transaction.Owner, transaction, nativeSymbol)
+			if err != nil {
+				// A transfer with unexpected metadata doesn't fail the others
+				loggerx.Global().Warn("ExportNotes: build rows failed", zap.Error(err))
+
+				continue
+			}
+
+			for _, row := range rows {
+				if err := writer.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+
+		if len(transactions) < exportPageSize {
+			return nil
+		}
+
+		last := pagination.New(transactions[len(transactions)-1])
+		cursor = &last
+	}
+}
@@ -21,7 +21,38 @@ import (
 	"github.com/tidwall/gjson"
 )
 
-func (s *Service) GetNotes(ctx context.Context, request model.GetRequest) ([]dbModel.Transaction, int64, error) {
+func (s *Service) GetNotes(ctx context.Context, request model.GetRequest) ([]dbModel.Transaction, model.Pagination, error) {
+	transactions, page, err := s.ListNotes(ctx, request)
+	if err != nil {
+		return nil, page, err
+	}
+
+	// get transfers from database
+	transactionHashes := make([]string, 0)
+	for _, transactionHash := range transactions {
+		transactionHashes = append(transactionHashes, transactionHash.Hash)
+	}
+
+	transferMap, err := s.GetTransfers(ctx, transactionHashes)
+	if err != nil {
+		return nil, page, err
+	}
+
+	for index := range transactions {
+		transfers := transferMap[transactions[index].Hash]
+
+		if len(transfers) > request.ActionLimit {
+			transfers = transfers[:request.ActionLimit]
+		}
+
+		transactions[index].Transfers = transfers
+	}
+
+	return transactions, page, nil
+}
+
+// ListNotes returns the notes of GetNotes without their transfers, for the callers loading them by GetTransfers
+func (s *Service) ListNotes(ctx context.Context, request model.GetRequest) ([]dbModel.Transaction, model.Pagination, error) {
 	request.Address = strings.ToLower(request.Address)
 
 	if len(request.Tag) > 0 {
@@ -29,20 +60,24 @@ func (s *Service) GetNotes(ctx context.Context, request model.GetRequest) ([]dbM
 	}
 
 	// get transactions from database
-	transactions, total, err := dao.GetTransactions(ctx, request)
+	transactions, page, err := dao.GetTransactions(ctx, request)
 	if err != nil {
-		return nil, 0, err
+		return nil, page, err
 	}
 
-	// get transfers from database
-	transactionHashes := make([]string, 0)
-	for _, transactionHash := range transactions {
-		transactionHashes = append(transactionHashes, transactionHash.Hash)
+	// publish mq message
+	if len(request.Cursor) == 0 && (request.Refresh || len(transactions) == 0) {
+		s.PublishIndexerMessage(ctx, protocol.Message{Address: request.Address, Refresh: request.Refresh})
 	}
 
+	return transactions, page, nil
+}
+
+// GetTransfers returns the transfers of the transactions by hash, with the related URLs of Lens publications
+func (s *Service) GetTransfers(ctx context.Context, transactionHashes []string) (map[string][]dbModel.Transfer, error) {
 	transfers, err := dao.GetTransfers(ctx, transactionHashes)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
 	transferMap := make(map[string][]dbModel.Transfer)
@@ -68,28 +103,17 @@ func (s *Service) GetNotes(ctx context.Context, request model.GetRequest) ([]dbM
 
 		transfer.RelatedUrls = lo.Uniq(transfer.RelatedUrls)
 
-		if len(transferMap[transfer.TransactionHash]) < request.ActionLimit {
-			transferMap[transfer.TransactionHash] = append(transferMap[transfer.TransactionHash], transfer)
-		}
-	}
-
-	for index := range transactions {
-		transactions[index].Transfers = transferMap[transactions[index].Hash]
-	}
-
-	// publish mq message
-	if len(request.Cursor) == 0 && (request.Refresh || len(transactions) == 0) {
-		s.PublishIndexerMessage(ctx, protocol.Message{Address: request.Address})
+		transferMap[transfer.TransactionHash] = append(transferMap[transfer.TransactionHash], transfer)
 	}
 
-	return transactions, total, nil
+	return transferMap, nil
 }
 
-func (s *Service) GetNotesByPlatform(ctx context.Context, request model.GetNotesByPlatformRequest) ([]dbModel.Transaction, error) {
+func (s *Service) GetNotesByPlatform(ctx context.Context, request model.GetNotesByPlatformRequest) ([]dbModel.Transaction, model.Pagination, error) {
 	// get transactions from database
-	transactions, err := dao.GetTransactionsByPlatform(ctx, request)
+	transactions, page, err := dao.GetTransactionsByPlatform(ctx, request)
 	if err != nil {
-		return nil, err
+		return nil, page, err
 	}
 
 	// get transfers from database
@@ -100,7 +124,7 @@ func (s *Service) GetNotesByPlatform(ctx context.Context, request model.GetNotes
 
 	transfers, err := dao.GetTransfers(ctx, transactionHashes)
 	if err != nil {
-		return nil, err
+		return nil, page, err
 	}
 
 	transferMap := make(map[string][]dbModel.Transfer)
@@ -112,17 +136,17 @@ func (s *Service) GetNotesByPlatform(ctx context.Context, request model.GetNotes
 		transactions[index].Transfers = transferMap[transactions[index].Hash]
 	}
 
-	return transactions, nil
+	return transactions, page, nil
 }
 
-func (s *Service) BatchGetNotes(ctx context.Context, request model.BatchGetNotesRequest) ([]dbModel.Transaction, int64, error) {
+func (s *Service) BatchGetNotes(ctx context.Context, request model.BatchGetNotesRequest) ([]dbModel.Transaction, model.Pagination, error) {
 	if len(request.Tag) > 0 {
 		request.Tag, request.Type, request.IncludePoap = s.CheckRequestTagAndType(request.Tag, request.Type)
 	}
 
-	transactions, total, err := dao.BatchGetTransactions(ctx, request)
+	transactions, page, err := dao.BatchGetTransactions(ctx, request)
 	if err != nil {
-		return nil, 0, err
+		return nil, page, err
 	}
 
 	transactionHashes := make([]string, 0)
@@ -132,7 +156,7 @@ func (s *Service) BatchGetNotes(ctx context.Context, request model.BatchGetNotes
 
 	transfers, err := dao.GetTransfers(ctx, transactionHashes)
 	if err != nil {
-		return nil, 0, err
+		return nil, page, err
 	}
 
 	transferMap := make(map[string][]dbModel.Transfer)
@@ -159,24 +183,24 @@ func (s *Service) BatchGetNotes(ctx context.Context, request model.BatchGetNotes
 					defer wg.Done()
 					n := rand.Intn(100)
 					time.Sleep(time.Duration(n) * time.Millisecond) // max 100ms * 50 = 5s
-					s.PublishIndexerMessage(ctx, protocol.Message{Address: address})
+					s.PublishIndexerMessage(ctx, protocol.Message{Address: address, Refresh: request.Refresh})
 				}(address)
 			}
 			wg.Wait()
 		}
 	}
 
-	return transactions, total, nil
+	return transactions, page, nil
 }
 
-func (s *Service) BatchGetSocialNotes(ctx context.Context, request model.BatchGetSocialNotesRequest) ([]dbModel.Transaction, int64, error) {
+func (s *Service) BatchGetSocialNotes(ctx context.Context, request model.BatchGetSocialNotesRequest) ([]dbModel.Transaction, model.Pagination, error) {
 	if request.Limit <= 0 || request.Limit > model.DefaultLimit {
 		request.Limit = model.DefaultLimit
 	}
 
-	transactions, total, err := dao.BatchGetSocialTransactions(ctx, request)
+	transactions, page, err := dao.BatchGetSocialTransactions(ctx, request)
 	if err != nil {
-		return nil, 0, err
+		return nil, page, err
 	}
 
 	transactionHashes := make([]string, 0)
@@ -186,7 +210,7 @@ func (s *Service) BatchGetSocialNotes(ctx context.Context, request model.BatchGe
 
 	transfers, err := dao.GetTransfers(ctx, transactionHashes)
 	if err != nil {
-		return nil, 0, err
+		return nil, page, err
 	}
 
 	transferMap := make(map[string][]dbModel.Transfer)
@@ -198,7 +222,7 @@ func (s *Service) BatchGetSocialNotes(ctx context.Context, request model.BatchGe
 		transactions[index].Transfers = transferMap[transactions[index].Hash]
 	}
 
-	return transactions, total, nil
+	return transactions, page, nil
 }
 
 func (s *Service) CheckRequestTagAndType(reqTags []string, reqTypes []string) ([]string, []string, bool) {
@@ -230,7 +254,7 @@ func (s *Service) CheckRequestTagAndType(reqTags []string, reqTypes []string) ([
 	return tags, typeList, includePoap
 }
 
-func (s *Service) GetNftFeeds(ctx context.Context, request model.GetRequest) ([]dbModel.Transaction, int64, error) {
+func (s *Service) GetNftFeeds(ctx context.Context, request model.GetRequest) ([]dbModel.Transaction, model.Pagination, error) {
 	request.Address = strings.ToLower(request.Address)
 
 	if request.Limit <= 0 || request.Limit > model.DefaultLimit {
@@ -247,7 +271,7 @@ func (s *Service) GetNftFeeds(ctx context.Context, request model.GetRequest) ([]
 
 	entries, err := s.kuroraClient.FetchDatasetRara(ctx, raraQuery)
 	if err != nil {
-		return nil, 0, err
+		return nil, model.Pagination{}, err
 	}
 
 	var tokenID *big.Int
@@ -272,13 +296,13 @@ func (s *Service) GetNftFeeds(ctx context.Context, request model.GetRequest) ([]
 	request.HashList = hashes
 
 	if len(request.HashList) == 0 {
-		return transactions, 0, nil
+		return transactions, model.Pagination{}, nil
 	}
 
 	// get transactions from database
-	transactions, total, err := dao.GetTransactions(ctx, request)
+	transactions, page, err := dao.GetTransactions(ctx, request)
 	if err != nil {
-		return nil, 0, err
+		return nil, page, err
 	}
 
 	// get transfers from database
@@ -289,7 +313,7 @@ func (s *Service) GetNftFeeds(ctx context.Context, request model.GetRequest) ([]
 
 	transfers, err := dao.GetTransfers(ctx, transactionHashes)
 	if err != nil {
-		return nil, 0, err
+		return nil, page, err
 	}
 
 	transferMap := make(map[string][]dbModel.Transfer)
@@ -301,7 +325,7 @@ func (s *Service) GetNftFeeds(ctx context.Context, request model.GetRequest) ([]
 		transactions[index].Transfers = transferMap[transactions[index].Hash]
 	}
 
-	return transactions, total, nil
+	return transactions, page, nil
 }
 
 func (s *Service) GetTransactionByHash(ctx context.Context, request model.GetTransactionRequest) (dbModel.Transaction, error) {
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/ethclientx"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/internal/allowlist"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dao"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/portfolio"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// GetPortfolio computes the balances of the tokens held by an address from its transfers and fees,
+// values them at the current stored prices, and compares them with the balances on chain on request.
+// The tokens without a stored price are returned unvalued, and their prices are fetched in the background.
+func (s *Service) GetPortfolio(ctx context.Context, request model.GetPortfolioRequest) (*model.Portfolio, error) {
+	request.Address = strings.ToLower(request.Address)
+	request.Network = lower(request.Network)
+
+	movements, err := s.getMovements(ctx, request.Address, request.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := portfolio.NewLedger()
+
+	for _, movement := range movements {
+		ledger.Add(movement)
+	}
+
+	result := model.Portfolio{
+		Address:  request.Address,
+		Holdings: make([]portfolio.Holding, 0),
+	}
+
+	blockSpam := request.BlockSpam == nil || *request.BlockSpam
+
+	for _, holding := range ledger.Holdings() {
+		if blockSpam && holding.ContractAddress != "" && allowlist.SpamList.Contains(holding.ContractAddress) {
+			continue
+		}
+
+		result.Holdings = append(result.Holdings, holding)
+	}
+
+	now := time.Now()
+
+	for index, holding := range result.Holdings {
+		if !holding.Fungible() {
+			continue
+		}
+
+		price, err := s.priceClient.Stored(ctx, holding.Network, holding.ContractAddress, now)
+		if err != nil {
+			loggerx.Global().Warn("GetPortfolio: get price failed", zap.Error(err), zap.String("network", holding.Network), zap.String("contract_address", holding.ContractAddress))
+
+			continue
+		}
+
+		if price == nil {
+			continue
+		}
+
+		value := holding.Balance.Mul(*price).Round(2)
+
+		result.Holdings[index].PriceUSD, result.Holdings[index].ValueUSD = price, &value
+		result.ValueUSD = result.ValueUSD.Add(value)
+	}
+
+	if request.Reconcile {
+		result.Drifts = s.reconcile(ctx, request.Address, result.Holdings)
+	}
+
+	return &result, nil
+}
+
+// GetPnL computes the cost basis and profit and loss of an address by replaying its movements,
+// the positions held are valued at the stored prices at the end of the time range
+func (s *Service) GetPnL(ctx context.Context, request model.GetPnLRequest) (*model.PnL, error) {
+	request.Address = strings.ToLower(request.Address)
+	request.Network = lower(request.Network)
+
+	if request.Method == "" {
+		request.Method = portfolio.MethodFIFO
+	}
+
+	movements, err := s.getMovements(ctx, request.Address, request.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	calculator := portfolio.NewCalculator(request.Method, request.Since, request.Until)
+	calculator.Add(movements)
+
+	valuedAt := request.Until
+	if valuedAt.IsZero() || valuedAt.After(time.Now()) {
+		valuedAt = time.Now()
+	}
+
+	result := model.PnL{
+		Address: request.Address,
+		Method:  request.Method,
+		Positions: calculator.Positions(func(token portfolio.Token) *decimal.Decimal {
+			price, err := s.priceClient.Stored(ctx, token.Network, token.ContractAddress, valuedAt)
+			if err != nil {
+				loggerx.Global().Warn("GetPnL: get price failed", zap.Error(err), zap.String("network", token.Network), zap.String("contract_address", token.ContractAddress))
+			}
+
+			return price
+		}),
+	}
+
+	for _, position := range result.Positions {
+		result.RealizedPnL = result.RealizedPnL.Add(position.RealizedPnL)
+
+		if position.UnrealizedPnL != nil {
+			result.UnrealizedPnL = result.UnrealizedPnL.Add(*position.UnrealizedPnL)
+		}
+	}
+
+	return &result, nil
+}
+
+// getMovements returns the balance changes of an address made by its transfers and fees, from the oldest
+func (s *Service) getMovements(ctx context.Context, address string, networks []string) ([]portfolio.Movement, error) {
+	transfers, err := dao.GetTokenTransfers(ctx, address, networks)
+	if err != nil {
+		loggerx.Global().Error("getMovements: get transfers failed", zap.Error(err), zap.String("address", address))
+
+		return nil, err
+	}
+
+	transactions, err := dao.GetFeeTransactions(ctx, address, networks)
+	if err != nil {
+		loggerx.Global().Error("getMovements: get fees failed", zap.Error(err), zap.String("address", address))
+
+		return nil, err
+	}
+
+	movements := make([]portfolio.Movement, 0, len(transfers)+len(transactions))
+
+	for _, transfer := range transfers {
+		transferMovements, err := portfolio.Movements(address, transfer)
+		if err != nil {
+			// A transfer with unexpected metadata doesn't fail the others
+			loggerx.Global().Warn("getMovements: build movements failed", zap.Error(err))
+
+			continue
+		}
+
+		movements = append(movements, transferMovements...)
+	}
+
+	for _, transaction := range transactions {
+		if movement := portfolio.FeeMovement(transaction); movement != nil {
+			movements = append(movements, *movement)
+		}
+	}
+
+	// The fee of a transaction is paid before its transfers
+	sort.SliceStable(movements, func(i, j int) bool {
+		a, b := movements[i], movements[j]
+
+		switch {
+		case !a.Timestamp.Equal(b.Timestamp):
+			return a.Timestamp.Before(b.Timestamp)
+		case a.TransactionHash != b.TransactionHash:
+			return a.TransactionHash < b.TransactionHash
+		case a.Fee != b.Fee:
+			return a.Fee
+		default:
+			return a.Index < b.Index
+		}
+	})
+
+	return movements, nil
+}
+
+// reconcile compares the holdings with the balances on the nodes of their networks,
+// the networks without a node are skipped
+func (s *Service) reconcile(ctx context.Context, address string, holdings []portfolio.Holding) []portfolio.Drift {
+	drifts := make([]portfolio.Drift, 0)
+
+	networks := make(map[string][]portfolio.Holding)
+
+	for _, holding := range holdings {
+		networks[holding.Network] = append(networks[holding.Network], holding)
+	}
+
+	for network, networkHoldings := range networks {
+		client, err := ethclientx.Global(network)
+		if err != nil {
+			continue
+		}
+
+		drifts = append(drifts, portfolio.Reconcile(ctx, client, address, networkHoldings)...)
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].Network < drifts[j].Network
+	})
+
+	return drifts
+}
@@ -43,7 +43,10 @@ func (s *Service) GetProfiles(c context.Context, request model.GetRequest) ([]*s
 	m := make(map[string]*social.Profile)
 	result := make([]*social.Profile, 0)
 
-	profiles, _ := dao.GetProfiles(c, request)
+	profiles, err := dao.GetProfiles(c, request)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, profile := range profiles {
 		m[profile.Platform] = profile
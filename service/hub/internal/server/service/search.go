@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dao"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+)
+
+// SearchNotes searches the posts of social notes by keyword, from the most relevant
+func (s *Service) SearchNotes(ctx context.Context, request model.SearchNotesRequest) ([]model.SearchNoteResult, model.Pagination, error) {
+	if request.Limit <= 0 || request.Limit > model.DefaultLimit {
+		request.Limit = model.DefaultLimit
+	}
+
+	request.Keyword = strings.TrimSpace(request.Keyword)
+	request.Address = lower(request.Address)
+	request.Network = lower(request.Network)
+	request.Platform = lower(request.Platform)
+
+	return dao.SearchSocialTransfers(ctx, request)
+}
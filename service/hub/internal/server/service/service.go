@@ -12,6 +12,8 @@ import (
 	"github.com/naturalselectionlabs/pregod/common/database"
 	"github.com/naturalselectionlabs/pregod/common/database/model"
 	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/mq"
+	"github.com/naturalselectionlabs/pregod/common/price"
 	"github.com/naturalselectionlabs/pregod/common/protocol"
 	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
 	"github.com/naturalselectionlabs/pregod/common/utils/shedlock"
@@ -19,30 +21,32 @@ import (
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/config"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dao"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/handler/maspool"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/webhook"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/websocket"
-	rabbitmq "github.com/rabbitmq/amqp091-go"
 
 	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
 type Service struct {
-	employer           *shedlock.Employer
-	rabbitmqConnection *rabbitmq.Connection
-	rabbitmqChannel    *rabbitmq.Channel
-	rabbitmqQueue      rabbitmq.Queue
-	WsHub              *websocket.WSHub
-	DeliveryCh         <-chan rabbitmq.Delivery
-	kuroraClient       *kurora.Client
-	mastodonPool       *maspool.InstancePool
+	employer     *shedlock.Employer
+	queue        mq.Queue
+	WsHub        *websocket.WSHub
+	Webhook      *webhook.Webhook
+	DeliveryCh   <-chan *mq.Delivery
+	kuroraClient *kurora.Client
+	mastodonPool *maspool.InstancePool
+	priceClient  *price.Client
 }
 
 func New() (s *Service) {
 	s = &Service{
-		employer: shedlock.New(),
-		WsHub:    websocket.NewHub(),
+		employer:    shedlock.New(),
+		priceClient: price.New(database.Global()),
 	}
 
+	s.WsHub = websocket.NewHub(s.GetNotesAfter)
+
 	kuroraClient, err := kurora.Dial(context.Background(), config.ConfigHub.Kurora.Endpoint, kurora.WithHTTPClient(http.DefaultClient))
 	if err != nil {
 		loggerx.Global().Fatal("connect kurora failed", zap.Error(err))
@@ -54,6 +58,8 @@ func New() (s *Service) {
 		loggerx.Global().Fatal("connect mq failed", zap.Error(err))
 	}
 
+	s.Webhook = webhook.New(config.ConfigHub.Webhook, s.queue)
+
 	serversAndCredentials := make(map[string]maspool.Credential, len(config.ConfigHub.Mastodon.Servers))
 
 	for _, server := range config.ConfigHub.Mastodon.Servers {
@@ -70,70 +76,22 @@ func New() (s *Service) {
 
 	s.mastodonPool = pool
 
-	go func() {
-		maxRetry := 3
-		for {
-			<-s.rabbitmqConnection.NotifyClose(make(chan *rabbitmq.Error))
-			loggerx.Global().Error("rabbitmq connection closed, reconnecting...")
-			time.Sleep(10 * time.Second)
-			if err := s.connectMQ(); err != nil {
-				loggerx.Global().Error("connect mq failed", zap.Error(err))
-			} else {
-				loggerx.Global().Info("connect mq success", zap.Error(err))
-			}
-			maxRetry--
-			if maxRetry == 0 {
-				panic("rabbitmq reconnect failed")
-			}
-		}
-	}()
 	return s
 }
 
-func (s *Service) connectMQ() error {
-	var err error
-	s.rabbitmqConnection, err = rabbitmq.Dial(config.ConfigHub.RabbitMQ.String())
-	if err != nil {
-		loggerx.Global().Error("rabbitmq dail failed", zap.Error(err))
-		return err
-	}
-
-	s.rabbitmqChannel, err = s.rabbitmqConnection.Channel()
-	if err != nil {
-		loggerx.Global().Error("rabbitmqConnection failed", zap.Error(err))
-		return err
-	}
-
-	if err = s.rabbitmqChannel.ExchangeDeclare(protocol.ExchangeJob, "direct", true, false, false, false, nil); err != nil {
-		loggerx.Global().Error("rabbitmqChannel ExchangeDeclare failed", zap.Error(err))
-		return err
-	}
-
-	if err = s.rabbitmqChannel.ExchangeDeclare(protocol.ExchangeRefresh, "fanout", true, false, false, false, nil); err != nil {
-		loggerx.Global().Error("rabbitmqChannel ExchangeDeclare Refresh failed", zap.Error(err))
-		return err
-	}
-
-	if s.rabbitmqQueue, err = s.rabbitmqChannel.QueueDeclare(
-		"", false, false, true, false, nil,
-	); err != nil {
-		loggerx.Global().Error("rabbitmq QueueDeclare failed", zap.Error(err))
-		return err
-	}
-
-	if err = s.rabbitmqChannel.QueueBind(
-		s.rabbitmqQueue.Name, "", protocol.ExchangeRefresh, false, nil,
-	); err != nil {
-		loggerx.Global().Error("rabbitmq QueueBind failed", zap.Error(err))
+func (s *Service) connectMQ() (err error) {
+	if s.queue, err = mq.Dial(config.ConfigHub.MQ, config.ConfigHub.RabbitMQ); err != nil {
+		loggerx.Global().Error("mq dial failed", zap.Error(err))
 		return err
 	}
 
-	deliveryCh, err := s.rabbitmqChannel.Consume(s.rabbitmqQueue.Name, "", true, false, false, false, nil)
-	if err != nil {
-		loggerx.Global().Error("rabbitmq Consume Refresh Msg failed", zap.Error(err))
+	// Refresh messages are broadcast to every hub, each of them consumes with an exclusive queue
+	if s.DeliveryCh, err = s.queue.Consume(context.Background(), mq.Binding{
+		Exchange: protocol.ExchangeRefresh,
+	}); err != nil {
+		loggerx.Global().Error("mq Consume Refresh Msg failed", zap.Error(err))
 		return err
 	}
-	s.DeliveryCh = deliveryCh
 
 	return nil
 }
@@ -184,10 +142,7 @@ func (s *Service) PublishIndexerMessage(ctx context.Context, message protocol.Me
 			return
 		}
 
-		if err := s.rabbitmqChannel.PublishWithContext(ctx, protocol.ExchangeJob, routingKey, false, false, rabbitmq.Publishing{
-			ContentType: protocol.ContentTypeJSON,
-			Body:        messageData,
-		}); err != nil {
+		if err := s.queue.Publish(ctx, protocol.ExchangeJob, routingKey, messageData); err != nil {
 			loggerx.Global().Error("publish indexer message failed", zap.Error(err), zap.String("address", message.Address), zap.String("network", network))
 			return
 		}
@@ -251,40 +206,19 @@ func (s *Service) PublishIndexerAssetMessage(ctx context.Context, address string
 			return
 		}
 
-		if err := s.rabbitmqChannel.PublishWithContext(ctx, protocol.ExchangeJob, protocol.IndexerAssetRoutingKey, false, false, rabbitmq.Publishing{
-			ContentType: protocol.ContentTypeJSON,
-			Body:        messageData,
-		}); err != nil {
+		if err := s.queue.Publish(ctx, protocol.ExchangeJob, protocol.IndexerAssetRoutingKey, messageData); err != nil {
 			loggerx.Global().Error("publish indexer asset message failed", zap.Error(err))
 			return
 		}
 	}
 }
 
-func (s *Service) SubscribeIndexerRefreshMessage(client *websocket.WSClient) {
-	ticker := time.NewTicker(10 * time.Second)
+// SubscribeIndexerRefreshMessage relays the notes written by indexers to the subscribers until the queue is closed
+func (s *Service) SubscribeIndexerRefreshMessage() {
+	for delivery := range s.DeliveryCh {
+		// Missed notes are resumed from the database, so there's no need to redeliver them
+		_ = s.queue.Ack(context.Background(), delivery)
 
-	for {
-		select {
-		case delivery := <-s.DeliveryCh:
-			if len(delivery.Body) == 0 {
-				loggerx.Global().Info("wait mq reconnected")
-				time.Sleep(3 * time.Second)
-				continue
-			}
-			message := protocol.RefreshMessage{}
-			if err := json.Unmarshal(delivery.Body, &message); err != nil {
-				loggerx.Global().Error("failed to unmarshal message", zap.Error(err))
-				continue
-			}
-			if _, ok := s.WsHub.Clients[client]; !ok {
-				return
-			}
-			s.WsHub.Broadcast <- delivery.Body
-		case <-ticker.C:
-			if _, ok := s.WsHub.Clients[client]; !ok {
-				return
-			}
-		}
+		s.WsHub.Broadcast <- delivery.Body
 	}
 }
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dao"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/websocket"
+)
+
+// The notes resumed at most, subscribers missing more should page through the notes API
+const maxResumeNotes = 4 * model.DefaultLimit
+
+// NewSubscription returns the subscription of the address and filters of request, which are applied as GetNotes does
+func (s *Service) NewSubscription(request model.GetRequest) *websocket.Subscription {
+	subscription := websocket.NewSubscription()
+	subscription.Addresses[strings.ToLower(request.Address)] = struct{}{}
+	subscription.ExcludePoap = !request.IncludePoap
+
+	if len(request.Tag) > 0 {
+		subscription.Tags, subscription.Types, request.IncludePoap = s.CheckRequestTagAndType(request.Tag, request.Type)
+		subscription.ExcludePoap = !request.IncludePoap
+	}
+
+	subscription.Networks = lower(request.Network)
+	subscription.Platforms = lower(request.Platform)
+
+	return subscription
+}
+
+// GetNotesAfter returns the notes of subscription since cursor from the oldest with their actions
+func (s *Service) GetNotesAfter(ctx context.Context, subscription websocket.Subscription, cursor string) ([]dbModel.Transaction, error) {
+	request := model.BatchGetNotesRequest{
+		Address:     subscription.AddressList(),
+		Tag:         subscription.Tags,
+		Type:        subscription.Types,
+		Network:     subscription.Networks,
+		Platform:    subscription.Platforms,
+		Cursor:      cursor,
+		Limit:       model.DefaultLimit,
+		IncludePoap: !subscription.ExcludePoap,
+	}
+
+	transactions := make([]dbModel.Transaction, 0)
+
+	for len(transactions) < maxResumeNotes {
+		page, err := dao.GetTransactionsAfter(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, page...)
+
+		if len(page) < request.Limit {
+			break
+		}
+
+		request.Cursor = pagination.New(page[len(page)-1]).String()
+	}
+
+	transactionHashes := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		transactionHashes = append(transactionHashes, transaction.Hash)
+	}
+
+	transfers, err := dao.GetTransfers(ctx, transactionHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	transferMap := make(map[string][]dbModel.Transfer)
+	for _, transfer := range transfers {
+		transferMap[transfer.TransactionHash] = append(transferMap[transfer.TransactionHash], transfer)
+	}
+
+	for index := range transactions {
+		transactions[index].Transfers = transferMap[transactions[index].Hash]
+	}
+
+	return transactions, nil
+}
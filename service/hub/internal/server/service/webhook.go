@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/naturalselectionlabs/pregod/common/database"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/dao"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/webhook"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxWebhooks         = 20
+	MaxWebhookAddresses = 1000
+)
+
+var (
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrWebhookLimitExceeded = fmt.Errorf("an API key can register at most %d webhooks with %d addresses each", MaxWebhooks, MaxWebhookAddresses)
+)
+
+func (s *Service) CreateWebhook(ctx context.Context, owner string, request model.PostWebhookRequest) (*dbModel.Webhook, error) {
+	webhooks, err := dao.GetWebhooks(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := lo.Uniq(lower(request.Address))
+
+	if len(webhooks) >= MaxWebhooks || len(addresses) > MaxWebhookAddresses {
+		return nil, ErrWebhookLimitExceeded
+	}
+
+	if err := webhook.ValidateURL(ctx, request.URL); err != nil {
+		return nil, err
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	item := dbModel.Webhook{
+		ID:        uuid.New().String(),
+		Owner:     owner,
+		URL:       request.URL,
+		Secret:    secret,
+		Addresses: addresses,
+		Tags:      lower(request.Tag),
+		Types:     lower(request.Type),
+		Networks:  lower(request.Network),
+		Platforms: lower(request.Platform),
+		Status:    true,
+	}
+
+	if err := dao.CreateWebhook(ctx, &item); err != nil {
+		return nil, err
+	}
+
+	s.Webhook.Reset()
+
+	return &item, nil
+}
+
+func (s *Service) GetWebhooks(ctx context.Context, owner string) ([]dbModel.Webhook, error) {
+	return dao.GetWebhooks(ctx, owner)
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, owner, id string) error {
+	deleted, err := dao.DeleteWebhook(ctx, owner, id)
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+
+	s.Webhook.Reset()
+
+	return nil
+}
+
+func (s *Service) GetWebhookDeliveries(ctx context.Context, owner string, request model.GetWebhookDeliveriesRequest) ([]dbModel.WebhookDelivery, error) {
+	if _, err := s.getWebhook(ctx, owner, request.ID); err != nil {
+		return nil, err
+	}
+
+	if request.Limit <= 0 || request.Limit > model.DefaultLimit {
+		request.Limit = model.DefaultLimit
+	}
+
+	return dao.GetWebhookDeliveries(ctx, request)
+}
+
+// ReplayWebhookDeliveries attempts the failed deliveries of a webhook again, it returns the number of them
+func (s *Service) ReplayWebhookDeliveries(ctx context.Context, owner string, request model.ReplayWebhookRequest) (int64, error) {
+	if _, err := s.getWebhook(ctx, owner, request.ID); err != nil {
+		return 0, err
+	}
+
+	return webhook.Replay(ctx, database.Global(), request.ID, request.DeliveryID)
+}
+
+func (s *Service) getWebhook(ctx context.Context, owner, id string) (dbModel.Webhook, error) {
+	item, err := dao.GetWebhook(ctx, owner, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return item, ErrWebhookNotFound
+	}
+
+	return item, err
+}
+
+func lower(values []string) []string {
+	return lo.Map(values, func(value string, _ int) string {
+		return strings.ToLower(value)
+	})
+}
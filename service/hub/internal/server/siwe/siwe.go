@@ -0,0 +1,247 @@
+package siwe
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	Version = "1"
+
+	headerSuffix = " wants you to sign in with your Ethereum account:"
+)
+
+var (
+	ErrInvalidMessage   = errors.New("invalid sign-in message")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpiredMessage   = errors.New("sign-in message is expired")
+	ErrNotYetValid      = errors.New("sign-in message is not yet valid")
+)
+
+// Message is an EIP-4361 Sign-In with Ethereum message
+type Message struct {
+	Domain         string
+	Address        common.Address
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        string
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// String renders the message in the EIP-4361 format, which is the text to sign
+func (m *Message) String() string {
+	var builder strings.Builder
+
+	builder.WriteString(m.Domain + headerSuffix + "\n")
+	builder.WriteString(m.Address.Hex() + "\n\n")
+
+	if len(m.Statement) > 0 {
+		builder.WriteString(m.Statement + "\n\n")
+	}
+
+	builder.WriteString("URI: " + m.URI + "\n")
+	builder.WriteString("Version: " + m.Version + "\n")
+	builder.WriteString("Chain ID: " + m.ChainID + "\n")
+	builder.WriteString("Nonce: " + m.Nonce + "\n")
+	builder.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339))
+
+	if m.ExpirationTime != nil {
+		builder.WriteString("\nExpiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+
+	if m.NotBefore != nil {
+		builder.WriteString("\nNot Before: " + m.NotBefore.UTC().Format(time.RFC3339))
+	}
+
+	if len(m.RequestID) > 0 {
+		builder.WriteString("\nRequest ID: " + m.RequestID)
+	}
+
+	if len(m.Resources) > 0 {
+		builder.WriteString("\nResources:")
+
+		for _, resource := range m.Resources {
+			builder.WriteString("\n- " + resource)
+		}
+	}
+
+	return builder.String()
+}
+
+// Validate checks the validity period of the message at now
+func (m *Message) Validate(now time.Time) error {
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return ErrExpiredMessage
+	}
+
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return ErrNotYetValid
+	}
+
+	return nil
+}
+
+// Parse parses an EIP-4361 message
+func Parse(text string) (*Message, error) {
+	scanner := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(text, "\r\n", "\n")))
+
+	var message Message
+
+	// The domain and the address
+	if !scanner.Scan() || !strings.HasSuffix(scanner.Text(), headerSuffix) {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidMessage)
+	}
+
+	message.Domain = strings.TrimSuffix(scanner.Text(), headerSuffix)
+
+	if !scanner.Scan() || !common.IsHexAddress(scanner.Text()) {
+		return nil, fmt.Errorf("%w: invalid address", ErrInvalidMessage)
+	}
+
+	message.Address = common.HexToAddress(scanner.Text())
+
+	var (
+		fields    = make(map[string]string)
+		resources bool
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case resources && strings.HasPrefix(line, "- "):
+			message.Resources = append(message.Resources, strings.TrimPrefix(line, "- "))
+		case line == "Resources:":
+			resources = true
+		case line == "":
+			continue
+		default:
+			key, value, found := strings.Cut(line, ": ")
+
+			switch key {
+			case "URI", "Version", "Chain ID", "Nonce", "Issued At", "Expiration Time", "Not Before", "Request ID":
+				if found {
+					fields[key] = value
+
+					continue
+				}
+			}
+
+			// The statement is the only line before the fields
+			if len(fields) > 0 || len(message.Statement) > 0 {
+				return nil, fmt.Errorf("%w: unexpected line %q", ErrInvalidMessage, line)
+			}
+
+			message.Statement = line
+		}
+	}
+
+	message.URI = fields["URI"]
+	message.Version = fields["Version"]
+	message.ChainID = fields["Chain ID"]
+	message.Nonce = fields["Nonce"]
+	message.RequestID = fields["Request ID"]
+
+	if len(message.URI) == 0 || message.Version != Version || len(message.ChainID) == 0 || len(message.Nonce) < 8 {
+		return nil, fmt.Errorf("%w: missing required fields", ErrInvalidMessage)
+	}
+
+	var err error
+
+	if message.IssuedAt, err = time.Parse(time.RFC3339, fields["Issued At"]); err != nil {
+		return nil, fmt.Errorf("%w: invalid issued at", ErrInvalidMessage)
+	}
+
+	if message.ExpirationTime, err = parseOptionalTime(fields["Expiration Time"]); err != nil {
+		return nil, fmt.Errorf("%w: invalid expiration time", ErrInvalidMessage)
+	}
+
+	if message.NotBefore, err = parseOptionalTime(fields["Not Before"]); err != nil {
+		return nil, fmt.Errorf("%w: invalid not before", ErrInvalidMessage)
+	}
+
+	return &message, nil
+}
+
+// Recover returns the address which signed text with personal_sign as EIP-191 defines
+func Recover(text, signature string) (common.Address, error) {
+	data, err := hexutil.Decode(signature)
+	if err != nil || len(data) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	// Wallets return the recovery id as 27 or 28
+	if data[crypto.RecoveryIDOffset] >= 27 {
+		data[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash([]byte(text)), data)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+// Verify parses text and checks it's signed by its address and valid at now
+func Verify(text, signature string, now time.Time) (*Message, error) {
+	message, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := Recover(text, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if signer != message.Address {
+		return nil, ErrInvalidSignature
+	}
+
+	if err := message.Validate(now); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// NewNonce returns a random alphanumeric nonce
+func NewNonce() (string, error) {
+	buffer := make([]byte, 16)
+
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buffer), nil
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
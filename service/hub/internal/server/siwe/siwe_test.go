@@ -0,0 +1,107 @@
+package siwe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	issuedAt := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	expirationTime := issuedAt.Add(10 * time.Minute)
+
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	message := Message{
+		Domain:         "api.rss3.io",
+		Address:        crypto.PubkeyToAddress(key.PublicKey),
+		Statement:      "Apply for an API key.",
+		URI:            "https://api.rss3.io/apikey/apply",
+		Version:        Version,
+		ChainID:        "1",
+		Nonce:          "32891756abcdefgh",
+		IssuedAt:       issuedAt,
+		ExpirationTime: &expirationTime,
+		Resources:      []string{"https://rss3.io"},
+	}
+
+	parsed, err := Parse(message.String())
+	assert.NoError(t, err)
+	assert.Equal(t, message, *parsed)
+
+	// The statement is optional
+	message.Statement = ""
+	parsed, err = Parse(message.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "", parsed.Statement)
+
+	_, err = Parse("example.com wants you to sign in with your Ethereum account:\nnot an address\n")
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	_, err = Parse(message.String() + "\nunexpected")
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	message.Nonce = ""
+	_, err = Parse(message.String())
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 5, 0, 0, time.UTC)
+	expirationTime := now.Add(5 * time.Minute)
+
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	other, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	message := Message{
+		Domain:         "api.rss3.io",
+		Address:        crypto.PubkeyToAddress(key.PublicKey),
+		URI:            "https://api.rss3.io/apikey/apply",
+		Version:        Version,
+		ChainID:        "1",
+		Nonce:          "32891756abcdefgh",
+		IssuedAt:       now.Add(-5 * time.Minute),
+		ExpirationTime: &expirationTime,
+	}
+
+	text := message.String()
+
+	signature := sign(t, text, key.D.Bytes())
+
+	verified, err := Verify(text, signature, now)
+	assert.NoError(t, err)
+	assert.Equal(t, message.Address, verified.Address)
+
+	_, err = Verify(text, signature, expirationTime)
+	assert.ErrorIs(t, err, ErrExpiredMessage)
+
+	_, err = Verify(text, sign(t, text, other.D.Bytes()), now)
+	assert.ErrorIs(t, err, ErrInvalidSignature, "signed by another account")
+
+	_, err = Verify(text+"\nRequest ID: 1", signature, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature, "tampered")
+
+	_, err = Verify(text, "0x1234", now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+// sign signs text as personal_sign does, with a recovery id of 27 or 28
+func sign(t *testing.T, text string, privateKey []byte) string {
+	key, err := crypto.ToECDSA(privateKey)
+	assert.NoError(t, err)
+
+	signature, err := crypto.Sign(accounts.TextHash([]byte(text)), key)
+	assert.NoError(t, err)
+
+	signature[crypto.RecoveryIDOffset] += 27
+
+	return hexutil.Encode(signature)
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var ErrInvalidURL = errors.New("invalid webhook url")
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't reachable from the internet either
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// ValidateURL reports whether rawURL can receive deliveries, it must be https and resolve to public addresses only
+func ValidateURL(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, err)
+	}
+
+	if target.Scheme != "https" || target.Hostname() == "" {
+		return fmt.Errorf("%w: an https url is required", ErrInvalidURL)
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, target.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, err)
+	}
+
+	for _, address := range addresses {
+		if !PublicIP(address.IP) {
+			return fmt.Errorf("%w: %s resolves to a non-public address", ErrInvalidURL, target.Hostname())
+		}
+	}
+
+	return nil
+}
+
+// PublicIP reports whether ip is routable on the internet, the loopback, private and link-local addresses aren't
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsUnspecified() ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// dialControl rejects the connections to non-public addresses, the address is checked after resolving,
+// so that a host resolving to a public address at creation and to a private one later is rejected too
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+		return fmt.Errorf("%w: %s is a non-public address", ErrInvalidURL, host)
+	}
+
+	return nil
+}
+
+// newHTTPClient returns a client which connects to public addresses over https only, including the redirects.
+// The proxies from the environment are ignored, since the address dialed would be of the proxy.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	dialer := net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if request.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirected to %s", ErrInvalidURL, request.URL.Scheme)
+			}
+
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,484 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/mq"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/websocket"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultInterval    = 5 * time.Second
+	DefaultTimeout     = 10 * time.Second
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = 30 * time.Second
+	DefaultMaxBackoff  = 6 * time.Hour
+
+	EventNotes = "notes"
+
+	// The webhooks are reloaded from the database at this interval
+	reloadInterval = time.Minute
+	// The due deliveries claimed at a time
+	batchSize = 100
+	// The requests sent at the same time
+	maxConcurrency = 16
+	// The response body kept in the delivery log
+	maxErrorLength = 512
+	// The backoff of consuming while the deliveries can't be created
+	consumeBackoff    = time.Second
+	maxConsumeBackoff = time.Minute
+)
+
+// Payload is the body of a delivery
+type Payload struct {
+	ID        string              `json:"id"`
+	WebhookID string              `json:"webhook_id"`
+	Event     string              `json:"event"`
+	Timestamp time.Time           `json:"timestamp"`
+	Result    []model.Transaction `json:"result"`
+}
+
+// Webhook matches the notes written by the indexers and crawlers with the registered webhooks, and delivers them.
+// The notes are consumed from a queue shared by the hubs, so that every note is delivered once,
+// and the deliveries are claimed from the database, so that any hub can retry them.
+type Webhook struct {
+	queue       mq.Queue
+	httpClient  *http.Client
+	interval    time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+
+	locker   sync.RWMutex
+	webhooks map[string][]model.Webhook
+	loadedAt time.Time
+}
+
+// Run creates the deliveries of the refresh messages and sends the due ones until the context is done
+func (w *Webhook) Run(ctx context.Context) error {
+	deliveryCh, err := w.queue.Consume(ctx, mq.Binding{
+		Exchange: protocol.ExchangeRefresh,
+		Queue:    protocol.HubWebhookQueue,
+	})
+	if err != nil {
+		return err
+	}
+
+	go w.deliverLoop(ctx)
+
+	failures := 0
+
+	for delivery := range deliveryCh {
+		if err := w.handleMessage(ctx, delivery.Body); err != nil {
+			loggerx.Global().Error("failed to create webhook deliveries", zap.Error(err))
+
+			_ = w.queue.Nack(context.Background(), delivery, true)
+
+			// Don't take the requeued message again right away while the database is unavailable
+			failures++
+
+			select {
+			case <-time.After(Backoff(failures, consumeBackoff, maxConsumeBackoff)):
+			case <-ctx.Done():
+			}
+
+			continue
+		}
+
+		failures = 0
+
+		_ = w.queue.Ack(context.Background(), delivery)
+	}
+
+	return nil
+}
+
+// handleMessage creates a delivery for each webhook matching the notes of a refresh message
+func (w *Webhook) handleMessage(ctx context.Context, data []byte) error {
+	var message protocol.RefreshMessage
+
+	if err := json.Unmarshal(data, &message); err != nil {
+		loggerx.Global().Error("failed to unmarshal refresh message", zap.Error(err))
+
+		return nil
+	}
+
+	if len(message.Transactions) == 0 {
+		return nil
+	}
+
+	webhooks, err := w.getWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := Match(webhooks, message.Transactions, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return database.Global().WithContext(ctx).Create(&deliveries).Error
+}
+
+// Match returns the deliveries of the notes to the webhooks indexed by address
+func Match(webhooks map[string][]model.Webhook, transactions []model.Transaction, now time.Time) ([]model.WebhookDelivery, error) {
+	var (
+		ids     []string
+		matched = make(map[string]model.Webhook)
+	)
+
+	owners := lo.Uniq(lo.Map(transactions, func(transaction model.Transaction, _ int) string {
+		return strings.ToLower(transaction.Owner)
+	}))
+
+	for _, owner := range owners {
+		for _, webhook := range webhooks[owner] {
+			if _, exists := matched[webhook.ID]; !exists {
+				ids = append(ids, webhook.ID)
+				matched[webhook.ID] = webhook
+			}
+		}
+	}
+
+	deliveries := make([]model.WebhookDelivery, 0, len(ids))
+
+	for _, id := range ids {
+		webhook := matched[id]
+
+		subscription := Subscription(webhook)
+
+		notes := subscription.Filter(transactions)
+		if len(notes) == 0 {
+			continue
+		}
+
+		payload := Payload{
+			ID:        uuid.New().String(),
+			WebhookID: webhook.ID,
+			Event:     EventNotes,
+			Timestamp: now,
+			Result:    notes,
+		}
+
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, model.WebhookDelivery{
+			ID:            payload.ID,
+			WebhookID:     webhook.ID,
+			Status:        model.WebhookDeliveryPending,
+			Payload:       data,
+			NextAttemptAt: now,
+		})
+	}
+
+	return deliveries, nil
+}
+
+// Subscription returns the filters of webhook
+func Subscription(webhook model.Webhook) *websocket.Subscription {
+	subscription := websocket.NewSubscription()
+
+	for _, address := range webhook.Addresses {
+		subscription.Addresses[strings.ToLower(address)] = struct{}{}
+	}
+
+	subscription.Tags = webhook.Tags
+	subscription.Types = webhook.Types
+	subscription.Networks = webhook.Networks
+	subscription.Platforms = webhook.Platforms
+
+	return subscription
+}
+
+// getWebhooks returns the enabled webhooks by address, the stale ones are used if reloading fails
+func (w *Webhook) getWebhooks(ctx context.Context) (map[string][]model.Webhook, error) {
+	w.locker.RLock()
+	webhooks, loadedAt := w.webhooks, w.loadedAt
+	w.locker.RUnlock()
+
+	if webhooks != nil && time.Since(loadedAt) < reloadInterval {
+		return webhooks, nil
+	}
+
+	var internalWebhooks []model.Webhook
+
+	if err := database.Global().WithContext(ctx).Where("status IS TRUE").Find(&internalWebhooks).Error; err != nil {
+		if webhooks != nil {
+			loggerx.Global().Error("failed to reload webhooks", zap.Error(err))
+
+			return webhooks, nil
+		}
+
+		return nil, fmt.Errorf("get webhooks: %w", err)
+	}
+
+	webhooks = make(map[string][]model.Webhook)
+
+	for _, webhook := range internalWebhooks {
+		for _, address := range lo.Uniq(webhook.Addresses) {
+			address = strings.ToLower(address)
+			webhooks[address] = append(webhooks[address], webhook)
+		}
+	}
+
+	w.locker.Lock()
+	w.webhooks, w.loadedAt = webhooks, time.Now()
+	w.locker.Unlock()
+
+	return webhooks, nil
+}
+
+// Reset drops the loaded webhooks, so that the changes take effect in the next message
+func (w *Webhook) Reset() {
+	w.locker.Lock()
+	defer w.locker.Unlock()
+
+	w.webhooks = nil
+}
+
+func (w *Webhook) deliverLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Keep sending while there are due deliveries
+		for {
+			count, err := w.deliver(ctx)
+			if err != nil && ctx.Err() == nil {
+				loggerx.Global().Error("failed to deliver webhooks", zap.Error(err))
+			}
+
+			if err != nil || count < batchSize {
+				break
+			}
+		}
+	}
+}
+
+// deliver claims and sends a batch of due deliveries, it returns the number of claimed deliveries
+func (w *Webhook) deliver(ctx context.Context) (int, error) {
+	var deliveries []model.WebhookDelivery
+
+	// The claimed deliveries are postponed, they are attempted again by any hub if this one stops
+	lease := time.Now().Add(2 * w.httpClient.Timeout)
+
+	if err := database.Global().WithContext(ctx).Raw(
+		`UPDATE webhook_delivery SET next_attempt_at = ? WHERE id IN (
+			SELECT id FROM webhook_delivery WHERE status = ? AND next_attempt_at <= NOW() ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED
+		) RETURNING *`,
+		lease, model.WebhookDeliveryPending, batchSize,
+	).Scan(&deliveries).Error; err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	var webhooks []model.Webhook
+
+	if err := database.Global().WithContext(ctx).
+		Where("id IN ?", lo.Uniq(lo.Map(deliveries, func(delivery model.WebhookDelivery, _ int) string {
+			return delivery.WebhookID
+		}))).
+		Find(&webhooks).Error; err != nil {
+		return 0, fmt.Errorf("get webhooks: %w", err)
+	}
+
+	webhookMap := lo.KeyBy(webhooks, func(webhook model.Webhook) string {
+		return webhook.ID
+	})
+
+	for _, chunk := range lo.Chunk(deliveries, maxConcurrency) {
+		var wg sync.WaitGroup
+
+		for _, delivery := range chunk {
+			wg.Add(1)
+
+			go func(delivery model.WebhookDelivery) {
+				defer wg.Done()
+
+				webhook, exists := webhookMap[delivery.WebhookID]
+
+				var (
+					statusCode int
+					sendErr    error
+				)
+
+				if !exists || !webhook.Status {
+					sendErr = fmt.Errorf("webhook is deleted or disabled")
+				} else {
+					statusCode, sendErr = w.send(ctx, webhook, delivery)
+				}
+
+				if err := w.settle(ctx, delivery, statusCode, sendErr, exists && webhook.Status); err != nil {
+					loggerx.Global().Error("failed to update webhook delivery", zap.Error(err), zap.String("delivery", delivery.ID))
+				}
+			}(delivery)
+		}
+
+		wg.Wait()
+	}
+
+	return len(deliveries), nil
+}
+
+// send posts the payload of delivery to webhook, a response other than 2xx is an error
+func (w *Webhook) send(ctx context.Context, webhook model.Webhook, delivery model.WebhookDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	// The addresses are checked by the dialer, since the host may resolve differently from the creation
+	if request.URL.Scheme != "https" {
+		return 0, fmt.Errorf("%w: an https url is required", ErrInvalidURL)
+	}
+
+	request.Header.Set("Content-Type", protocol.ContentTypeJSON)
+	request.Header.Set("User-Agent", "pregod-webhook/"+protocol.Version)
+	request.Header.Set(HeaderWebhookID, webhook.ID)
+	request.Header.Set(HeaderDelivery, delivery.ID)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	response, err := w.httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorLength))
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return response.StatusCode, fmt.Errorf("unexpected status %d: %s", response.StatusCode, body)
+	}
+
+	return response.StatusCode, nil
+}
+
+// settle records the result of an attempt, a failed delivery is retried with backoff until the attempts run out
+func (w *Webhook) settle(ctx context.Context, delivery model.WebhookDelivery, statusCode int, sendErr error, retryable bool) error {
+	now := time.Now()
+	attempts := delivery.Attempts + 1
+
+	updates := map[string]any{
+		"attempts":      attempts,
+		"response_code": statusCode,
+		"error":         "",
+	}
+
+	switch {
+	case sendErr == nil:
+		updates["status"] = model.WebhookDeliverySucceeded
+		updates["delivered_at"] = now
+	case !retryable || attempts >= w.maxAttempts:
+		updates["status"] = model.WebhookDeliveryFailed
+		updates["error"] = truncate(sendErr.Error(), maxErrorLength)
+	default:
+		updates["next_attempt_at"] = now.Add(Backoff(attempts, w.backoff, w.maxBackoff))
+		updates["error"] = truncate(sendErr.Error(), maxErrorLength)
+	}
+
+	return database.Global().WithContext(ctx).
+		Model(&model.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(updates).Error
+}
+
+// Replay resets the failed deliveries of webhook to be attempted again, all of them if ids is empty
+func Replay(ctx context.Context, db *gorm.DB, webhookID string, ids []string) (int64, error) {
+	sql := db.WithContext(ctx).
+		Model(&model.WebhookDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhookID, model.WebhookDeliveryFailed)
+
+	if len(ids) > 0 {
+		sql = sql.Where("id IN ?", ids)
+	}
+
+	result := sql.Updates(map[string]any{
+		"status":          model.WebhookDeliveryPending,
+		"attempts":        0,
+		"error":           "",
+		"next_attempt_at": time.Now(),
+	})
+
+	return result.RowsAffected, result.Error
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+
+	return value[:length]
+}
+
+func New(config *configx.Webhook, queue mq.Queue) *Webhook {
+	webhook := Webhook{
+		queue:       queue,
+		httpClient:  newHTTPClient(DefaultTimeout),
+		interval:    DefaultInterval,
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+	}
+
+	if config != nil {
+		if config.Interval > 0 {
+			webhook.interval = config.Interval
+		}
+
+		if config.Timeout > 0 {
+			webhook.httpClient.Timeout = config.Timeout
+		}
+
+		if config.MaxAttempts > 0 {
+			webhook.maxAttempts = config.MaxAttempts
+		}
+
+		if config.Backoff > 0 {
+			webhook.backoff = config.Backoff
+		}
+
+		if config.MaxBackoff > 0 {
+			webhook.maxBackoff = config.MaxBackoff
+		}
+	}
+
+	return &webhook
+}
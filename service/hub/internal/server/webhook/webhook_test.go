@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+
+	signature := Sign("secret", 1700000000, body)
+
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.True(t, Verify("secret", 1700000000, body, signature))
+	assert.False(t, Verify("secret", 1700000001, body, signature), "timestamp")
+	assert.False(t, Verify("other", 1700000000, body, signature), "secret")
+	assert.False(t, Verify("secret", 1700000000, []byte(`{"id":"2"}`), signature), "body")
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(0, 30*time.Second, time.Hour))
+	assert.Equal(t, 30*time.Second, Backoff(1, 30*time.Second, time.Hour))
+	assert.Equal(t, time.Minute, Backoff(2, 30*time.Second, time.Hour))
+	assert.Equal(t, 8*time.Minute, Backoff(5, 30*time.Second, time.Hour))
+	assert.Equal(t, time.Hour, Backoff(100, 30*time.Second, time.Hour))
+	assert.Equal(t, time.Minute, Backoff(1, time.Hour, time.Minute))
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	all := model.Webhook{ID: "all", Addresses: pq.StringArray{"0xa", "0xb"}}
+	swaps := model.Webhook{ID: "swaps", Addresses: pq.StringArray{"0xA"}, Tags: pq.StringArray{filter.TagExchange}, Types: pq.StringArray{filter.ExchangeSwap}}
+	polygon := model.Webhook{ID: "polygon", Addresses: pq.StringArray{"0xa"}, Networks: pq.StringArray{protocol.NetworkPolygon}}
+
+	webhooks := map[string][]model.Webhook{
+		"0xa": {all, swaps, polygon},
+		"0xb": {all},
+	}
+
+	transactions := []model.Transaction{
+		{Hash: "0x1", Owner: "0xa", Network: protocol.NetworkEthereum, Tag: filter.TagExchange, Type: filter.ExchangeSwap},
+		{Hash: "0x2", Owner: "0xb", Network: protocol.NetworkEthereum, Tag: filter.TagTransaction, Type: filter.TransactionTransfer},
+		{Hash: "0x3", Owner: "0xc", Network: protocol.NetworkPolygon},
+	}
+
+	deliveries, err := Match(webhooks, transactions, now)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 2, "no polygon notes of 0xa")
+
+	expected := map[string][]string{
+		"all":   {"0x1", "0x2"},
+		"swaps": {"0x1"},
+	}
+
+	for _, delivery := range deliveries {
+		var payload Payload
+
+		assert.NoError(t, json.Unmarshal(delivery.Payload, &payload))
+		assert.Equal(t, delivery.ID, payload.ID)
+		assert.Equal(t, delivery.WebhookID, payload.WebhookID)
+		assert.Equal(t, model.WebhookDeliveryPending, delivery.Status)
+		assert.Equal(t, now, delivery.NextAttemptAt)
+
+		var hashes []string
+		for _, note := range payload.Result {
+			hashes = append(hashes, note.Hash)
+		}
+
+		assert.Equal(t, expected[delivery.WebhookID], hashes)
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	ctx := context.Background()
+
+	assert.NoError(t, ValidateURL(ctx, "https://8.8.8.8/webhook"))
+
+	for _, rawURL := range []string{
+		"http://8.8.8.8/webhook",
+		"https://127.0.0.1/webhook",
+		"https://10.0.0.1/webhook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://100.64.0.1/webhook",
+		"https://[::1]/webhook",
+		"https:///webhook",
+	} {
+		assert.ErrorIs(t, ValidateURL(ctx, rawURL), ErrInvalidURL, rawURL)
+	}
+
+	// The dialer checks the resolved addresses, a host rebound to a private address is rejected on delivery
+	assert.NoError(t, dialControl("tcp4", "8.8.8.8:443", nil))
+	assert.ErrorIs(t, dialControl("tcp4", "192.168.1.1:443", nil), ErrInvalidURL)
+	assert.ErrorIs(t, dialControl("tcp6", "[fe80::1]:443", nil), ErrInvalidURL)
+}
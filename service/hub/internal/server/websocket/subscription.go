@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"strings"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/samber/lo"
+)
+
+// Subscription filters the notes delivered to a client, an empty filter matches everything
+type Subscription struct {
+	Addresses map[string]struct{}
+	Tags      []string
+	Types     []string
+	Networks  []string
+	Platforms []string
+	// ExcludePoap hides POAP notes as the notes API does by default
+	ExcludePoap bool
+}
+
+// Match reports whether transaction is a successful note of a subscribed address passing the filters
+func (s *Subscription) Match(transaction dbModel.Transaction) bool {
+	if _, exists := s.Addresses[strings.ToLower(transaction.Owner)]; !exists {
+		return false
+	}
+
+	// Failed transactions are hidden as the notes API does
+	if transaction.Success != nil && !*transaction.Success {
+		return false
+	}
+
+	if s.ExcludePoap && transaction.Type == filter.CollectiblePoap {
+		return false
+	}
+
+	return matchAny(s.Tags, transaction.Tag) &&
+		matchAny(s.Types, transaction.Type) &&
+		matchAny(s.Networks, transaction.Network) &&
+		matchAny(s.Platforms, transaction.Platform)
+}
+
+// Filter returns the notes of transactions matched by the subscription
+func (s *Subscription) Filter(transactions []dbModel.Transaction) []dbModel.Transaction {
+	return lo.Filter(transactions, func(transaction dbModel.Transaction, _ int) bool {
+		return s.Match(transaction)
+	})
+}
+
+func (s *Subscription) AddressList() []string {
+	addresses := make([]string, 0, len(s.Addresses))
+	for address := range s.Addresses {
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}
+
+func (s *Subscription) clone() Subscription {
+	addresses := make(map[string]struct{}, len(s.Addresses))
+	for address := range s.Addresses {
+		addresses[address] = struct{}{}
+	}
+
+	return Subscription{
+		Addresses:   addresses,
+		Tags:        s.Tags,
+		Types:       s.Types,
+		Networks:    s.Networks,
+		Platforms:   s.Platforms,
+		ExcludePoap: s.ExcludePoap,
+	}
+}
+
+func matchAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lower(values []string) []string {
+	return lo.Map(values, func(value string, _ int) string {
+		return strings.ToLower(value)
+	})
+}
+
+func NewSubscription() *Subscription {
+	return &Subscription{
+		Addresses: make(map[string]struct{}),
+	}
+}
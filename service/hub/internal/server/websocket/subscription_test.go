@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"testing"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionMatch(t *testing.T) {
+	failed := false
+
+	subscription := NewSubscription()
+	subscription.Addresses["0xa"] = struct{}{}
+
+	note := dbModel.Transaction{
+		Hash:     "0x1",
+		Owner:    "0xA",
+		Network:  protocol.NetworkEthereum,
+		Platform: "Uniswap",
+		Tag:      filter.TagExchange,
+		Type:     filter.ExchangeSwap,
+	}
+
+	assert.True(t, subscription.Match(note), "no filters")
+	assert.False(t, subscription.Match(dbModel.Transaction{Owner: "0xb"}), "unsubscribed address")
+	assert.False(t, subscription.Match(dbModel.Transaction{Owner: "0xa", Success: &failed}), "failed transaction")
+
+	subscription.Tags = []string{filter.TagExchange}
+	subscription.Networks = []string{protocol.NetworkEthereum, protocol.NetworkPolygon}
+	subscription.Platforms = lower([]string{"Uniswap"})
+	assert.True(t, subscription.Match(note))
+
+	subscription.Types = []string{filter.ExchangeLiquidity}
+	assert.False(t, subscription.Match(note), "type")
+
+	subscription.Types = nil
+	subscription.Networks = []string{protocol.NetworkPolygon}
+	assert.False(t, subscription.Match(note), "network")
+
+	assert.Empty(t, subscription.Filter([]dbModel.Transaction{note}))
+}
+
+func TestSubscriptionClone(t *testing.T) {
+	subscription := NewSubscription()
+	subscription.Addresses["0xa"] = struct{}{}
+
+	clone := subscription.clone()
+	subscription.Addresses["0xb"] = struct{}{}
+
+	assert.Equal(t, []string{"0xa"}, clone.AddressList())
+}
@@ -1,7 +1,6 @@
 package websocket
 
 import (
-	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -10,50 +9,35 @@ import (
 )
 
 const (
-	waitTime = 10 * time.Second
+	// The time allowed to write a message to the peer
+	writeWait = 10 * time.Second
+	// The time allowed to read the next pong from the peer
+	pongWait = 60 * time.Second
+	// Pings are sent within pongWait
+	pingPeriod = pongWait * 9 / 10
+	// The maximum size of a request from the peer
+	maxMessageSize = 64 * 1024
+
+	// The messages buffered for a client, the client is dropped when it is full
+	SendBufferSize = 256
 )
 
-var globalLocker sync.RWMutex
-
-var clientMaps map[string]*WSClient
-
-var once sync.Once
-
-func ReplaceGlobal(clientId string, client *WSClient) {
-	globalLocker.Lock()
-
-	defer globalLocker.Unlock()
-
-	clientMaps[clientId] = client
-}
-
-func GetClientMaps() map[string]*WSClient {
-	once.Do(func() {
-		clientMaps = make(map[string]*WSClient)
-	})
-
-	globalLocker.RLock()
-
-	defer globalLocker.RUnlock()
-
-	item := make(map[string]*WSClient)
-	for key, value := range clientMaps {
-		item[key] = value
-	}
-
-	return item
-}
-
+// WSClient is a subscriber of the hub, Conn is nil for the subscribers over other transports,
+// such as the SSE streams, which read Send themselves
 type WSClient struct {
-	Hub        *WSHub
-	Conn       *websocket.Conn
-	Send       chan []byte
-	ClientId   []byte
-	AddressMap map[string]struct{}
+	Hub          *WSHub
+	Conn         *websocket.Conn
+	Send         chan []byte
+	ClientId     []byte
+	Subscription *Subscription
+
+	// closeCode and closeText are sent to the peer when Send is closed by the hub
+	closeCode int
+	closeText string
 }
 
 func (c *WSClient) WriteMsg() {
-	ticker := time.NewTicker(waitTime)
+	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -61,10 +45,11 @@ func (c *WSClient) WriteMsg() {
 	for {
 		select {
 		case message, ok := <-c.Send:
+			_ = c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+
 			if !ok {
-				if err := c.Conn.WriteMessage(websocket.CloseMessage, []byte{}); err != nil {
-					return
-				}
+				_ = c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeText))
+
 				return
 			}
 
@@ -79,6 +64,8 @@ func (c *WSClient) WriteMsg() {
 				return
 			}
 		case <-ticker.C:
+			_ = c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
@@ -92,6 +79,12 @@ func (c *WSClient) ReadMsg() {
 		c.Conn.Close()
 	}()
 
+	c.Conn.SetReadLimit(maxMessageSize)
+	_ = c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
@@ -101,6 +94,17 @@ func (c *WSClient) ReadMsg() {
 			break
 		}
 
-		c.Hub.Action <- map[string][]byte{string(c.ClientId): message}
+		c.Hub.Action <- ClientMessage{Client: c, Data: message}
+	}
+}
+
+func NewClient(hub *WSHub, conn *websocket.Conn, clientId string) *WSClient {
+	return &WSClient{
+		Hub:          hub,
+		Conn:         conn,
+		Send:         make(chan []byte, SendBufferSize),
+		ClientId:     []byte(clientId),
+		Subscription: NewSubscription(),
+		closeCode:    websocket.CloseNormalClosure,
 	}
 }
@@ -1,35 +1,57 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/gorilla/websocket"
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
 	"github.com/naturalselectionlabs/pregod/common/protocol"
 	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
 	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/model"
+	"github.com/naturalselectionlabs/pregod/service/hub/internal/server/pagination"
 	"go.uber.org/zap"
-	"golang.org/x/exp/maps"
 )
 
+const (
+	HeartbeatInterval = 30 * time.Second
+
+	resumeTimeout = 30 * time.Second
+)
+
+// ResumeFunc returns the notes of subscription newer than the note of cursor in ascending order,
+// it's called with a copy of the subscription outside the hub
+type ResumeFunc func(ctx context.Context, subscription Subscription, cursor string) ([]dbModel.Transaction, error)
+
+// ClientMessage is a request read from a client
+type ClientMessage struct {
+	Client *WSClient
+	Data   []byte
+}
+
+type delivery struct {
+	client *WSClient
+	data   []byte
+}
+
 type WSHub struct {
 	Clients    map[*WSClient]string
-	Action     chan map[string][]byte
+	Action     chan ClientMessage
 	Broadcast  chan []byte
 	Register   chan *WSClient
 	Unregister chan *WSClient
-}
 
-func NewHub() *WSHub {
-	return &WSHub{
-		Action:     make(chan map[string][]byte),
-		Broadcast:  make(chan []byte),
-		Register:   make(chan *WSClient),
-		Unregister: make(chan *WSClient),
-		Clients:    make(map[*WSClient]string),
-	}
+	resume  ResumeFunc
+	deliver chan delivery
 }
 
 func (h *WSHub) Run() {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case client := <-h.Register:
@@ -37,7 +59,6 @@ func (h *WSHub) Run() {
 		case client := <-h.Unregister:
 			if _, ok := h.Clients[client]; ok {
 				delete(h.Clients, client)
-				delete(GetClientMaps(), string(client.ClientId))
 				close(client.Send)
 			}
 		case data := <-h.Broadcast:
@@ -46,44 +67,158 @@ func (h *WSHub) Run() {
 				loggerx.Global().Error("failed to unmarshal message", zap.Error(err))
 				continue
 			}
+
+			if len(message.Transactions) == 0 {
+				continue
+			}
+
 			for client := range h.Clients {
-				if _, ok := client.AddressMap[message.Address.Address]; ok {
-					client.Send <- data
+				if notes := client.Subscription.Filter(message.Transactions); len(notes) > 0 {
+					h.send(client, notification(notes))
 				}
 			}
-		case data := <-h.Action:
+		case item := <-h.deliver:
+			if _, ok := h.Clients[item.client]; ok {
+				h.send(item.client, item.data)
+			}
+		case <-ticker.C:
+			now := time.Now()
+			heartbeat, _ := json.Marshal(model.WebsocketNotification{Event: model.EventHeartbeat, Timestamp: &now})
+
 			for client := range h.Clients {
-				if message, ok := data[string(client.ClientId)]; ok {
-					request := model.WebSocketRequest{}
-					var response []byte
-					if err := json.Unmarshal(message, &request); err != nil || request.Action == "" || request.Id == nil {
-						loggerx.Global().Error("failed to unmarshal websocket message", zap.Error(err))
-						response, _ = json.Marshal(model.WebsocketResponse{Status: "error", Result: map[string]any{"msg": "failed to unmarshal websocket message"}})
-						client.Send <- response
-					}
-
-					switch request.Action {
-					case model.Subscribe:
-						for _, address := range request.AddressArr {
-							client.AddressMap[strings.ToLower(address)] = struct{}{}
-						}
-						response, _ = json.Marshal(model.WebsocketResponse{Id: *request.Id, Status: "success", Result: map[string]any{"msg": request.Action, "address": maps.Keys(client.AddressMap)}})
-						client.Send <- response
-					case model.Unsubscribe:
-						for _, address := range request.AddressArr {
-							delete(client.AddressMap, strings.ToLower(address))
-						}
-						response, _ = json.Marshal(model.WebsocketResponse{Id: *request.Id, Status: "success", Result: map[string]any{"msg": request.Action, "address": maps.Keys(client.AddressMap)}})
-						client.Send <- response
-					case model.Query:
-						response, _ = json.Marshal(model.WebsocketResponse{Id: *request.Id, Status: "success", Result: map[string]any{"msg": request.Action, "address": maps.Keys(client.AddressMap)}})
-						client.Send <- response
-					default:
-						response, _ = json.Marshal(model.WebsocketResponse{Id: *request.Id, Status: "error", Result: map[string]any{"msg": "unsupport action: " + request.Action}})
-						client.Send <- response
-					}
-				}
+				h.send(client, heartbeat)
+			}
+		case message := <-h.Action:
+			if _, ok := h.Clients[message.Client]; ok {
+				h.handleAction(message.Client, message.Data)
 			}
 		}
 	}
 }
+
+func (h *WSHub) handleAction(client *WSClient, data []byte) {
+	request := model.WebSocketRequest{}
+	if err := json.Unmarshal(data, &request); err != nil || request.Action == "" || request.Id == nil {
+		loggerx.Global().Error("failed to unmarshal websocket message", zap.Error(err))
+		h.respond(client, model.WebsocketResponse{Status: "error", Result: map[string]any{"msg": "failed to unmarshal websocket message"}})
+
+		return
+	}
+
+	subscription := client.Subscription
+
+	switch request.Action {
+	case model.Subscribe:
+		for _, address := range request.AddressArr {
+			subscription.Addresses[strings.ToLower(address)] = struct{}{}
+		}
+
+		// The filters given replace the previous ones
+		if len(request.Tag) > 0 {
+			subscription.Tags = lower(request.Tag)
+		}
+
+		if len(request.Type) > 0 {
+			subscription.Types = lower(request.Type)
+		}
+
+		if len(request.Network) > 0 {
+			subscription.Networks = lower(request.Network)
+		}
+
+		if len(request.Platform) > 0 {
+			subscription.Platforms = lower(request.Platform)
+		}
+
+		if len(request.Cursor) > 0 && h.resume != nil {
+			go h.replay(client, subscription.clone(), request.Cursor)
+		}
+	case model.Unsubscribe:
+		for _, address := range request.AddressArr {
+			delete(subscription.Addresses, strings.ToLower(address))
+		}
+	case model.Query:
+	default:
+		h.respond(client, model.WebsocketResponse{Id: *request.Id, Status: "error", Result: map[string]any{"msg": "unsupport action: " + request.Action}})
+
+		return
+	}
+
+	h.respond(client, model.WebsocketResponse{Id: *request.Id, Status: "success", Result: map[string]any{
+		"msg":      request.Action,
+		"address":  subscription.AddressList(),
+		"tag":      subscription.Tags,
+		"type":     subscription.Types,
+		"network":  subscription.Networks,
+		"platform": subscription.Platforms,
+	}})
+}
+
+// replay delivers the notes missed by a reconnected client since cursor, they may overlap with the notes
+// broadcast meanwhile, so clients should deduplicate notes by hash
+func (h *WSHub) replay(client *WSClient, subscription Subscription, cursor string) {
+	ctx, cancel := context.WithTimeout(context.Background(), resumeTimeout)
+	defer cancel()
+
+	notes, err := h.resume(ctx, subscription, cursor)
+	if err != nil {
+		loggerx.Global().Error("failed to resume websocket subscription", zap.Error(err), zap.String("websocket_id", string(client.ClientId)), zap.String("cursor", cursor))
+
+		data, _ := json.Marshal(model.WebsocketNotification{Event: model.EventError, Cursor: cursor, Message: "failed to resume from cursor"})
+		h.deliver <- delivery{client: client, data: data}
+
+		return
+	}
+
+	if len(notes) > 0 {
+		h.deliver <- delivery{client: client, data: notification(notes)}
+	}
+}
+
+func (h *WSHub) respond(client *WSClient, response model.WebsocketResponse) {
+	data, _ := json.Marshal(response)
+
+	h.send(client, data)
+}
+
+// send never blocks the hub, a client which doesn't keep up is dropped and should reconnect with its last cursor
+func (h *WSHub) send(client *WSClient, data []byte) {
+	select {
+	case client.Send <- data:
+	default:
+		loggerx.Global().Warn("websocket client is too slow", zap.String("websocket_id", string(client.ClientId)))
+
+		delete(h.Clients, client)
+
+		client.closeCode = websocket.CloseTryAgainLater
+		client.closeText = "too slow, resume with the last cursor"
+		close(client.Send)
+	}
+}
+
+// notification sorts notes from the oldest, its cursor is the cursor of the latest one
+func notification(notes []dbModel.Transaction) []byte {
+	sort.SliceStable(notes, func(i, j int) bool {
+		return pagination.New(notes[i]).Compare(pagination.New(notes[j])) > 0
+	})
+
+	data, _ := json.Marshal(model.WebsocketNotification{
+		Event:  model.EventNotes,
+		Cursor: pagination.New(notes[len(notes)-1]).String(),
+		Result: notes,
+	})
+
+	return data
+}
+
+func NewHub(resume ResumeFunc) *WSHub {
+	return &WSHub{
+		Action:     make(chan ClientMessage),
+		Broadcast:  make(chan []byte),
+		Register:   make(chan *WSClient),
+		Unregister: make(chan *WSClient),
+		Clients:    make(map[*WSClient]string),
+		resume:     resume,
+		deliver:    make(chan delivery),
+	}
+}
@@ -0,0 +1,55 @@
+// Package testutil builds the notes shared by the tests of the hub, it is only imported by the tests
+package testutil
+
+import (
+	"encoding/json"
+	"time"
+
+	dbModel "github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	Alice = "0x000000000000000000000000000000000000a11c"
+	Bob   = "0x0000000000000000000000000000000000000b0b"
+	USDC  = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	NFT   = "0x00000000000000000000000000000000000000f7"
+
+	Hash = "0x01"
+)
+
+var Timestamp = time.Unix(1700000000, 0)
+
+// Transfer returns a transfer of the transaction Hash on Ethereum
+func Transfer(tag, kind, from, to, metadata string) dbModel.Transfer {
+	return dbModel.Transfer{
+		TransactionHash: Hash,
+		Timestamp:       Timestamp,
+		Tag:             tag,
+		Type:            kind,
+		AddressFrom:     from,
+		AddressTo:       to,
+		Network:         protocol.NetworkEthereum,
+		Metadata:        json.RawMessage(metadata),
+	}
+}
+
+// Transaction returns the transaction Hash sent by Alice, the transfers are indexed in order
+func Transaction(success bool, transfers ...dbModel.Transfer) dbModel.Transaction {
+	fee := decimal.RequireFromString("0.002")
+
+	for index := range transfers {
+		transfers[index].Index = int64(index)
+	}
+
+	return dbModel.Transaction{
+		Hash:        Hash,
+		Timestamp:   Timestamp,
+		AddressFrom: Alice,
+		Network:     protocol.NetworkEthereum,
+		Fee:         &fee,
+		Success:     &success,
+		Transfers:   transfers,
+	}
+}
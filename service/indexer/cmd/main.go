@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
 	"net/http"
 	"net/http/pprof"
+	"os"
 	"time"
 
 	"github.com/naturalselectionlabs/pregod/common/protocol"
 	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/backfill"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/config"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/server"
 	"github.com/sirupsen/logrus"
@@ -29,6 +35,237 @@ func init() {
 	})
 }
 
+func newDeadLetterCommand(srv *server.Server) *cobra.Command {
+	deadLetterCommand := &cobra.Command{
+		Use:   "deadletter",
+		Short: "Inspect and replay the messages failed more than the retry ceiling",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return srv.InitializeQueue()
+		},
+	}
+
+	deadLetterCommand.PersistentFlags().Int("limit", 100, "maximum number of dead letters")
+
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "Print the dead letters as JSON and keep them in the queue",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			deadLetters, err := srv.ListDeadLetters(context.Background(), limit)
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(deadLetters)
+		},
+	}
+
+	replayCommand := &cobra.Command{
+		Use:   "replay",
+		Short: "Publish the dead letters back to their queues",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			replayed, err := srv.ReplayDeadLetters(context.Background(), limit)
+
+			loggerx.Global().Info("replay dead letters completion", zap.Int("replayed", replayed))
+
+			return err
+		},
+	}
+
+	deadLetterCommand.AddCommand(listCommand, replayCommand)
+
+	return deadLetterCommand
+}
+
+func newIndexCommand(srv *server.Server) *cobra.Command {
+	indexCommand := &cobra.Command{
+		Use:   "index",
+		Short: "Index an address once without the queue",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				message protocol.Message
+				options server.IndexOptions
+				dump    bool
+				diff    bool
+			)
+
+			message.Address, _ = cmd.Flags().GetString("address")
+			message.Network, _ = cmd.Flags().GetString("network")
+			options.Datasources, _ = cmd.Flags().GetStringSlice("datasource")
+			options.Workers, _ = cmd.Flags().GetStringSlice("worker")
+			options.DryRun, _ = cmd.Flags().GetBool("dry-run")
+			dump, _ = cmd.Flags().GetBool("dump")
+			diff, _ = cmd.Flags().GetBool("diff")
+
+			// The refresh messages aren't published without the queue
+			if err := srv.InitializeIndex(); err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+
+			if diff {
+				result, err := srv.Diff(context.Background(), &message, options)
+				if err != nil && result == nil {
+					return err
+				}
+
+				if err := encoder.Encode(result); err != nil {
+					return err
+				}
+
+				loggerx.Global().Info("diff completion", zap.String("address", message.Address), zap.String("network", message.Network), zap.Int("added", result.Added), zap.Int("removed", result.Removed), zap.Int("changed", result.Changed))
+
+				return err
+			}
+
+			transactions, err := srv.Index(context.Background(), &message, options)
+			if err != nil && transactions == nil {
+				return err
+			}
+
+			// Dump the transactions even if some of the datasources failed
+			if dump {
+				if err := encoder.Encode(transactions); err != nil {
+					return err
+				}
+			}
+
+			loggerx.Global().Info("index completion", zap.String("address", message.Address), zap.String("network", message.Network), zap.Int("transactions", len(transactions)), zap.Bool("dry_run", options.DryRun))
+
+			return err
+		},
+	}
+
+	indexCommand.Flags().String("address", "", "address to index")
+	indexCommand.Flags().String("network", protocol.NetworkEthereum, "network to index")
+	indexCommand.Flags().StringSlice("datasource", nil, "datasources to run, all of them run if empty")
+	indexCommand.Flags().StringSlice("worker", nil, "workers to run, all of them run if empty")
+	indexCommand.Flags().Bool("dry-run", false, "skip writing the transactions to the database")
+	indexCommand.Flags().Bool("dump", false, "print the transactions with their transfers as JSON")
+	indexCommand.Flags().Bool("diff", false, "print the differences from the stored transactions as JSON instead of writing the database")
+
+	_ = indexCommand.MarkFlagRequired("address")
+
+	return indexCommand
+}
+
+func newBackfillCommand(srv *server.Server) *cobra.Command {
+	backfillCommand := &cobra.Command{
+		Use:   "backfill",
+		Short: "Manage the checkpoints of indexing the history of addresses",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return srv.InitializeDatabase()
+		},
+	}
+
+	addCommand := &cobra.Command{
+		Use:   "add",
+		Short: "Create a checkpoint, it's backfilled by the indexers with backfill enabled",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			address, _ := cmd.Flags().GetString("address")
+			restart, _ := cmd.Flags().GetBool("restart")
+
+			networks, _ := cmd.Flags().GetStringSlice("network")
+
+			for _, network := range networks {
+				if err := backfill.Add(context.Background(), address, network, restart); err != nil {
+					return fmt.Errorf("add %s checkpoint: %w", network, err)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	addCommand.Flags().String("address", "", "address to backfill")
+	addCommand.Flags().StringSlice("network", []string{protocol.NetworkEthereum}, "networks to backfill")
+	addCommand.Flags().Bool("restart", false, "restart the finished checkpoints from the latest block")
+
+	_ = addCommand.MarkFlagRequired("address")
+
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "Print the checkpoints as JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			address, _ := cmd.Flags().GetString("address")
+			pending, _ := cmd.Flags().GetBool("pending")
+
+			checkpoints, err := backfill.List(context.Background(), address, pending)
+			if err != nil {
+				return err
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(checkpoints)
+		},
+	}
+
+	listCommand.Flags().String("address", "", "only print the checkpoints of this address")
+	listCommand.Flags().Bool("pending", false, "only print the unfinished checkpoints")
+
+	backfillCommand.AddCommand(addCommand, listCommand)
+
+	return backfillCommand
+}
+
+func newPricesCommand(srv *server.Server) *cobra.Command {
+	pricesCommand := &cobra.Command{
+		Use:   "prices",
+		Short: "Manage the USD values of the fees and tokens",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return srv.InitializeDatabase()
+		},
+	}
+
+	backfillCommand := &cobra.Command{
+		Use:   "backfill",
+		Short: "Value the stored fees and transfers which were indexed without USD values",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var options server.PriceBackfillOptions
+
+			options.Networks, _ = cmd.Flags().GetStringSlice("network")
+
+			for name, timestamp := range map[string]*time.Time{"since": &options.Since, "until": &options.Until} {
+				value, _ := cmd.Flags().GetString(name)
+				if value == "" {
+					continue
+				}
+
+				var err error
+
+				if *timestamp, err = time.Parse(time.RFC3339, value); err != nil {
+					return fmt.Errorf("parse %s: %w", name, err)
+				}
+			}
+
+			result, err := srv.BackfillPrices(context.Background(), options)
+			if result != nil {
+				loggerx.Global().Info("backfill prices completion", zap.Int("transactions", result.Transactions), zap.Int("transfers", result.Transfers))
+			}
+
+			return err
+		},
+	}
+
+	backfillCommand.Flags().StringSlice("network", nil, "networks to value, all of them are valued if empty")
+	backfillCommand.Flags().String("since", "", "only value the rows at or after this RFC 3339 time")
+	backfillCommand.Flags().String("until", "", "only value the rows before this RFC 3339 time")
+
+	pricesCommand.AddCommand(backfillCommand)
+
+	return pricesCommand
+}
+
 func main() {
 	config.Initialize()
 
@@ -46,6 +283,7 @@ func main() {
 		server.HandleFunc("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
 		server.HandleFunc("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
 		server.HandleFunc("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+		server.Handle("/debug/vars", expvar.Handler())
 		logrus.Fatal(http.ListenAndServe("localhost:6060", server))
 	}()
 
@@ -53,6 +291,8 @@ func main() {
 		return srv.Run()
 	}
 
+	rootCommand.AddCommand(newDeadLetterCommand(srv), newIndexCommand(srv), newBackfillCommand(srv), newPricesCommand(srv))
+
 	if err := rootCommand.Execute(); err != nil {
 		loggerx.Global().Fatal("indexer execution failed", zap.Error(err))
 	}
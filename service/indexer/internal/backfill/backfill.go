@@ -0,0 +1,284 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/ethclientx"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/common/utils/shedlock"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultWindow   = 100_000
+	DefaultInterval = 10 * time.Second
+	DefaultJobs     = 4
+
+	lockTimeout = time.Minute
+)
+
+// Handler indexes the transactions in the block range of message, and returns the number of them
+type Handler func(ctx context.Context, message *protocol.Message) (transactions int, err error)
+
+// Backfill walks the history of addresses backwards in block windows. The progress is saved in checkpoints
+// after every window, so it resumes from the last window after crashes. Each address is backfilled
+// at most one window per interval, and at most jobs addresses are backfilled at the same time.
+type Backfill struct {
+	window   int64
+	interval time.Duration
+	jobs     int
+	employer *shedlock.Employer
+	handler  Handler
+}
+
+// Run backfills the pending checkpoints until the context is done
+func (b *Backfill) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var checkpoints []model.BackfillCheckpoint
+
+		// The least recently updated checkpoints go first, so that addresses take turns
+		if err := database.Global().WithContext(ctx).
+			Where("done = ?", false).
+			Order("updated_at").
+			Limit(b.jobs).
+			Find(&checkpoints).Error; err != nil {
+			if ctx.Err() == nil {
+				loggerx.Global().Error("failed to get backfill checkpoints", zap.Error(err))
+			}
+
+			continue
+		}
+
+		var wg sync.WaitGroup
+
+		for _, checkpoint := range checkpoints {
+			wg.Add(1)
+
+			go func(checkpoint model.BackfillCheckpoint) {
+				defer wg.Done()
+
+				b.step(ctx, checkpoint)
+			}(checkpoint)
+		}
+
+		wg.Wait()
+	}
+}
+
+// step indexes the next window of a checkpoint, the other replicas skip it while it's locked
+func (b *Backfill) step(ctx context.Context, checkpoint model.BackfillCheckpoint) {
+	lockKey := fmt.Sprintf("backfill:%s:%s", checkpoint.Address, checkpoint.Network)
+
+	if !b.employer.DoLock(lockKey, lockTimeout) {
+		return
+	}
+
+	defer b.employer.UnLock(lockKey)
+
+	renewalCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		for {
+			time.Sleep(lockTimeout / 2)
+
+			if err := b.employer.Renewal(renewalCtx, lockKey, lockTimeout); err != nil {
+				return
+			}
+		}
+	}()
+
+	if checkpoint.BlockNumberHead == 0 {
+		head, err := latestBlockNumber(ctx, checkpoint.Network)
+		if err != nil {
+			b.fail(ctx, checkpoint, fmt.Errorf("get latest block number: %w", err))
+
+			return
+		}
+
+		checkpoint.BlockNumberHead = head
+		checkpoint.BlockNumber = head + 1
+	}
+
+	from, to := NextWindow(checkpoint.BlockNumber, b.window)
+
+	message := protocol.Message{
+		Address:       checkpoint.Address,
+		Network:       checkpoint.Network,
+		BlockNumber:   from,
+		BlockNumberTo: to,
+	}
+
+	startTime := time.Now()
+
+	transactions, err := b.handler(ctx, &message)
+	if err != nil {
+		// Interrupted by shutdown, the window is indexed again next time
+		if ctx.Err() == nil {
+			b.fail(ctx, checkpoint, err)
+		}
+
+		return
+	}
+
+	checkpoint.BlockNumber = from
+	checkpoint.Transactions += int64(transactions)
+	checkpoint.Done = from == 0
+	checkpoint.Error = ""
+
+	if err := database.Global().WithContext(ctx).Save(&checkpoint).Error; err != nil {
+		loggerx.Global().Error("failed to save backfill checkpoint", zap.Error(err), zap.String("address", checkpoint.Address), zap.String("network", checkpoint.Network))
+
+		return
+	}
+
+	loggerx.Global().Info(
+		"backfill window completion",
+		zap.String("address", checkpoint.Address),
+		zap.String("network", checkpoint.Network),
+		zap.Int64("from", from),
+		zap.Int64("to", to),
+		zap.Int("transactions", transactions),
+		zap.Bool("done", checkpoint.Done),
+		zap.Duration("duration", time.Since(startTime)),
+	)
+}
+
+// fail records the error and keeps the progress, the window is retried after the other checkpoints
+func (b *Backfill) fail(ctx context.Context, checkpoint model.BackfillCheckpoint, err error) {
+	loggerx.Global().Error("backfill window failed", zap.Error(err), zap.String("address", checkpoint.Address), zap.String("network", checkpoint.Network))
+
+	if err := database.Global().WithContext(ctx).
+		Model(&model.BackfillCheckpoint{}).
+		Where("address = ? AND network = ?", checkpoint.Address, checkpoint.Network).
+		Updates(map[string]any{
+			"error":      err.Error(),
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+		loggerx.Global().Error("failed to save backfill checkpoint", zap.Error(err), zap.String("address", checkpoint.Address), zap.String("network", checkpoint.Network))
+	}
+}
+
+// NextWindow returns the inclusive block range ending before the lowest indexed block
+func NextWindow(blockNumber, window int64) (from, to int64) {
+	to = blockNumber - 1
+
+	if from = blockNumber - window; from < 0 {
+		from = 0
+	}
+
+	return from, to
+}
+
+// Add creates the checkpoint of an address and network, a finished checkpoint is restarted from the latest block
+// if restart is true, and an unfinished one is kept as is
+func Add(ctx context.Context, address, network string, restart bool) error {
+	if !lo.Contains(protocol.EthclientNetworks, network) {
+		return fmt.Errorf("unsupported network: %s", network)
+	}
+
+	checkpoint := model.BackfillCheckpoint{
+		Address: strings.ToLower(address),
+		Network: network,
+	}
+
+	onConflict := clause.OnConflict{
+		DoNothing: true,
+	}
+
+	if restart {
+		onConflict = clause.OnConflict{
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Eq{Column: clause.Column{Table: checkpoint.TableName(), Name: "done"}, Value: true},
+			}},
+			DoUpdates: clause.AssignmentColumns([]string{"block_number_head", "block_number", "transactions", "done", "error", "updated_at"}),
+		}
+	}
+
+	return database.Global().WithContext(ctx).Clauses(onConflict).Create(&checkpoint).Error
+}
+
+// List returns the checkpoints of an address, or all of them if address is empty
+func List(ctx context.Context, address string, pending bool) ([]model.BackfillCheckpoint, error) {
+	query := database.Global().WithContext(ctx).Model((*model.BackfillCheckpoint)(nil))
+
+	if address != "" {
+		query = query.Where("address = ?", strings.ToLower(address))
+	}
+
+	if pending {
+		query = query.Where("done = ?", false)
+	}
+
+	checkpoints := make([]model.BackfillCheckpoint, 0)
+
+	if err := query.Order("updated_at DESC").Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+func latestBlockNumber(ctx context.Context, network string) (int64, error) {
+	ethereumClient, err := ethclientx.Global(network)
+	if err != nil {
+		return 0, err
+	}
+
+	blockNumber, err := ethereumClient.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if blockNumber == 0 {
+		return 0, errors.New("invalid latest block number")
+	}
+
+	return int64(blockNumber), nil
+}
+
+func New(config *configx.Backfill, employer *shedlock.Employer, handler Handler) *Backfill {
+	backfill := Backfill{
+		window:   DefaultWindow,
+		interval: DefaultInterval,
+		jobs:     DefaultJobs,
+		employer: employer,
+		handler:  handler,
+	}
+
+	if config != nil {
+		if config.Window > 0 {
+			backfill.window = config.Window
+		}
+
+		if config.Interval > 0 {
+			backfill.interval = config.Interval
+		}
+
+		if config.Jobs > 0 {
+			backfill.jobs = config.Jobs
+		}
+	}
+
+	return &backfill
+}
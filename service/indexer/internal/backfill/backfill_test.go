@@ -0,0 +1,47 @@
+package backfill
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextWindow(t *testing.T) {
+	testcases := []struct {
+		name        string
+		blockNumber int64
+		window      int64
+		from        int64
+		to          int64
+	}{
+		{name: "first window", blockNumber: 1_000_001, window: 100_000, from: 900_001, to: 1_000_000},
+		{name: "last window", blockNumber: 50_000, window: 100_000, from: 0, to: 49_999},
+		{name: "exact window", blockNumber: 100_000, window: 100_000, from: 0, to: 99_999},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			from, to := NextWindow(testcase.blockNumber, testcase.window)
+
+			assert.Equal(t, testcase.from, from)
+			assert.Equal(t, testcase.to, to)
+		})
+	}
+
+	// Windows are contiguous and end at the genesis block
+	var (
+		blockNumber int64 = 250_001
+		windows     int
+	)
+
+	for blockNumber > 0 {
+		from, to := NextWindow(blockNumber, 100_000)
+
+		assert.Equal(t, blockNumber-1, to)
+
+		blockNumber = from
+		windows++
+	}
+
+	assert.Equal(t, 3, windows)
+}
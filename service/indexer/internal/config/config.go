@@ -11,6 +11,11 @@ import (
 type Config struct {
 	Mode          configx.Mode           `mapstructure:"mode"`
 	RabbitMQ      *configx.RabbitMQ      `mapstructure:"rabbitmq"`
+	MQ            *configx.MQ            `mapstructure:"mq"`
+	Scheduler     *configx.Scheduler     `mapstructure:"scheduler"`
+	Backfill      *configx.Backfill      `mapstructure:"backfill"`
+	Registry      *configx.Registry      `mapstructure:"registry"`
+	Reorg         *configx.Reorg         `mapstructure:"reorg"`
 	Postgres      *configx.Postgres      `mapstructure:"postgres"`
 	EthereumEtl   *configx.PostgresEtl   `mapstructure:"ethereumetl"`
 	Kurora        *configx.Kurora        `mapstructure:"kurora"`
@@ -46,3 +51,18 @@ func Initialize() {
 		logrus.Fatalln(err)
 	}
 }
+
+// Reload reads the config file again, the environment variables are still applied
+func Reload() (*Config, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	var config Config
+
+	if err := viper.Unmarshal(&config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
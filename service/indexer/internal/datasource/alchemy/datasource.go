@@ -63,6 +63,10 @@ func (d *Datasource) Handle(ctx context.Context, message *protocol.Message) ([]m
 			continue
 		}
 
+		if message.BlockNumberTo > 0 && internalTransaction.BlockNumber > message.BlockNumberTo {
+			continue
+		}
+
 		if internalTransaction.AddressFrom != "" && !strings.EqualFold(internalTransaction.AddressFrom, message.Address) && !allowlist.AllowList.Contains(internalTransaction.AddressFrom) {
 			continue
 		}
@@ -100,6 +104,10 @@ func (d *Datasource) getAllAssetTransferHashes(ctx context.Context, message *pro
 		Order:       "desc",
 	}
 
+	if message.BlockNumberTo > 0 {
+		parameter.ToBlock = hexutil.EncodeUint64(uint64(message.BlockNumberTo))
+	}
+
 	// Get the transactions sent from this address
 	internalTransactions, err := d.getAssetTransactionHashes(ctx, message, parameter)
 	if err != nil {
@@ -101,6 +101,7 @@ func (d *Datasource) handleTransactions(ctx context.Context, message *protocol.M
 
 	internalTransactions, _, err := blockscoutClient.GetTransactionList(ctx, common.HexToAddress(message.Address), &blockscout.GetTransactionListOption{
 		StartBlock: message.BlockNumber,
+		EndBlock:   message.BlockNumberTo,
 	})
 	if err != nil {
 		return nil, err
@@ -137,6 +138,7 @@ func (d *Datasource) handleTokenTransfers(ctx context.Context, message *protocol
 
 	internalTokenTransfers, _, err := blockscoutClient.GetTokenTransactionList(ctx, common.HexToAddress(message.Address), &blockscout.GetTokenTransactionListOption{
 		StartBlock: message.BlockNumber,
+		EndBlock:   message.BlockNumberTo,
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,211 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+type Result struct {
+	Added        int           `json:"added"`
+	Removed      int           `json:"removed"`
+	Changed      int           `json:"changed"`
+	Unchanged    int           `json:"unchanged"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+type Transaction struct {
+	Hash      string     `json:"hash"`
+	Network   string     `json:"network"`
+	Timestamp time.Time  `json:"timestamp"`
+	Change    string     `json:"change"`
+	Fields    []Field    `json:"fields,omitempty"`
+	Transfers []Transfer `json:"transfers,omitempty"`
+}
+
+type Transfer struct {
+	Index  int64   `json:"index"`
+	Change string  `json:"change"`
+	Fields []Field `json:"fields,omitempty"`
+	// Stored and Built are set for the added and removed transfers
+	Stored *model.Transfer `json:"stored,omitempty"`
+	Built  *model.Transfer `json:"built,omitempty"`
+}
+
+type Field struct {
+	Name   string `json:"name"`
+	Stored any    `json:"stored"`
+	Built  any    `json:"built"`
+}
+
+// Compare finds the differences from the stored transactions to the built ones,
+// transactions are matched by hash and network, and transfers by index.
+func Compare(stored, built []model.Transaction) Result {
+	var result Result
+
+	storedMap := make(map[string]model.Transaction, len(stored))
+
+	for _, transaction := range stored {
+		storedMap[transactionKey(transaction)] = transaction
+	}
+
+	builtKeys := make(map[string]struct{}, len(built))
+
+	for _, builtTransaction := range built {
+		key := transactionKey(builtTransaction)
+		builtKeys[key] = struct{}{}
+
+		storedTransaction, exists := storedMap[key]
+		if !exists {
+			result.Added++
+			result.Transactions = append(result.Transactions, newTransaction(builtTransaction, ChangeAdded))
+
+			continue
+		}
+
+		transaction := newTransaction(builtTransaction, ChangeChanged)
+		transaction.Fields = compareTransaction(storedTransaction, builtTransaction)
+		transaction.Transfers = compareTransfers(storedTransaction.Transfers, builtTransaction.Transfers)
+
+		if len(transaction.Fields) == 0 && len(transaction.Transfers) == 0 {
+			result.Unchanged++
+
+			continue
+		}
+
+		result.Changed++
+		result.Transactions = append(result.Transactions, transaction)
+	}
+
+	for _, storedTransaction := range stored {
+		if _, exists := builtKeys[transactionKey(storedTransaction)]; exists {
+			continue
+		}
+
+		result.Removed++
+		result.Transactions = append(result.Transactions, newTransaction(storedTransaction, ChangeRemoved))
+	}
+
+	// Latest first, the order of maps must not leak into the result
+	sort.SliceStable(result.Transactions, func(i, j int) bool {
+		left, right := result.Transactions[i], result.Transactions[j]
+
+		if !left.Timestamp.Equal(right.Timestamp) {
+			return left.Timestamp.After(right.Timestamp)
+		}
+
+		if left.Network != right.Network {
+			return left.Network < right.Network
+		}
+
+		return left.Hash < right.Hash
+	})
+
+	return result
+}
+
+func compareTransaction(stored, built model.Transaction) (fields []Field) {
+	fields = appendField(fields, "tag", stored.Tag, built.Tag)
+	fields = appendField(fields, "type", stored.Type, built.Type)
+	fields = appendField(fields, "platform", stored.Platform, built.Platform)
+
+	return fields
+}
+
+func compareTransfers(stored, built []model.Transfer) (transfers []Transfer) {
+	storedMap := make(map[int64]model.Transfer, len(stored))
+
+	for _, transfer := range stored {
+		storedMap[transfer.Index] = transfer
+	}
+
+	builtIndexes := make(map[int64]struct{}, len(built))
+
+	for index := range built {
+		builtTransfer := built[index]
+		builtIndexes[builtTransfer.Index] = struct{}{}
+
+		storedTransfer, exists := storedMap[builtTransfer.Index]
+		if !exists {
+			transfers = append(transfers, Transfer{Index: builtTransfer.Index, Change: ChangeAdded, Built: &builtTransfer})
+
+			continue
+		}
+
+		var fields []Field
+
+		fields = appendField(fields, "tag", storedTransfer.Tag, builtTransfer.Tag)
+		fields = appendField(fields, "type", storedTransfer.Type, builtTransfer.Type)
+		fields = appendField(fields, "platform", storedTransfer.Platform, builtTransfer.Platform)
+		fields = appendField(fields, "address_from", storedTransfer.AddressFrom, builtTransfer.AddressFrom)
+		fields = appendField(fields, "address_to", storedTransfer.AddressTo, builtTransfer.AddressTo)
+
+		if !equalJSON(storedTransfer.Metadata, builtTransfer.Metadata) {
+			fields = append(fields, Field{Name: "metadata", Stored: storedTransfer.Metadata, Built: builtTransfer.Metadata})
+		}
+
+		if len(fields) > 0 {
+			transfers = append(transfers, Transfer{Index: builtTransfer.Index, Change: ChangeChanged, Fields: fields})
+		}
+	}
+
+	for index := range stored {
+		storedTransfer := stored[index]
+
+		if _, exists := builtIndexes[storedTransfer.Index]; !exists {
+			transfers = append(transfers, Transfer{Index: storedTransfer.Index, Change: ChangeRemoved, Stored: &storedTransfer})
+		}
+	}
+
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].Index < transfers[j].Index
+	})
+
+	return transfers
+}
+
+func appendField(fields []Field, name, stored, built string) []Field {
+	if stored == built {
+		return fields
+	}
+
+	return append(fields, Field{Name: name, Stored: stored, Built: built})
+}
+
+// equalJSON ignores the formatting and the order of keys, jsonb doesn't keep them
+func equalJSON(a, b json.RawMessage) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+
+	var valueA, valueB any
+
+	if json.Unmarshal(a, &valueA) != nil || json.Unmarshal(b, &valueB) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(valueA, valueB)
+}
+
+func newTransaction(transaction model.Transaction, change string) Transaction {
+	return Transaction{
+		Hash:      transaction.Hash,
+		Network:   transaction.Network,
+		Timestamp: transaction.Timestamp,
+		Change:    change,
+	}
+}
+
+func transactionKey(transaction model.Transaction) string {
+	return transaction.Network + ":" + transaction.Hash
+}
@@ -0,0 +1,146 @@
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	timestamp := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	stored := []model.Transaction{
+		{
+			Hash:      "0x1",
+			Network:   protocol.NetworkEthereum,
+			Timestamp: timestamp,
+			Tag:       filter.TagTransaction,
+			Type:      filter.TransactionTransfer,
+			Transfers: []model.Transfer{
+				{Index: 0, Tag: filter.TagTransaction, Type: filter.TransactionTransfer, Metadata: json.RawMessage(`{"a": 1, "b": 2}`)},
+				{Index: 1, Tag: filter.TagTransaction, Type: filter.TransactionTransfer, Metadata: json.RawMessage(`{}`)},
+			},
+		},
+		{
+			Hash:      "0x2",
+			Network:   protocol.NetworkEthereum,
+			Timestamp: timestamp.Add(time.Hour),
+			Tag:       filter.TagTransaction,
+			Type:      filter.TransactionTransfer,
+			Transfers: []model.Transfer{
+				{Index: 0, Tag: filter.TagTransaction, Type: filter.TransactionTransfer, Metadata: json.RawMessage(`{}`)},
+			},
+		},
+		{
+			Hash:      "0x3",
+			Network:   protocol.NetworkEthereum,
+			Timestamp: timestamp,
+		},
+	}
+
+	built := []model.Transaction{
+		// Keys are reordered, transfer 1 is removed, and transfer 2 is added
+		{
+			Hash:      "0x1",
+			Network:   protocol.NetworkEthereum,
+			Timestamp: timestamp,
+			Tag:       filter.TagTransaction,
+			Type:      filter.TransactionTransfer,
+			Transfers: []model.Transfer{
+				{Index: 0, Tag: filter.TagTransaction, Type: filter.TransactionTransfer, Metadata: json.RawMessage(`{"b":2,"a":1}`)},
+				{Index: 2, Tag: filter.TagTransaction, Type: filter.TransactionTransfer, Metadata: json.RawMessage(`{}`)},
+			},
+		},
+		// Recognized as a swap
+		{
+			Hash:      "0x2",
+			Network:   protocol.NetworkEthereum,
+			Timestamp: timestamp.Add(time.Hour),
+			Tag:       filter.TagExchange,
+			Type:      filter.ExchangeSwap,
+			Transfers: []model.Transfer{
+				{Index: 0, Tag: filter.TagExchange, Type: filter.ExchangeSwap, Metadata: json.RawMessage(`{"protocol":"Uniswap"}`)},
+			},
+		},
+		// Same hash on another network
+		{
+			Hash:      "0x1",
+			Network:   protocol.NetworkPolygon,
+			Timestamp: timestamp,
+		},
+	}
+
+	result := Compare(stored, built)
+
+	assert.Equal(t, 1, result.Added)
+	assert.Equal(t, 1, result.Removed)
+	assert.Equal(t, 2, result.Changed)
+	assert.Equal(t, 0, result.Unchanged)
+
+	// Latest first, then ordered by network and hash
+	assert.Equal(t, []string{"0x2", "0x1", "0x3", "0x1"}, hashes(result))
+	assert.Equal(t, []string{ChangeChanged, ChangeChanged, ChangeRemoved, ChangeAdded}, changes(result))
+
+	swap := result.Transactions[0]
+	assert.Equal(t, []Field{
+		{Name: "tag", Stored: filter.TagTransaction, Built: filter.TagExchange},
+		{Name: "type", Stored: filter.TransactionTransfer, Built: filter.ExchangeSwap},
+	}, swap.Fields)
+	assert.Len(t, swap.Transfers, 1)
+	assert.Equal(t, []string{"tag", "type", "metadata"}, fieldNames(swap.Transfers[0].Fields))
+
+	transfers := result.Transactions[1].Transfers
+	assert.Len(t, transfers, 2)
+	assert.Equal(t, int64(1), transfers[0].Index)
+	assert.Equal(t, ChangeRemoved, transfers[0].Change)
+	assert.NotNil(t, transfers[0].Stored)
+	assert.Equal(t, int64(2), transfers[1].Index)
+	assert.Equal(t, ChangeAdded, transfers[1].Change)
+	assert.NotNil(t, transfers[1].Built)
+}
+
+func TestCompareUnchanged(t *testing.T) {
+	transactions := []model.Transaction{
+		{
+			Hash:    "0x1",
+			Network: protocol.NetworkEthereum,
+			Transfers: []model.Transfer{
+				{Index: 0, Metadata: json.RawMessage(`{"a":[1,2]}`)},
+			},
+		},
+	}
+
+	result := Compare(transactions, transactions)
+
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Empty(t, result.Transactions)
+}
+
+func hashes(result Result) (hashes []string) {
+	for _, transaction := range result.Transactions {
+		hashes = append(hashes, transaction.Hash)
+	}
+
+	return hashes
+}
+
+func changes(result Result) (changes []string) {
+	for _, transaction := range result.Transactions {
+		changes = append(changes, transaction.Change)
+	}
+
+	return changes
+}
+
+func fieldNames(fields []Field) (names []string) {
+	for _, field := range fields {
+		names = append(names, field.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,205 @@
+package merge
+
+import (
+	"sort"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/samber/lo"
+)
+
+// Precedence returns the sources of network from the highest priority
+type Precedence func(network string) []string
+
+type candidate struct {
+	transaction model.Transaction
+	rank        int
+	result      int
+	position    int
+}
+
+// Merge groups the transactions returned by datasources by hash and network, and merges each group into one.
+// results are in the order of datasources, the sources not in precedence rank after the listed ones in this order.
+// A merged transaction keeps the fields of the highest ranked source and fills the empty ones from the others,
+// transfers are merged by index in the same way, and Sources records all the contributing sources by rank.
+// The output is sorted by block number, timestamp, network and hash, so that it doesn't depend on the datasources timing.
+func Merge(results [][]model.Transaction, precedence Precedence) []model.Transaction {
+	var (
+		keys   []string
+		groups = make(map[string][]candidate)
+		ranks  = make(map[string]map[string]int)
+	)
+
+	for resultIndex, transactions := range results {
+		for position, transaction := range transactions {
+			key := transaction.Network + ":" + transaction.Hash
+
+			if _, exists := groups[key]; !exists {
+				keys = append(keys, key)
+			}
+
+			groups[key] = append(groups[key], candidate{
+				transaction: transaction,
+				rank:        rank(ranks, precedence, transaction),
+				result:      resultIndex,
+				position:    position,
+			})
+		}
+	}
+
+	merged := make([]model.Transaction, 0, len(keys))
+
+	for _, key := range keys {
+		candidates := groups[key]
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].rank != candidates[j].rank {
+				return candidates[i].rank < candidates[j].rank
+			}
+
+			if candidates[i].result != candidates[j].result {
+				return candidates[i].result < candidates[j].result
+			}
+
+			return candidates[i].position < candidates[j].position
+		})
+
+		merged = append(merged, mergeCandidates(candidates))
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		left, right := merged[i], merged[j]
+
+		if left.BlockNumber != right.BlockNumber {
+			return left.BlockNumber > right.BlockNumber
+		}
+
+		if !left.Timestamp.Equal(right.Timestamp) {
+			return left.Timestamp.After(right.Timestamp)
+		}
+
+		if left.Network != right.Network {
+			return left.Network < right.Network
+		}
+
+		return left.Hash < right.Hash
+	})
+
+	return merged
+}
+
+// rank is the index of the source in the precedence of the network, unlisted sources share the lowest rank
+func rank(ranks map[string]map[string]int, precedence Precedence, transaction model.Transaction) int {
+	networkRanks, exists := ranks[transaction.Network]
+	if !exists {
+		networkRanks = make(map[string]int)
+
+		if precedence != nil {
+			for index, source := range precedence(transaction.Network) {
+				if _, exists := networkRanks[source]; !exists {
+					networkRanks[source] = index
+				}
+			}
+		}
+
+		ranks[transaction.Network] = networkRanks
+	}
+
+	if index, exists := networkRanks[transaction.Source]; exists {
+		return index
+	}
+
+	return len(networkRanks)
+}
+
+func mergeCandidates(candidates []candidate) model.Transaction {
+	transaction := candidates[0].transaction
+
+	var (
+		sources   []string
+		addresses []string
+		transfers []model.Transfer
+		indexes   = make(map[int64]struct{})
+	)
+
+	for _, candidate := range candidates {
+		other := candidate.transaction
+
+		if other.Source != "" && !lo.Contains(sources, other.Source) {
+			sources = append(sources, other.Source)
+		}
+
+		for _, address := range other.Addresses {
+			if !lo.Contains(addresses, address) {
+				addresses = append(addresses, address)
+			}
+		}
+
+		for _, transfer := range other.Transfers {
+			if _, exists := indexes[transfer.Index]; exists {
+				continue
+			}
+
+			indexes[transfer.Index] = struct{}{}
+			transfers = append(transfers, transfer)
+		}
+
+		fillTransaction(&transaction, other)
+	}
+
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].Index < transfers[j].Index
+	})
+
+	transaction.Sources = sources
+	transaction.Addresses = addresses
+	transaction.Transfers = transfers
+
+	return transaction
+}
+
+// fillTransaction sets the empty fields of transaction from other
+func fillTransaction(transaction *model.Transaction, other model.Transaction) {
+	if transaction.BlockNumber == 0 {
+		transaction.BlockNumber = other.BlockNumber
+	}
+
+	if transaction.BlockHash == "" && transaction.BlockNumber == other.BlockNumber {
+		transaction.BlockHash = other.BlockHash
+	}
+
+	if transaction.Timestamp.IsZero() {
+		transaction.Timestamp = other.Timestamp
+	}
+
+	if transaction.Owner == "" {
+		transaction.Owner = other.Owner
+	}
+
+	if transaction.Fee == nil {
+		transaction.Fee = other.Fee
+	}
+
+	if transaction.AddressFrom == "" {
+		transaction.AddressFrom = other.AddressFrom
+	}
+
+	if transaction.AddressTo == "" {
+		transaction.AddressTo = other.AddressTo
+	}
+
+	if transaction.Platform == "" {
+		transaction.Platform = other.Platform
+	}
+
+	if transaction.Tag == "" && transaction.Type == "" {
+		transaction.Tag, transaction.Type = other.Tag, other.Type
+	}
+
+	if transaction.Success == nil {
+		transaction.Success = other.Success
+	}
+
+	if len(transaction.SourceData) == 0 {
+		transaction.SourceData = other.SourceData
+	}
+}
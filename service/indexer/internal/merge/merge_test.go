@@ -0,0 +1,131 @@
+package merge
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	timestamp := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	kurora := []model.Transaction{
+		{
+			Hash:        "0x1",
+			Network:     protocol.NetworkEthereum,
+			Source:      protocol.SourceKurora,
+			BlockNumber: 100,
+			Timestamp:   timestamp,
+			Addresses:   pq.StringArray{"0xa"},
+			Transfers: []model.Transfer{
+				{Index: 1, Source: protocol.SourceKurora, Metadata: json.RawMessage(`{"from":"kurora"}`)},
+			},
+		},
+	}
+
+	alchemy := []model.Transaction{
+		{
+			Hash:        "0x1",
+			Network:     protocol.NetworkEthereum,
+			Source:      protocol.SourceAlchemy,
+			BlockNumber: 100,
+			AddressFrom: "0xa",
+			SourceData:  json.RawMessage(`{"receipt":{}}`),
+			Addresses:   pq.StringArray{"0xa", "0xb"},
+			Transfers: []model.Transfer{
+				{Index: protocol.IndexVirtual, Source: protocol.SourceAlchemy},
+				{Index: 1, Source: protocol.SourceAlchemy, Metadata: json.RawMessage(`{"from":"alchemy"}`)},
+			},
+		},
+		// Same hash on another network isn't merged
+		{
+			Hash:        "0x1",
+			Network:     protocol.NetworkPolygon,
+			Source:      protocol.SourceAlchemy,
+			BlockNumber: 200,
+		},
+	}
+
+	moralis := []model.Transaction{
+		{
+			Hash:        "0x2",
+			Network:     protocol.NetworkEthereum,
+			Source:      protocol.SourceMoralis,
+			BlockNumber: 100,
+			Timestamp:   timestamp.Add(time.Second),
+		},
+	}
+
+	precedence := func(network string) []string {
+		return map[string][]string{
+			protocol.NetworkEthereum: {protocol.SourceAlchemy, protocol.SourceKurora},
+		}[network]
+	}
+
+	merged := Merge([][]model.Transaction{kurora, alchemy, moralis}, precedence)
+
+	assert.Equal(t, []string{"polygon:0x1", "ethereum:0x2", "ethereum:0x1"}, keys(merged))
+
+	transaction := merged[2]
+	assert.Equal(t, protocol.SourceAlchemy, transaction.Source)
+	assert.Equal(t, pq.StringArray{protocol.SourceAlchemy, protocol.SourceKurora}, transaction.Sources)
+	assert.Equal(t, pq.StringArray{"0xa", "0xb"}, transaction.Addresses)
+	assert.Equal(t, timestamp, transaction.Timestamp, "filled from kurora")
+	assert.Equal(t, "0xa", transaction.AddressFrom)
+	assert.Len(t, transaction.Transfers, 2)
+	assert.Equal(t, protocol.IndexVirtual, transaction.Transfers[0].Index)
+	assert.JSONEq(t, `{"from":"alchemy"}`, string(transaction.Transfers[1].Metadata))
+
+	// The order of results only matters for the sources without precedence
+	reversed := Merge([][]model.Transaction{moralis, alchemy, kurora}, precedence)
+	assert.Equal(t, merged, reversed)
+
+	withoutPrecedence := Merge([][]model.Transaction{kurora, alchemy, moralis}, nil)
+	assert.Equal(t, protocol.SourceKurora, withoutPrecedence[2].Source)
+	assert.JSONEq(t, `{"from":"kurora"}`, string(withoutPrecedence[2].Transfers[1].Metadata))
+}
+
+func TestMergeDeterministic(t *testing.T) {
+	var results [][]model.Transaction
+
+	for _, source := range []string{protocol.SourceKurora, protocol.SourceAlchemy, protocol.SourceMoralis, protocol.SourceBlockscout} {
+		var transactions []model.Transaction
+
+		for _, hash := range []string{"0x3", "0x1", "0x2"} {
+			transactions = append(transactions, model.Transaction{
+				Hash:        hash,
+				Network:     protocol.NetworkEthereum,
+				Source:      source,
+				BlockNumber: 100,
+				Transfers: []model.Transfer{
+					{Index: 0, Source: source},
+				},
+			})
+		}
+
+		results = append(results, transactions)
+	}
+
+	expected := Merge(results, nil)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, expected, Merge(results, nil))
+	}
+
+	assert.Equal(t, []string{"ethereum:0x1", "ethereum:0x2", "ethereum:0x3"}, keys(expected))
+	assert.Equal(t, pq.StringArray{protocol.SourceKurora, protocol.SourceAlchemy, protocol.SourceMoralis, protocol.SourceBlockscout}, expected[0].Sources)
+	assert.Equal(t, protocol.SourceKurora, expected[0].Transfers[0].Source)
+}
+
+func keys(transactions []model.Transaction) (keys []string) {
+	for _, transaction := range transactions {
+		keys = append(keys, transaction.Network+":"+transaction.Hash)
+	}
+
+	return keys
+}
@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/samber/lo"
+)
+
+// Component is implemented by both datasources and workers
+type Component interface {
+	Name() string
+	Networks() []string
+}
+
+// Registry decides which datasources and workers run for a network, and how long they may run.
+// The config can be replaced at any time, the following lookups see the new one.
+type Registry struct {
+	locker sync.RWMutex
+	config configx.Registry
+}
+
+// Datasource returns whether the datasource runs for network, and its timeout which is zero if unlimited
+func (r *Registry) Datasource(component Component, network string) (bool, time.Duration) {
+	r.locker.RLock()
+	defer r.locker.RUnlock()
+
+	return lookup(r.config.Datasources, component, network)
+}
+
+// Worker returns whether the worker runs for network, and its timeout which is zero if unlimited
+func (r *Registry) Worker(component Component, network string) (bool, time.Duration) {
+	r.locker.RLock()
+	defer r.locker.RUnlock()
+
+	return lookup(r.config.Workers, component, network)
+}
+
+// Precedence returns the sources of network from the highest priority, it is empty if not configured
+func (r *Registry) Precedence(network string) []string {
+	r.locker.RLock()
+	defer r.locker.RUnlock()
+
+	return r.config.Precedence[network]
+}
+
+// Reload replaces the config, a nil config enables all the components on their own networks
+func (r *Registry) Reload(config *configx.Registry) {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+
+	if config == nil {
+		r.config = configx.Registry{}
+	} else {
+		r.config = *config
+	}
+}
+
+func lookup(components configx.Components, component Component, network string) (bool, time.Duration) {
+	name := component.Name()
+
+	if len(components.Enabled) > 0 && !containsName(components.Enabled, name) {
+		return false, 0
+	}
+
+	if containsName(components.Disabled, name) {
+		return false, 0
+	}
+
+	if !lo.Contains(component.Networks(), network) {
+		return false, 0
+	}
+
+	timeout := components.Timeout
+
+	for _, override := range components.Overrides {
+		if !strings.EqualFold(override.Name, name) {
+			continue
+		}
+
+		if len(override.Networks) > 0 && !lo.Contains(override.Networks, network) {
+			return false, 0
+		}
+
+		if override.Timeout > 0 {
+			timeout = override.Timeout
+		}
+	}
+
+	return true, timeout
+}
+
+// containsName matches names case-insensitively, since viper lowercases the keys of config
+func containsName(names []string, name string) bool {
+	for _, element := range names {
+		if strings.EqualFold(element, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func New(config *configx.Registry) *Registry {
+	var registry Registry
+
+	registry.Reload(config)
+
+	return &registry
+}
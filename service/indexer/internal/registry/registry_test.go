@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type component struct {
+	name     string
+	networks []string
+}
+
+func (c component) Name() string {
+	return c.name
+}
+
+func (c component) Networks() []string {
+	return c.networks
+}
+
+func TestRegistry(t *testing.T) {
+	var (
+		moralis    = component{name: "moralis", networks: []string{protocol.NetworkEthereum, protocol.NetworkPolygon}}
+		blockscout = component{name: "blockscout", networks: []string{protocol.NetworkXDAI}}
+		gitcoin    = component{name: "gitcoin", networks: []string{protocol.NetworkEthereum}}
+		friendtech = component{name: "Friend.tech", networks: []string{protocol.NetworkBase}}
+	)
+
+	registry := New(nil)
+
+	enabled, timeout := registry.Datasource(moralis, protocol.NetworkPolygon)
+	assert.True(t, enabled)
+	assert.Zero(t, timeout)
+
+	enabled, _ = registry.Datasource(blockscout, protocol.NetworkEthereum)
+	assert.False(t, enabled, "unsupported network")
+
+	registry.Reload(&configx.Registry{
+		Datasources: configx.Components{
+			Timeout: time.Minute,
+			Overrides: []configx.Component{
+				{Name: "moralis", Networks: []string{protocol.NetworkEthereum, protocol.NetworkXDAI}, Timeout: time.Second},
+			},
+		},
+		Workers: configx.Components{
+			Disabled: []string{"friend.tech"},
+		},
+	})
+
+	enabled, timeout = registry.Datasource(moralis, protocol.NetworkEthereum)
+	assert.True(t, enabled)
+	assert.Equal(t, time.Second, timeout)
+
+	enabled, _ = registry.Datasource(moralis, protocol.NetworkPolygon)
+	assert.False(t, enabled, "not in the networks of override")
+
+	enabled, _ = registry.Datasource(moralis, protocol.NetworkXDAI)
+	assert.False(t, enabled, "overrides can't add networks")
+
+	enabled, timeout = registry.Datasource(blockscout, protocol.NetworkXDAI)
+	assert.True(t, enabled)
+	assert.Equal(t, time.Minute, timeout)
+
+	enabled, _ = registry.Worker(friendtech, protocol.NetworkBase)
+	assert.False(t, enabled)
+
+	enabled, _ = registry.Worker(gitcoin, protocol.NetworkEthereum)
+	assert.True(t, enabled)
+
+	registry.Reload(&configx.Registry{
+		Workers: configx.Components{
+			Enabled: []string{"friend.tech"},
+		},
+	})
+
+	enabled, _ = registry.Worker(friendtech, protocol.NetworkBase)
+	assert.True(t, enabled)
+
+	enabled, _ = registry.Worker(gitcoin, protocol.NetworkEthereum)
+	assert.False(t, enabled, "not in the enabled set")
+}
@@ -0,0 +1,403 @@
+package reorg
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/ethclientx"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/common/utils/shedlock"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultDepth    = 64
+	DefaultInterval = time.Minute
+
+	lockTimeout = 5 * time.Minute
+
+	// The headers of a network requested at the same time
+	maxConcurrency = 16
+)
+
+// DefaultDepths are the confirmations of the networks which reorg often or deeply
+var DefaultDepths = map[string]int64{
+	protocol.NetworkEthereum:          12,
+	protocol.NetworkPolygon:           256,
+	protocol.NetworkBinanceSmartChain: 15,
+	protocol.NetworkBase:              64,
+}
+
+// Reindexer indexes the transactions of an address on network again
+type Reindexer func(ctx context.Context, address, network string) error
+
+// Block is a block of the stored transactions
+type Block struct {
+	Number int64  `gorm:"column:block_number"`
+	Hash   string `gorm:"column:block_hash"`
+}
+
+// Reorg checks the stored transactions of the unconfirmed blocks against the canonical chain,
+// the transactions of orphaned blocks are deleted and their owners are indexed again.
+// It also advances the finality of the transactions as the chains grow.
+type Reorg struct {
+	enabled  bool
+	interval time.Duration
+	depths   map[string]int64
+	employer *shedlock.Employer
+	reindex  Reindexer
+
+	locker sync.RWMutex
+	heads  map[string]int64
+}
+
+// Run checks the networks every interval until the context is done
+func (r *Reorg) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var wg sync.WaitGroup
+
+		for _, network := range protocol.EthclientNetworks {
+			wg.Add(1)
+
+			go func(network string) {
+				defer wg.Done()
+
+				if err := r.check(ctx, network); err != nil && ctx.Err() == nil {
+					loggerx.Global().Error("failed to check reorg", zap.Error(err), zap.String("network", network))
+				}
+			}(network)
+		}
+
+		wg.Wait()
+	}
+}
+
+// Depth returns the confirmations of network, the blocks at least this deep under the head are final
+func (r *Reorg) Depth(network string) int64 {
+	if depth, exists := r.depths[network]; exists {
+		return depth
+	}
+
+	return DefaultDepth
+}
+
+// Finality returns the state of a transaction when it is indexed, the transactions of the checked networks
+// are pending until their blocks are checked, unless they are known to be deeper than the depth
+func (r *Reorg) Finality(network string, blockNumber int64) string {
+	if !r.enabled || !lo.Contains(protocol.EthclientNetworks, network) {
+		return model.FinalityFinalized
+	}
+
+	r.locker.RLock()
+	head, exists := r.heads[network]
+	r.locker.RUnlock()
+
+	if exists && blockNumber > 0 && head-blockNumber >= r.Depth(network) {
+		return model.FinalityFinalized
+	}
+
+	return model.FinalityPending
+}
+
+// check compares the blocks of the stored transactions within the depth of network with the canonical ones,
+// the other replicas skip the network while it's locked
+func (r *Reorg) check(ctx context.Context, network string) error {
+	lockKey := fmt.Sprintf("reorg:%s", network)
+
+	if !r.employer.DoLock(lockKey, lockTimeout) {
+		return nil
+	}
+
+	defer r.employer.UnLock(lockKey)
+
+	ethereumClient, err := ethclientx.Global(network)
+	if err != nil {
+		return err
+	}
+
+	head, err := ethereumClient.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("get latest block number: %w", err)
+	}
+
+	r.locker.Lock()
+	r.heads[network] = int64(head)
+	r.locker.Unlock()
+
+	from := int64(head) - r.Depth(network)
+	if from < 0 {
+		from = 0
+	}
+
+	if err := advance(ctx, network, int64(head), from); err != nil {
+		return err
+	}
+
+	// Transactions are indexed by timestamp rather than block number
+	fromHeader, err := getHeader(ctx, ethereumClient, from)
+	if err != nil {
+		return err
+	}
+
+	var stored []Block
+
+	if err := database.Global().WithContext(ctx).
+		Model((*model.Transaction)(nil)).
+		Distinct("block_number", "block_hash").
+		Where("network = ?", network).
+		Where("timestamp >= ?", time.Unix(int64(fromHeader.Timestamp), 0)).
+		Where("block_number >= ?", from).
+		Where("block_hash <> ''").
+		Scan(&stored).Error; err != nil {
+		return fmt.Errorf("get stored blocks: %w", err)
+	}
+
+	if len(stored) == 0 {
+		return nil
+	}
+
+	var (
+		locker    sync.Mutex
+		canonical = make(map[int64]string)
+		numbers   = lo.Uniq(lo.Map(stored, func(block Block, _ int) int64 {
+			return block.Number
+		}))
+	)
+
+	for _, chunk := range lo.Chunk(numbers, maxConcurrency) {
+		var (
+			wg       sync.WaitGroup
+			chunkErr error
+		)
+
+		for _, number := range chunk {
+			wg.Add(1)
+
+			go func(number int64) {
+				defer wg.Done()
+
+				header, err := getHeader(ctx, ethereumClient, number)
+
+				locker.Lock()
+				defer locker.Unlock()
+
+				if err != nil {
+					chunkErr = err
+
+					return
+				}
+
+				canonical[number] = header.Hash.String()
+			}(number)
+		}
+
+		wg.Wait()
+
+		// An unknown canonical hash must not be taken as a reorg
+		if chunkErr != nil {
+			return chunkErr
+		}
+	}
+
+	orphaned := Orphaned(stored, canonical)
+
+	if len(orphaned) > 0 {
+		if err := r.rollback(ctx, network, orphaned, canonical); err != nil {
+			return err
+		}
+	}
+
+	// The remaining pending transactions of the checked blocks are canonical
+	if err := database.Global().WithContext(ctx).
+		Model((*model.Transaction)(nil)).
+		Where("network = ? AND finality = ?", network, model.FinalityPending).
+		Where("block_hash IN ?", lo.Values(canonical)).
+		Update("finality", model.FinalityConfirmed).Error; err != nil {
+		return fmt.Errorf("confirm transactions: %w", err)
+	}
+
+	return nil
+}
+
+// advance finalizes the transactions of network below from, and updates the confirmations of the others
+func advance(ctx context.Context, network string, head, from int64) error {
+	if err := database.Global().WithContext(ctx).
+		Model((*model.Transaction)(nil)).
+		Where("network = ? AND finality <> ?", network, model.FinalityFinalized).
+		Where("block_number < ?", from).
+		Updates(map[string]any{
+			"finality":      model.FinalityFinalized,
+			"confirmations": 0,
+		}).Error; err != nil {
+		return fmt.Errorf("finalize transactions: %w", err)
+	}
+
+	if err := database.Global().WithContext(ctx).
+		Model((*model.Transaction)(nil)).
+		Where("network = ? AND finality <> ?", network, model.FinalityFinalized).
+		Where("block_number > 0").
+		Update("confirmations", gorm.Expr("GREATEST(? - block_number, 0)", head)).Error; err != nil {
+		return fmt.Errorf("update confirmations: %w", err)
+	}
+
+	return nil
+}
+
+// rollback deletes the transactions of orphaned blocks and their transfers, and indexes their owners again
+func (r *Reorg) rollback(ctx context.Context, network string, orphaned []Block, canonical map[int64]string) error {
+	var transactions []model.Transaction
+
+	for _, block := range orphaned {
+		var internalTransactions []model.Transaction
+
+		if err := database.Global().WithContext(ctx).
+			Select("hash", "owner").
+			Where("network = ? AND block_number = ? AND block_hash = ?", network, block.Number, block.Hash).
+			Find(&internalTransactions).Error; err != nil {
+			return fmt.Errorf("get orphaned transactions: %w", err)
+		}
+
+		transactions = append(transactions, internalTransactions...)
+	}
+
+	hashes := lo.Uniq(lo.Map(transactions, func(transaction model.Transaction, _ int) string {
+		return transaction.Hash
+	}))
+
+	owners := lo.Uniq(lo.Map(transactions, func(transaction model.Transaction, _ int) string {
+		return transaction.Owner
+	}))
+
+	if err := database.Global().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, chunk := range lo.Chunk(hashes, 500) {
+			if err := tx.Where("network = ? AND transaction_hash IN ?", network, chunk).Delete(&model.Transfer{}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("network = ? AND hash IN ?", network, chunk).Delete(&model.Transaction{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// The nonces recorded after the orphaned blocks would skip indexing the owners again
+		for _, chunk := range lo.Chunk(owners, 500) {
+			if err := tx.Model(&model.Address{}).Where("address IN ?", chunk).Update("nonce", gorm.Expr("nonce - ?", network)).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("delete orphaned transactions: %w", err)
+	}
+
+	for _, owner := range owners {
+		if err := r.reindex(ctx, owner, network); err != nil {
+			loggerx.Global().Error("failed to reindex address", zap.Error(err), zap.String("address", owner), zap.String("network", network))
+		}
+	}
+
+	for _, block := range orphaned {
+		loggerx.Global().Warn(
+			"block orphaned",
+			zap.String("network", network),
+			zap.Int64("block_number", block.Number),
+			zap.String("block_hash", block.Hash),
+			zap.String("canonical_block_hash", canonical[block.Number]),
+		)
+	}
+
+	loggerx.Global().Info("reorg rollback completion", zap.String("network", network), zap.Int("blocks", len(orphaned)), zap.Int("transactions", len(hashes)), zap.Int("addresses", len(owners)))
+
+	return nil
+}
+
+type header struct {
+	Hash      common.Hash    `json:"hash"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+}
+
+// getHeader returns the block hash reported by the node, since the hash computed from
+// go-ethereum headers doesn't match on the networks with extra header fields, such as Celo
+func getHeader(ctx context.Context, ethereumClient *ethclient.Client, number int64) (*header, error) {
+	var result header
+
+	if err := ethereumClient.Client().CallContext(ctx, &result, "eth_getBlockByNumber", hexutil.EncodeBig(big.NewInt(number)), false); err != nil {
+		return nil, fmt.Errorf("get header %d: %w", number, err)
+	}
+
+	if result.Hash == (common.Hash{}) {
+		return nil, fmt.Errorf("header %d not found", number)
+	}
+
+	return &result, nil
+}
+
+// Orphaned returns the stored blocks which hashes are not canonical, ordered by number and hash
+func Orphaned(stored []Block, canonical map[int64]string) []Block {
+	orphaned := lo.Filter(stored, func(block Block, _ int) bool {
+		hash, exists := canonical[block.Number]
+
+		return exists && hash != block.Hash
+	})
+
+	sort.SliceStable(orphaned, func(i, j int) bool {
+		if orphaned[i].Number != orphaned[j].Number {
+			return orphaned[i].Number < orphaned[j].Number
+		}
+
+		return orphaned[i].Hash < orphaned[j].Hash
+	})
+
+	return orphaned
+}
+
+func New(config *configx.Reorg, employer *shedlock.Employer, reindex Reindexer) *Reorg {
+	reorg := Reorg{
+		heads:    make(map[string]int64),
+		interval: DefaultInterval,
+		depths:   make(map[string]int64),
+		employer: employer,
+		reindex:  reindex,
+	}
+
+	for network, depth := range DefaultDepths {
+		reorg.depths[network] = depth
+	}
+
+	if config != nil {
+		reorg.enabled = config.Enabled
+
+		if config.Interval > 0 {
+			reorg.interval = config.Interval
+		}
+
+		for network, depth := range config.Depths {
+			reorg.depths[network] = depth
+		}
+	}
+
+	return &reorg
+}
@@ -0,0 +1,63 @@
+package reorg
+
+import (
+	"testing"
+	"time"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrphaned(t *testing.T) {
+	stored := []Block{
+		{Number: 3, Hash: "0x3b"},
+		{Number: 1, Hash: "0x1a"},
+		{Number: 2, Hash: "0x2a"},
+		// Two versions of a block are stored
+		{Number: 3, Hash: "0x3a"},
+		// Unknown to the node yet
+		{Number: 4, Hash: "0x4a"},
+	}
+
+	canonical := map[int64]string{
+		1: "0x1a",
+		2: "0x2b",
+		3: "0x3c",
+	}
+
+	assert.Equal(t, []Block{
+		{Number: 2, Hash: "0x2a"},
+		{Number: 3, Hash: "0x3a"},
+		{Number: 3, Hash: "0x3b"},
+	}, Orphaned(stored, canonical))
+}
+
+func TestDepth(t *testing.T) {
+	reorg := New(&configx.Reorg{
+		Interval: time.Second,
+		Depths: map[string]int64{
+			protocol.NetworkPolygon: 512,
+			protocol.NetworkXDAI:    8,
+		},
+	}, nil, nil)
+
+	assert.Equal(t, int64(512), reorg.Depth(protocol.NetworkPolygon))
+	assert.Equal(t, int64(8), reorg.Depth(protocol.NetworkXDAI))
+	assert.Equal(t, DefaultDepths[protocol.NetworkEthereum], reorg.Depth(protocol.NetworkEthereum))
+	assert.Equal(t, int64(DefaultDepth), reorg.Depth(protocol.NetworkFantom))
+}
+
+func TestFinality(t *testing.T) {
+	disabled := New(nil, nil, nil)
+	assert.Equal(t, model.FinalityFinalized, disabled.Finality(protocol.NetworkEthereum, 100))
+
+	reorg := New(&configx.Reorg{Enabled: true}, nil, nil)
+	assert.Equal(t, model.FinalityFinalized, reorg.Finality(protocol.NetworkArweave, 100), "unchecked network")
+	assert.Equal(t, model.FinalityPending, reorg.Finality(protocol.NetworkEthereum, 100), "unknown head")
+
+	reorg.heads[protocol.NetworkEthereum] = 112
+	assert.Equal(t, model.FinalityFinalized, reorg.Finality(protocol.NetworkEthereum, 100))
+	assert.Equal(t, model.FinalityPending, reorg.Finality(protocol.NetworkEthereum, 101))
+}
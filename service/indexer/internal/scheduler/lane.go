@@ -0,0 +1,80 @@
+package scheduler
+
+import "time"
+
+// lane keeps the pending jobs grouped by address, addresses are served in the order they become pending
+type lane struct {
+	addresses []string
+	jobs      map[string][]*Job
+	size      int
+}
+
+func (l *lane) push(job *Job) {
+	if _, exists := l.jobs[job.Address]; !exists {
+		l.addresses = append(l.addresses, job.Address)
+	}
+
+	l.jobs[job.Address] = append(l.jobs[job.Address], job)
+	l.size++
+}
+
+// pop takes the first available job, its address is moved to the end of the lane if it still has pending jobs
+func (l *lane) pop(available func(job *Job) bool) *Job {
+	for addressIndex, address := range l.addresses {
+		jobs := l.jobs[address]
+
+		for jobIndex, job := range jobs {
+			if !available(job) {
+				continue
+			}
+
+			jobs = append(jobs[:jobIndex:jobIndex], jobs[jobIndex+1:]...)
+
+			l.addresses = append(l.addresses[:addressIndex:addressIndex], l.addresses[addressIndex+1:]...)
+
+			if len(jobs) == 0 {
+				delete(l.jobs, address)
+			} else {
+				l.jobs[address] = jobs
+				l.addresses = append(l.addresses, address)
+			}
+
+			l.size--
+
+			return job
+		}
+	}
+
+	return nil
+}
+
+func (l *lane) oldest() (oldest time.Time) {
+	for _, jobs := range l.jobs {
+		// Jobs of an address are in the order they are submitted
+		if oldest.IsZero() || jobs[0].submittedAt.Before(oldest) {
+			oldest = jobs[0].submittedAt
+		}
+	}
+
+	return oldest
+}
+
+func (l *lane) clear() []*Job {
+	jobs := make([]*Job, 0, l.size)
+
+	for _, address := range l.addresses {
+		jobs = append(jobs, l.jobs[address]...)
+	}
+
+	l.addresses = nil
+	l.jobs = make(map[string][]*Job)
+	l.size = 0
+
+	return jobs
+}
+
+func newLane() *lane {
+	return &lane{
+		jobs: make(map[string][]*Job),
+	}
+}
@@ -0,0 +1,296 @@
+package scheduler
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+)
+
+type Lane int
+
+const (
+	// LaneRefresh is for the messages published by the hub when a user asks for refreshing
+	LaneRefresh Lane = iota
+	// LaneIO is for the messages of protocol.IndexerWorkQueueIO
+	LaneIO
+	// LaneBackground is for the other messages, such as the re-indexes published by the crawler
+	LaneBackground
+	// LaneBackfill is for the history windows of backfill
+	LaneBackfill
+
+	laneCount = iota
+)
+
+const defaultCapacity = 100
+
+var (
+	laneNames = [laneCount]string{"refresh", "io", "background", "backfill"}
+
+	defaultWeights = [laneCount]int{4, 2, 1, 1}
+
+	// Exposed at /debug/vars
+	metrics = expvar.NewMap("indexer_scheduler")
+)
+
+func (l Lane) String() string {
+	if l < 0 || l >= laneCount {
+		return "unknown"
+	}
+
+	return laneNames[l]
+}
+
+type Job struct {
+	Lane    Lane
+	Address string
+	Network string
+	// Run is called in its own goroutine when the job is dispatched
+	Run func()
+	// Drop is called if the job is still pending when the scheduler stops
+	Drop func()
+
+	submittedAt time.Time
+}
+
+// Scheduler dispatches the jobs with a bounded concurrency in total and for each network.
+// Lanes are visited in priority order, each takes at most its weight of jobs in a round,
+// so that a busy lane can't starve the others. Addresses of a lane are served in round-robin.
+type Scheduler struct {
+	locker         sync.Mutex
+	cond           *sync.Cond
+	concurrency    int
+	capacity       int
+	networkLimits  map[string]int
+	weights        [laneCount]int
+	credits        [laneCount]int
+	lanes          [laneCount]*lane
+	running        int
+	networkRunning map[string]int
+	stopped        bool
+}
+
+// Submit blocks while the pending jobs reach the capacity, so that consumers stop receiving,
+// the job is dropped if the scheduler has been stopped
+func (s *Scheduler) Submit(job *Job) {
+	s.locker.Lock()
+
+	for !s.stopped && s.pending() >= s.capacity {
+		s.cond.Wait()
+	}
+
+	if s.stopped {
+		s.locker.Unlock()
+
+		if job.Drop != nil {
+			job.Drop()
+		}
+
+		return
+	}
+
+	defer s.locker.Unlock()
+
+	if job.Lane < 0 || job.Lane >= laneCount {
+		job.Lane = LaneBackground
+	}
+
+	job.submittedAt = time.Now()
+
+	s.lanes[job.Lane].push(job)
+
+	metrics.Add("submitted."+job.Lane.String(), 1)
+
+	s.schedule()
+}
+
+// Stop stops dispatching and drops the pending jobs, the running jobs are not affected
+func (s *Scheduler) Stop() (dropped int) {
+	s.locker.Lock()
+
+	s.stopped = true
+	s.cond.Broadcast()
+
+	var jobs []*Job
+
+	for _, lane := range s.lanes {
+		jobs = append(jobs, lane.clear()...)
+	}
+
+	s.updateMetrics()
+
+	s.locker.Unlock()
+
+	for _, job := range jobs {
+		if job.Drop != nil {
+			job.Drop()
+		}
+	}
+
+	return len(jobs)
+}
+
+type Stats struct {
+	Running int                `json:"running"`
+	Pending map[string]int     `json:"pending"`
+	Network map[string]int     `json:"network"`
+	Oldest  map[string]float64 `json:"oldest"`
+}
+
+// Stats returns the number of running jobs, the pending jobs and the seconds the oldest job has waited of each lane
+func (s *Scheduler) Stats() Stats {
+	s.locker.Lock()
+
+	defer s.locker.Unlock()
+
+	stats := Stats{
+		Running: s.running,
+		Pending: make(map[string]int),
+		Network: make(map[string]int),
+		Oldest:  make(map[string]float64),
+	}
+
+	for network, running := range s.networkRunning {
+		stats.Network[network] = running
+	}
+
+	for index, lane := range s.lanes {
+		name := Lane(index).String()
+
+		stats.Pending[name] = lane.size
+
+		if oldest := lane.oldest(); !oldest.IsZero() {
+			stats.Oldest[name] = time.Since(oldest).Seconds()
+		}
+	}
+
+	return stats
+}
+
+// schedule dispatches the jobs until the concurrency is exhausted, it must be called with the lock held
+func (s *Scheduler) schedule() {
+	for !s.stopped && s.running < s.concurrency {
+		job := s.next()
+		if job == nil {
+			break
+		}
+
+		s.running++
+		s.networkRunning[job.Network]++
+
+		metrics.Add("dispatched."+job.Lane.String(), 1)
+		metrics.AddFloat("wait_seconds."+job.Lane.String(), time.Since(job.submittedAt).Seconds())
+
+		go s.run(job)
+
+		s.cond.Signal()
+	}
+
+	s.updateMetrics()
+}
+
+func (s *Scheduler) run(job *Job) {
+	defer func() {
+		s.locker.Lock()
+
+		defer s.locker.Unlock()
+
+		s.running--
+
+		if s.networkRunning[job.Network]--; s.networkRunning[job.Network] <= 0 {
+			delete(s.networkRunning, job.Network)
+		}
+
+		s.schedule()
+	}()
+
+	job.Run()
+}
+
+// next takes the job to run from the lanes, a new round starts when the lanes run out of their credits
+func (s *Scheduler) next() *Job {
+	for round := 0; round < 2; round++ {
+		for index, lane := range s.lanes {
+			if s.credits[index] <= 0 {
+				continue
+			}
+
+			if job := lane.pop(s.available); job != nil {
+				s.credits[index]--
+
+				return job
+			}
+		}
+
+		s.credits = s.weights
+	}
+
+	return nil
+}
+
+func (s *Scheduler) pending() (pending int) {
+	for _, lane := range s.lanes {
+		pending += lane.size
+	}
+
+	return pending
+}
+
+func (s *Scheduler) available(job *Job) bool {
+	limit, exists := s.networkLimits[job.Network]
+
+	return !exists || s.networkRunning[job.Network] < limit
+}
+
+func (s *Scheduler) updateMetrics() {
+	running := new(expvar.Int)
+	running.Set(int64(s.running))
+
+	metrics.Set("running", running)
+
+	for index, lane := range s.lanes {
+		pending := new(expvar.Int)
+		pending.Set(int64(lane.size))
+
+		metrics.Set("pending."+Lane(index).String(), pending)
+	}
+}
+
+func New(config *configx.Scheduler) *Scheduler {
+	scheduler := Scheduler{
+		concurrency:    config.Concurrency,
+		capacity:       config.Capacity,
+		networkLimits:  make(map[string]int),
+		weights:        defaultWeights,
+		networkRunning: make(map[string]int),
+	}
+
+	// Nothing would be dispatched without any concurrency
+	if scheduler.concurrency <= 0 {
+		scheduler.concurrency = 1
+	}
+
+	if scheduler.capacity <= 0 {
+		scheduler.capacity = defaultCapacity
+	}
+
+	for network, limit := range config.Networks {
+		scheduler.networkLimits[network] = limit
+	}
+
+	for index := range scheduler.weights {
+		if weight, exists := config.Weights[Lane(index).String()]; exists && weight > 0 {
+			scheduler.weights[index] = weight
+		}
+	}
+
+	for index := range scheduler.lanes {
+		scheduler.lanes[index] = newLane()
+	}
+
+	scheduler.credits = scheduler.weights
+	scheduler.cond = sync.NewCond(&scheduler.locker)
+
+	return &scheduler
+}
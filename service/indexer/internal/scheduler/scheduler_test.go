@@ -0,0 +1,229 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder submits the jobs while the scheduler is blocked by a running job, and records the order they run
+type recorder struct {
+	locker  sync.Mutex
+	order   []string
+	blocker chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (r *recorder) job(lane Lane, address, network string) *Job {
+	r.wg.Add(1)
+
+	return &Job{
+		Lane:    lane,
+		Address: address,
+		Network: network,
+		Run: func() {
+			defer r.wg.Done()
+
+			r.locker.Lock()
+			r.order = append(r.order, address+"@"+network)
+			r.locker.Unlock()
+		},
+	}
+}
+
+func (r *recorder) block(scheduler *Scheduler) {
+	r.blocker = make(chan struct{})
+
+	scheduler.Submit(&Job{
+		Lane:    LaneRefresh,
+		Address: "blocker",
+		Run: func() {
+			<-r.blocker
+		},
+	})
+}
+
+func (r *recorder) release() []string {
+	close(r.blocker)
+
+	r.wg.Wait()
+
+	return r.order
+}
+
+func TestSchedulerWeights(t *testing.T) {
+	scheduler := New(&configx.Scheduler{
+		Concurrency: 1,
+		Weights: map[string]int{
+			"refresh":    2,
+			"io":         1,
+			"background": 1,
+		},
+	})
+
+	r := recorder{}
+	r.block(scheduler)
+
+	for _, address := range []string{"r1", "r2", "r3", "r4"} {
+		scheduler.Submit(r.job(LaneRefresh, address, protocol.NetworkEthereum))
+	}
+
+	scheduler.Submit(r.job(LaneIO, "i1", protocol.NetworkEthereum))
+	scheduler.Submit(r.job(LaneBackground, "b1", protocol.NetworkEthereum))
+
+	assert.Equal(t, 6, scheduler.Stats().Pending["refresh"]+scheduler.Stats().Pending["io"]+scheduler.Stats().Pending["background"])
+
+	// The blocker used a credit of refresh lane in the first round
+	assert.Equal(t, []string{
+		"r1@ethereum", "i1@ethereum", "b1@ethereum",
+		"r2@ethereum", "r3@ethereum",
+		"r4@ethereum",
+	}, r.release())
+}
+
+func TestSchedulerAddressFairness(t *testing.T) {
+	scheduler := New(&configx.Scheduler{
+		Concurrency: 1,
+	})
+
+	r := recorder{}
+	r.block(scheduler)
+
+	for _, network := range []string{protocol.NetworkEthereum, protocol.NetworkPolygon, protocol.NetworkArbitrum} {
+		scheduler.Submit(r.job(LaneBackground, "a", network))
+	}
+
+	scheduler.Submit(r.job(LaneBackground, "b", protocol.NetworkEthereum))
+	scheduler.Submit(r.job(LaneBackground, "c", protocol.NetworkEthereum))
+
+	assert.Equal(t, []string{
+		"a@ethereum", "b@ethereum", "c@ethereum",
+		"a@polygon", "a@arbitrum",
+	}, r.release())
+}
+
+func TestSchedulerNetworkLimit(t *testing.T) {
+	scheduler := New(&configx.Scheduler{
+		Concurrency: 4,
+		Networks: map[string]int{
+			protocol.NetworkEthereum: 1,
+		},
+	})
+
+	var (
+		locker  sync.Mutex
+		running int
+		maximum int
+		wg      sync.WaitGroup
+	)
+
+	for _, address := range []string{"a", "b", "c", "d", "e"} {
+		wg.Add(1)
+
+		scheduler.Submit(&Job{
+			Address: address,
+			Network: protocol.NetworkEthereum,
+			Run: func() {
+				defer wg.Done()
+
+				locker.Lock()
+				if running++; running > maximum {
+					maximum = running
+				}
+				locker.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				locker.Lock()
+				running--
+				locker.Unlock()
+			},
+		})
+	}
+
+	// Other networks are not blocked by ethereum
+	polygonCh := make(chan struct{})
+
+	scheduler.Submit(&Job{
+		Address: "f",
+		Network: protocol.NetworkPolygon,
+		Run: func() {
+			close(polygonCh)
+		},
+	})
+
+	select {
+	case <-polygonCh:
+	case <-time.After(time.Second):
+		t.Fatal("polygon job is blocked")
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 1, maximum)
+}
+
+func TestSchedulerStop(t *testing.T) {
+	scheduler := New(&configx.Scheduler{
+		Concurrency: 1,
+	})
+
+	r := recorder{}
+	r.block(scheduler)
+
+	var dropped []string
+
+	for _, address := range []string{"a", "b"} {
+		address := address
+
+		scheduler.Submit(&Job{
+			Address: address,
+			Run: func() {
+				t.Errorf("dropped job %s is run", address)
+			},
+			Drop: func() {
+				dropped = append(dropped, address)
+			},
+		})
+	}
+
+	assert.Equal(t, 2, scheduler.Stop())
+	assert.Equal(t, []string{"a", "b"}, dropped)
+	assert.Equal(t, 1, scheduler.Stats().Running)
+
+	r.release()
+}
+
+func TestSchedulerCapacity(t *testing.T) {
+	scheduler := New(&configx.Scheduler{
+		Concurrency: 1,
+		Capacity:    1,
+	})
+
+	r := recorder{}
+	r.block(scheduler)
+
+	scheduler.Submit(r.job(LaneBackground, "a", protocol.NetworkEthereum))
+
+	submittedCh := make(chan struct{})
+
+	go func() {
+		scheduler.Submit(r.job(LaneBackground, "b", protocol.NetworkEthereum))
+
+		close(submittedCh)
+	}()
+
+	select {
+	case <-submittedCh:
+		t.Fatal("submitting isn't blocked by the capacity")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.Equal(t, []string{"a@ethereum", "b@ethereum"}, r.release())
+
+	<-submittedCh
+}
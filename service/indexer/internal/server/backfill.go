@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/scheduler"
+	"github.com/samber/lo"
+)
+
+var errDropped = errors.New("dropped by scheduler")
+
+// backfillWindow indexes a backfill window in the backfill lane of the scheduler, so that it yields to live indexing
+func (s *Server) backfillWindow(ctx context.Context, message *protocol.Message) (int, error) {
+	type result struct {
+		transactions int
+		err          error
+	}
+
+	resultCh := make(chan result, 1)
+
+	s.scheduler.Submit(&scheduler.Job{
+		Lane:    scheduler.LaneBackfill,
+		Address: message.Address,
+		Network: message.Network,
+		Run: func() {
+			transactions, err := s.indexWindow(ctx, message)
+
+			resultCh <- result{transactions: transactions, err: err}
+		},
+		Drop: func() {
+			resultCh <- result{err: errDropped}
+		},
+	})
+
+	select {
+	case result := <-resultCh:
+		return result.transactions, result.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// indexWindow builds all the transactions in the block range of message, a window with failed datasources
+// is not saved, otherwise the checkpoint would skip the transactions of them
+func (s *Server) indexWindow(ctx context.Context, message *protocol.Message) (int, error) {
+	transactions, err := s.handleDatasources(ctx, message, s.datasources)
+	if err != nil {
+		return 0, err
+	}
+
+	// Not every datasource supports the upper bound
+	transactions = lo.Filter(transactions, func(transaction model.Transaction, _ int) bool {
+		return transaction.BlockNumber >= message.BlockNumber && transaction.BlockNumber <= message.BlockNumberTo
+	})
+
+	result := s.buildTransactions(ctx, message, transactions, s.graph.Levels, 0)
+
+	if err := s.upsertTransactions(ctx, message, database.Global().WithContext(ctx).Begin(), result); err != nil {
+		return 0, err
+	}
+
+	return len(result), nil
+}
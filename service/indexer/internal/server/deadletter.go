@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/mq"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"go.uber.org/zap"
+)
+
+// Stop reading the dead letter queue when nothing arrives in this duration
+const deadLetterIdleTimeout = 3 * time.Second
+
+// ListDeadLetters returns at most limit dead letters and leaves them in the queue,
+// amqp delivers no more unacknowledged messages than the mq prefetch count.
+func (s *Server) ListDeadLetters(ctx context.Context, limit int) ([]protocol.DeadLetterMessage, error) {
+	deadLetters := make([]protocol.DeadLetterMessage, 0)
+	deliveries := make([]*mq.Delivery, 0)
+
+	defer func() {
+		for _, delivery := range deliveries {
+			if err := s.queue.Nack(context.Background(), delivery, true); err != nil {
+				loggerx.Global().Error("failed to requeue dead letter", zap.Error(err))
+			}
+		}
+	}()
+
+	err := s.consumeDeadLetters(ctx, limit, func(delivery *mq.Delivery, deadLetter protocol.DeadLetterMessage) error {
+		deliveries = append(deliveries, delivery)
+		deadLetters = append(deadLetters, deadLetter)
+
+		return nil
+	})
+
+	return deadLetters, err
+}
+
+// ReplayDeadLetters publishes at most limit dead letters to their original queues with a reset retry count
+func (s *Server) ReplayDeadLetters(ctx context.Context, limit int) (replayed int, err error) {
+	err = s.consumeDeadLetters(ctx, limit, func(delivery *mq.Delivery, deadLetter protocol.DeadLetterMessage) error {
+		deadLetter.Message.Retry = 0
+
+		messageData, err := json.Marshal(&deadLetter.Message)
+		if err != nil {
+			return err
+		}
+
+		if err := s.queue.Publish(ctx, protocol.ExchangeJob, deadLetter.RoutingKey, messageData); err != nil {
+			_ = s.queue.Nack(ctx, delivery, true)
+
+			return err
+		}
+
+		replayed++
+
+		return s.queue.Ack(ctx, delivery)
+	})
+
+	return replayed, err
+}
+
+func (s *Server) consumeDeadLetters(ctx context.Context, limit int, handler func(delivery *mq.Delivery, deadLetter protocol.DeadLetterMessage) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	defer cancel()
+
+	deliveryCh, err := s.queue.Consume(ctx, mq.Binding{
+		Exchange:   protocol.ExchangeJob,
+		RoutingKey: protocol.IndexerDeadLetterRoutingKey,
+		Queue:      protocol.IndexerDeadLetterQueue,
+	})
+	if err != nil {
+		return fmt.Errorf("consume %s: %w", protocol.IndexerDeadLetterQueue, err)
+	}
+
+	for count := 0; count < limit; count++ {
+		var delivery *mq.Delivery
+
+		select {
+		case delivery = <-deliveryCh:
+		case <-time.After(deadLetterIdleTimeout):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if delivery == nil {
+			return nil
+		}
+
+		deadLetter := protocol.DeadLetterMessage{}
+		if err := json.Unmarshal(delivery.Body, &deadLetter); err != nil {
+			loggerx.Global().Error("drop malformed dead letter", zap.Error(err), zap.ByteString("body", delivery.Body))
+
+			_ = s.queue.Ack(ctx, delivery)
+
+			continue
+		}
+
+		if err := handler(delivery, deadLetter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
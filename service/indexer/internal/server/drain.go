@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/mq"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"go.uber.org/zap"
+)
+
+// job is a message being handled, it holds the shedlock key of its address and network
+type job struct {
+	Queue     string
+	Address   string
+	Network   string
+	LockKey   string
+	StartedAt time.Time
+
+	delivery *mq.Delivery
+}
+
+// jobTracker counts the dispatched deliveries and records the started jobs, so that they can be drained
+type jobTracker struct {
+	waitGroup sync.WaitGroup
+	locker    sync.Mutex
+	jobs      map[*mq.Delivery]*job
+}
+
+// add must be called before the delivery is handed to its goroutine
+func (t *jobTracker) add() {
+	t.waitGroup.Add(1)
+}
+
+func (t *jobTracker) start(delivery *mq.Delivery, message *protocol.Message, lockKey string) {
+	t.locker.Lock()
+
+	defer t.locker.Unlock()
+
+	t.jobs[delivery] = &job{
+		Queue:     delivery.Queue,
+		Address:   message.Address,
+		Network:   message.Network,
+		LockKey:   lockKey,
+		StartedAt: time.Now(),
+		delivery:  delivery,
+	}
+}
+
+// done must be called after the delivery has been settled
+func (t *jobTracker) done(delivery *mq.Delivery) {
+	t.locker.Lock()
+	delete(t.jobs, delivery)
+	t.locker.Unlock()
+
+	t.waitGroup.Done()
+}
+
+// wait returns false if the jobs are still running after timeout
+func (t *jobTracker) wait(timeout time.Duration) bool {
+	doneCh := make(chan struct{})
+
+	go func() {
+		t.waitGroup.Wait()
+
+		close(doneCh)
+	}()
+
+	select {
+	case <-doneCh:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (t *jobTracker) running() []*job {
+	t.locker.Lock()
+
+	defer t.locker.Unlock()
+
+	jobs := make([]*job, 0, len(t.jobs))
+
+	for _, job := range t.jobs {
+		jobs = append(jobs, job)
+	}
+
+	return jobs
+}
+
+// drain waits for the in-flight jobs after consuming is stopped, the jobs still running after DRAIN_TIMEOUT
+// are cancelled, and those ignoring the cancellation are abandoned with their locks released
+func (s *Server) drain(cancelJobs context.CancelFunc) []*job {
+	loggerx.Global().Info("start draining jobs", zap.Int("jobs", len(s.jobs.running())), zap.Duration("timeout", DRAIN_TIMEOUT))
+
+	if s.jobs.wait(DRAIN_TIMEOUT) {
+		return nil
+	}
+
+	// The cancelled jobs fail and their messages are requeued by settle
+	cancelJobs()
+
+	if s.jobs.wait(DRAIN_CANCEL_TIMEOUT) {
+		return nil
+	}
+
+	abandonedJobs := s.jobs.running()
+
+	for _, abandonedJob := range abandonedJobs {
+		s.employer.UnLock(abandonedJob.LockKey)
+
+		if err := s.queue.Nack(context.Background(), abandonedJob.delivery, true); err != nil {
+			loggerx.Global().Error("failed to requeue abandoned job", zap.Error(err), zap.String("queue", abandonedJob.Queue))
+		}
+
+		loggerx.Global().Warn(
+			"job abandoned",
+			zap.String("queue", abandonedJob.Queue),
+			zap.String("address", abandonedJob.Address),
+			zap.String("network", abandonedJob.Network),
+			zap.Duration("duration", time.Since(abandonedJob.StartedAt)),
+		)
+	}
+
+	return abandonedJobs
+}
+
+func newJobTracker() *jobTracker {
+	return &jobTracker{
+		jobs: make(map[*mq.Delivery]*job),
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/datasource"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/diff"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker"
+	"github.com/samber/lo"
+)
+
+// Workers build at most MAX_LATEST_TRANSACTIONS transactions
+const DIFF_LIMIT = MAX_LATEST_TRANSACTIONS
+
+type IndexOptions struct {
+	// Datasources and Workers restrict the ones to run by name, all of them run if empty
+	Datasources []string
+	Workers     []string
+	// DryRun skips writing the transactions to the database
+	DryRun bool
+}
+
+// Index runs the datasources and workers for an address once without the queue and locks,
+// it indexes the latest MAX_LATEST_TRANSACTIONS transactions of the address and returns the ones built by the workers
+func (s *Server) Index(ctx context.Context, message *protocol.Message, options IndexOptions) ([]model.Transaction, error) {
+	datasources, err := filterByName(s.datasources, options.Datasources, datasource.Datasource.Name)
+	if err != nil {
+		return nil, fmt.Errorf("filter datasources: %w", err)
+	}
+
+	workers, err := filterByName(s.workers, options.Workers, worker.Worker.Name)
+	if err != nil {
+		return nil, fmt.Errorf("filter workers: %w", err)
+	}
+
+	message.Address = strings.ToLower(message.Address)
+
+	transactions, datasourceError := s.handleDatasources(ctx, message, datasources)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	transactions = s.buildTransactions(ctx, message, transactions, s.graph.Filter(workers), MAX_LATEST_TRANSACTIONS)
+
+	if !options.DryRun {
+		if err := s.upsertTransactions(ctx, message, database.Global().WithContext(ctx).Begin(), transactions); err != nil {
+			return nil, err
+		}
+	}
+
+	return transactions, datasourceError
+}
+
+// Diff indexes an address without writing the database, and compares the result with the stored transactions.
+// The stored transactions older than the oldest built one are not compared, since workers only build the latest ones,
+// and neither are the transactions of the crawlers.
+func (s *Server) Diff(ctx context.Context, message *protocol.Message, options IndexOptions) (*diff.Result, error) {
+	options.DryRun = true
+
+	built, err := s.Index(ctx, message, options)
+	if err != nil && built == nil {
+		return nil, err
+	}
+
+	// The transactions of the crawlers aren't written by upsertTransactions
+	built = lo.Filter(built, func(transaction model.Transaction, _ int) bool {
+		return !crawled(transaction)
+	})
+
+	stored, storedErr := getStoredTransactions(ctx, message, built)
+	if storedErr != nil {
+		return nil, fmt.Errorf("get stored transactions: %w", storedErr)
+	}
+
+	stored = lo.Filter(stored, func(transaction model.Transaction, _ int) bool {
+		return !crawled(transaction)
+	})
+
+	result := diff.Compare(stored, built)
+
+	return &result, err
+}
+
+func getStoredTransactions(ctx context.Context, message *protocol.Message, built []model.Transaction) ([]model.Transaction, error) {
+	query := database.Global().WithContext(ctx).
+		Model((*model.Transaction)(nil)).
+		Where("owner = ?", message.Address).
+		Where("network = ?", message.Network).
+		Order("timestamp DESC").
+		Limit(DIFF_LIMIT)
+
+	if len(built) > 0 {
+		oldest := lo.MinBy(built, func(a, b model.Transaction) bool {
+			return a.Timestamp.Before(b.Timestamp)
+		})
+
+		query = query.Where("timestamp >= ?", oldest.Timestamp)
+	}
+
+	var transactions []model.Transaction
+
+	if err := query.Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	if len(transactions) == 0 {
+		return transactions, nil
+	}
+
+	var transfers []model.Transfer
+
+	hashes := lo.Map(transactions, func(transaction model.Transaction, _ int) string {
+		return transaction.Hash
+	})
+
+	for _, chunk := range lo.Chunk(hashes, DIFF_LIMIT) {
+		var internalTransfers []model.Transfer
+
+		if err := database.Global().WithContext(ctx).
+			Where("transaction_hash IN ?", chunk).
+			Where("network = ?", message.Network).
+			Order("index").
+			Find(&internalTransfers).Error; err != nil {
+			return nil, err
+		}
+
+		transfers = append(transfers, internalTransfers...)
+	}
+
+	transferMap := lo.GroupBy(transfers, func(transfer model.Transfer) string {
+		return transfer.TransactionHash
+	})
+
+	for index := range transactions {
+		transactions[index].Transfers = transferMap[transactions[index].Hash]
+	}
+
+	return transactions, nil
+}
+
+// filterByName keeps the elements in names in their original order, it fails if any of names doesn't exist
+func filterByName[T any](elements []T, names []string, name func(T) string) ([]T, error) {
+	if len(names) == 0 {
+		return elements, nil
+	}
+
+	available := lo.Map(elements, func(element T, _ int) string {
+		return name(element)
+	})
+
+	for _, elementName := range names {
+		if !lo.Contains(available, elementName) {
+			return nil, fmt.Errorf("%s doesn't exist, available: %s", elementName, strings.Join(available, ", "))
+		}
+	}
+
+	return lo.Filter(elements, func(element T, _ int) bool {
+		return lo.Contains(names, name(element))
+	}), nil
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/datasource"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/registry"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/reorg"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const owner = "0x000000000000000000000000000000000000a11c"
+
+// dryRunPool begins and ends the transactions of a dry run session, the statements aren't executed
+type dryRunPool struct {
+	gorm.ConnPool
+}
+
+func (p *dryRunPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (p *dryRunPool) Commit() error {
+	return nil
+}
+
+func (p *dryRunPool) Rollback() error {
+	return nil
+}
+
+// dryRun replaces the global database with a dry run session, it returns the transactions created
+func dryRun(t *testing.T) *[]model.Transaction {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunPool{}}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	assert.NoError(t, err)
+
+	var created []model.Transaction
+
+	assert.NoError(t, db.Callback().Create().After("gorm:create").Register("test:created", func(tx *gorm.DB) {
+		if transactions, ok := tx.Statement.Dest.([]model.Transaction); ok && tx.Error == nil {
+			created = append(created, transactions...)
+		}
+	}))
+
+	database.ReplaceGlobal(db)
+
+	return &created
+}
+
+type staticDatasource struct {
+	transactions []model.Transaction
+}
+
+func (d *staticDatasource) Name() string {
+	return "static"
+}
+
+func (d *staticDatasource) Networks() []string {
+	return []string{protocol.NetworkEthereum}
+}
+
+func (d *staticDatasource) Handle(context.Context, *protocol.Message) ([]model.Transaction, error) {
+	return append([]model.Transaction(nil), d.transactions...), nil
+}
+
+// newIndexServer returns a server initialized as InitializeIndex does, without dialing
+func newIndexServer(t *testing.T, datasources ...datasource.Datasource) *Server {
+	graph, err := worker.NewGraph(nil)
+	assert.NoError(t, err)
+
+	return &Server{
+		datasources: datasources,
+		graph:       graph,
+		registry:    registry.New(nil),
+		reorg:       reorg.New(&configx.Reorg{Enabled: true}, nil, nil),
+	}
+}
+
+// indexTransactions returns a transaction of owner, the transactions without transfers aren't built
+func indexTransactions() []model.Transaction {
+	return []model.Transaction{
+		{
+			Hash:        "0x01",
+			Owner:       owner,
+			AddressFrom: owner,
+			Network:     protocol.NetworkEthereum,
+			BlockNumber: 100,
+			Transfers: []model.Transfer{
+				{
+					TransactionHash: "0x01",
+					AddressFrom:     owner,
+					Network:         protocol.NetworkEthereum,
+					Metadata:        json.RawMessage(`{"standard":"Native","value":"1"}`),
+				},
+			},
+		},
+	}
+}
+
+func TestIndex(t *testing.T) {
+	created := dryRun(t)
+
+	server := newIndexServer(t, &staticDatasource{transactions: indexTransactions()})
+
+	transactions, err := server.Index(context.Background(), &protocol.Message{Address: owner, Network: protocol.NetworkEthereum}, IndexOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+
+	// The head isn't known without the checks, so the transaction is pending
+	assert.Len(t, *created, 1)
+	assert.Equal(t, model.FinalityPending, (*created)[0].Finality)
+}
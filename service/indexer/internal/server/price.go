@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/price"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PRICE_BACKFILL_BATCH is the number of rows valued at a time
+const PRICE_BACKFILL_BATCH = 500
+
+type PriceBackfillOptions struct {
+	// Networks restricts the rows to value, all networks are valued if empty
+	Networks []string
+	// Since and Until restrict the timestamp of the rows, no limit if they are zero
+	Since time.Time
+	Until time.Time
+}
+
+type PriceBackfillResult struct {
+	Transactions int `json:"transactions"`
+	Transfers    int `json:"transfers"`
+}
+
+// BackfillPrices sets the USD values of the stored fees and transfers which were indexed without them,
+// the values already set are kept. The rows whose tokens have no price stay unvalued.
+func (s *Server) BackfillPrices(ctx context.Context, options PriceBackfillOptions) (*PriceBackfillResult, error) {
+	var (
+		result      PriceBackfillResult
+		priceClient = price.New(database.Global())
+		err         error
+	)
+
+	if result.Transactions, err = s.backfillFeePrices(ctx, priceClient, options); err != nil {
+		return &result, fmt.Errorf("backfill fee prices: %w", err)
+	}
+
+	if result.Transfers, err = s.backfillTransferPrices(ctx, priceClient, options); err != nil {
+		return &result, fmt.Errorf("backfill transfer prices: %w", err)
+	}
+
+	return &result, nil
+}
+
+func (s *Server) backfillFeePrices(ctx context.Context, priceClient *price.Client, options PriceBackfillOptions) (int, error) {
+	var (
+		valued int
+		last   *model.Transaction
+	)
+
+	for {
+		sql := filterPriceBackfill(database.Global().WithContext(ctx), options).
+			Where("fee IS NOT NULL AND fee_value_usd IS NULL")
+
+		if last != nil {
+			sql = sql.Where("(hash, owner, network, source) > (?, ?, ?, ?)", last.Hash, last.Owner, last.Network, last.Source)
+		}
+
+		var transactions []model.Transaction
+
+		if err := sql.
+			Order("hash, owner, network, source").
+			Limit(PRICE_BACKFILL_BATCH).
+			Find(&transactions).Error; err != nil {
+			return valued, err
+		}
+
+		for index := range transactions {
+			transaction := &transactions[index]
+
+			if err := price.Enrich(ctx, transaction, priceClient.Price); err != nil {
+				return valued, err
+			}
+
+			if transaction.FeeValueUSD == nil {
+				continue
+			}
+
+			if err := database.Global().WithContext(ctx).
+				Model(&model.Transaction{}).
+				Where("hash = ? AND owner = ? AND network = ? AND source = ?", transaction.Hash, transaction.Owner, transaction.Network, transaction.Source).
+				Update("fee_value_usd", transaction.FeeValueUSD).Error; err != nil {
+				return valued, err
+			}
+
+			valued++
+		}
+
+		if len(transactions) < PRICE_BACKFILL_BATCH {
+			return valued, nil
+		}
+
+		last = &transactions[len(transactions)-1]
+
+		loggerx.Global().Info("backfill fee prices", zap.Int("valued", valued), zap.Time("timestamp", last.Timestamp))
+	}
+}
+
+func (s *Server) backfillTransferPrices(ctx context.Context, priceClient *price.Client, options PriceBackfillOptions) (int, error) {
+	var (
+		valued int
+		last   *model.Transfer
+	)
+
+	for {
+		// The social posts have no tokens
+		sql := filterPriceBackfill(database.Global().WithContext(ctx), options).
+			Where("tag <> ?", filter.TagSocial)
+
+		if last != nil {
+			sql = sql.Where("(transaction_hash, network, index) > (?, ?, ?)", last.TransactionHash, last.Network, last.Index)
+		}
+
+		var transfers []model.Transfer
+
+		if err := sql.
+			Order("transaction_hash, network, index").
+			Limit(PRICE_BACKFILL_BATCH).
+			Find(&transfers).Error; err != nil {
+			return valued, err
+		}
+
+		for index := range transfers {
+			transfer := &transfers[index]
+
+			changed, err := price.EnrichTransfer(ctx, transfer, priceClient.Price)
+			if err != nil {
+				return valued, err
+			}
+
+			if !changed {
+				continue
+			}
+
+			if err := database.Global().WithContext(ctx).
+				Model(&model.Transfer{}).
+				Where("transaction_hash = ? AND network = ? AND index = ?", transfer.TransactionHash, transfer.Network, transfer.Index).
+				Update("metadata", transfer.Metadata).Error; err != nil {
+				return valued, err
+			}
+
+			valued++
+		}
+
+		if len(transfers) < PRICE_BACKFILL_BATCH {
+			return valued, nil
+		}
+
+		last = &transfers[len(transfers)-1]
+
+		loggerx.Global().Info("backfill transfer prices", zap.Int("valued", valued), zap.String("transaction_hash", last.TransactionHash))
+	}
+}
+
+func filterPriceBackfill(sql *gorm.DB, options PriceBackfillOptions) *gorm.DB {
+	if len(options.Networks) > 0 {
+		sql = sql.Where("network IN ?", options.Networks)
+	}
+
+	if !options.Since.IsZero() {
+		sql = sql.Where("timestamp >= ?", options.Since)
+	}
+
+	if !options.Until.IsZero() {
+		sql = sql.Where("timestamp < ?", options.Until)
+	}
+
+	return sql
+}
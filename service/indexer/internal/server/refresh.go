@@ -0,0 +1,24 @@
+package server
+
+import (
+	"context"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/mq"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"go.uber.org/zap"
+)
+
+// publishRefresh broadcasts the newly written notes of message to the hubs, failures are only logged
+// since the notes are already stored, and subscribers can catch up from their cursors
+func (s *Server) publishRefresh(ctx context.Context, message *protocol.Message, transactions []model.Transaction) {
+	// Backfilled notes are historical, and the index command runs without the queue
+	if s.queue == nil || message.BlockNumberTo > 0 || len(transactions) == 0 {
+		return
+	}
+
+	if err := mq.PublishRefresh(ctx, s.queue, message.Address, message.Network, transactions); err != nil {
+		loggerx.Global().Error("failed to publish refresh message", zap.Error(err), zap.String("address", message.Address), zap.String("network", message.Network))
+	}
+}
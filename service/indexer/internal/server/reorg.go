@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+)
+
+// reindex publishes a message to index an address again after its transactions are rolled back by reorg
+func (s *Server) reindex(ctx context.Context, address, network string) error {
+	messageData, err := json.Marshal(&protocol.Message{
+		Address: address,
+		Network: network,
+		Reorg:   true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.queue.Publish(ctx, protocol.ExchangeJob, protocol.IndexerWorkRoutingKey, messageData)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	configx "github.com/naturalselectionlabs/pregod/common/config"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/mq"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReindex(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	queue, err := mq.Dial(&configx.MQ{Driver: configx.MQDriverMemory}, nil)
+	assert.NoError(t, err)
+
+	binding := mq.Binding{
+		Exchange:   protocol.ExchangeJob,
+		RoutingKey: protocol.IndexerWorkRoutingKey,
+		Queue:      protocol.IndexerWorkQueue,
+	}
+
+	deliveryCh, err := queue.Consume(ctx, binding)
+	assert.NoError(t, err)
+
+	server := &Server{queue: queue}
+
+	const address = "0x000000000000000000000000000000000000a11c"
+
+	assert.NoError(t, server.reindex(ctx, address, protocol.NetworkEthereum))
+
+	var message protocol.Message
+
+	select {
+	case delivery := <-deliveryCh:
+		assert.NoError(t, json.Unmarshal(delivery.Body, &message))
+		assert.NoError(t, queue.Ack(ctx, delivery))
+	case <-ctx.Done():
+		t.Fatal("reindex message not published")
+	}
+
+	assert.Equal(t, address, message.Address)
+	assert.True(t, message.Reorg)
+
+	// An address receiving transfers keeps its nonce across the reorg, the rolled back notes are built again anyway
+	addressStatus := model.Address{NonceMap: map[string]int64{protocol.NetworkEthereum: 7}}
+
+	assert.False(t, indexed(&message, addressStatus, 7))
+	assert.True(t, indexed(&protocol.Message{Address: address, Network: protocol.NetworkEthereum}, addressStatus, 7))
+	assert.False(t, indexed(&protocol.Message{Address: address, Network: protocol.NetworkEthereum}, addressStatus, 8))
+}
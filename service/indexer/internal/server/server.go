@@ -17,17 +17,20 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/naturalselectionlabs/pregod/common/cache"
 	"github.com/naturalselectionlabs/pregod/common/command"
+	configx "github.com/naturalselectionlabs/pregod/common/config"
 	"github.com/naturalselectionlabs/pregod/common/database"
 	"github.com/naturalselectionlabs/pregod/common/database/model"
 	"github.com/naturalselectionlabs/pregod/common/database/model/metadata"
 	"github.com/naturalselectionlabs/pregod/common/databeat"
 	"github.com/naturalselectionlabs/pregod/common/ethclientx"
 	"github.com/naturalselectionlabs/pregod/common/metadata_url"
+	"github.com/naturalselectionlabs/pregod/common/mq"
 	"github.com/naturalselectionlabs/pregod/common/protocol"
 	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
 	"github.com/naturalselectionlabs/pregod/common/utils/opentelemetry"
 	"github.com/naturalselectionlabs/pregod/common/utils/shedlock"
 	"github.com/naturalselectionlabs/pregod/internal/allowlist"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/backfill"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/config"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/datasource"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/datasource/alchemy"
@@ -45,7 +48,10 @@ import (
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/datasource/zksync"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/datasource_asset"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/datasource_asset/nftscan"
-	rabbitmqx "github.com/naturalselectionlabs/pregod/service/indexer/internal/rabbitmq"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/merge"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/registry"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/reorg"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/scheduler"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker/build_transactions"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker/collectible/marketplace"
@@ -57,6 +63,7 @@ import (
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker/exchange/swap"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker/governance/snapshot"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker/metaverse"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker/price"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker/social/crossbell"
 	lens_worker "github.com/naturalselectionlabs/pregod/service/indexer/internal/worker/social/lens"
 	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker/transaction"
@@ -69,6 +76,7 @@ import (
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -80,14 +88,36 @@ const (
 	// 我们应当认为大多数任务都是刷不出来内容的、快速的，所以这个值应当是足够的
 	// 此时即使任务堆积、刷新慢，至少 indexer 还有复活的机会并缓慢消耗掉任务；而不是死掉然后任务丢掉
 	MAX_CONCURRENT_JOBS = 50
+
+	// Only the latest transactions are built for a message, backfill builds the older ones
+	MAX_LATEST_TRANSACTIONS = 500
+
+	// Failed messages are published again after RETRY_BASE_DELAY * 2^retry, and dead-lettered after MAX_RETRY
+	MAX_RETRY        = 5
+	RETRY_BASE_DELAY = 10 * time.Second
+	RETRY_MAX_DELAY  = 5 * time.Minute
+
+	// In-flight jobs are waited for DRAIN_TIMEOUT on shutdown, then cancelled and abandoned after DRAIN_CANCEL_TIMEOUT
+	DRAIN_TIMEOUT        = 20 * time.Second
+	DRAIN_CANCEL_TIMEOUT = 5 * time.Second
 )
 
+// errLocked means the address is being indexed by another job, the message is dropped
+var errLocked = errors.New("locked")
+
 type Server struct {
 	config           *config.Config
 	datasources      []datasource.Datasource
 	datasourcesAsset []datasource_asset.Datasource
 	workers          []worker.Worker
+	graph            *worker.Graph
+	registry         *registry.Registry
 	employer         *shedlock.Employer
+	queue            mq.Queue
+	scheduler        *scheduler.Scheduler
+	backfill         *backfill.Backfill
+	reorg            *reorg.Reorg
+	jobs             *jobTracker
 }
 
 var _ command.Interface = &Server{}
@@ -114,12 +144,60 @@ func (s *Server) Initialize() (err error) {
 		)),
 	))
 
-	databaseClient, err := database.Dial(s.config.Postgres.String(), false)
-	if err != nil {
+	if err := s.InitializeIndex(); err != nil {
 		return err
 	}
 
-	database.ReplaceGlobal(databaseClient)
+	if err := s.dialQueue(); err != nil {
+		return err
+	}
+
+	schedulerConfig := configx.Scheduler{}
+	if s.config.Scheduler != nil {
+		schedulerConfig = *s.config.Scheduler
+	}
+
+	if schedulerConfig.Concurrency == 0 {
+		schedulerConfig.Concurrency = MAX_CONCURRENT_JOBS
+	}
+
+	s.scheduler = scheduler.New(&schedulerConfig)
+
+	s.backfill = backfill.New(s.config.Backfill, s.employer, s.backfillWindow)
+
+	for _, internalWorker := range s.workers {
+		for _, job := range internalWorker.Jobs() {
+			if err := s.employer.AddJob(job.Name(), job.Spec(), job.Timeout(), worker.NewCronJob(s.employer, job)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// asset
+	// alchemyAssetDatasource, err := alchemy_asset.New(s.config.RPC)
+	// if err != nil {
+	//	return err
+	//}
+
+	// NFTScan datasource
+	if s.config.NFTScan != nil {
+		datasourceNFTScan, err := nftscan.New(context.TODO(), *s.config.NFTScan)
+		if err != nil {
+			return fmt.Errorf("create nftscan datasource: %w", err)
+		}
+
+		s.datasourcesAsset = append(s.datasourcesAsset, datasourceNFTScan)
+	}
+
+	return nil
+}
+
+// InitializeIndex dials the databases, redis and the nodes, and initializes the datasources and workers,
+// it is enough for indexing an address once without the queue
+func (s *Server) InitializeIndex() (err error) {
+	if err := s.InitializeDatabase(); err != nil {
+		return err
+	}
 
 	ethDbClient, err := database.Dial(s.config.EthereumEtl.String(), false)
 	if err != nil {
@@ -128,20 +206,14 @@ func (s *Server) Initialize() (err error) {
 
 	database.ReplaceEthDb(ethDbClient)
 
-	redisClient, err := cache.Dial(s.config.Redis)
-	if err != nil {
+	if err := s.initializeCache(); err != nil {
 		return err
 	}
 
-	cache.ReplaceGlobal(redisClient)
+	s.registry = registry.New(s.config.Registry)
 
 	metadata_url.New(s.config.RPC.IPFS.IO)
 
-	err = rabbitmqx.InitializeMQ(s.config.RabbitMQ)
-	if err != nil {
-		return err
-	}
-
 	ethereumClientMap, err := ethclientx.Dial(s.config.RPC, protocol.EthclientNetworks)
 	if err != nil {
 		return err
@@ -222,10 +294,18 @@ func (s *Server) Initialize() (err error) {
 		transaction.New(),
 		metaverse.New(),
 		friendtech.New(),
+		price.New(),
+	}
+
+	if s.graph, err = worker.NewGraph(s.workers); err != nil {
+		return fmt.Errorf("build worker graph: %w", err)
 	}
 
 	s.employer = shedlock.New()
 
+	// The finality of the upserted transactions, the checks only run with the queue
+	s.reorg = reorg.New(s.config.Reorg, s.employer, s.reindex)
+
 	for _, internalWorker := range s.workers {
 		loggerx.Global().Info("start initializing worker", zap.String("worker", internalWorker.Name()))
 
@@ -236,32 +316,56 @@ func (s *Server) Initialize() (err error) {
 		}
 
 		loggerx.Global().Info("initialize worker completion", zap.String("worker", internalWorker.Name()), zap.Duration("duration", time.Since(startTime)))
+	}
 
-		if internalWorker.Jobs() == nil {
-			continue
-		}
+	return nil
+}
 
-		for _, job := range internalWorker.Jobs() {
-			if err := s.employer.AddJob(job.Name(), job.Spec(), job.Timeout(), worker.NewCronJob(s.employer, job)); err != nil {
-				return err
-			}
-		}
+// InitializeDatabase dials postgres, it is enough for the commands which only operate the database
+func (s *Server) InitializeDatabase() error {
+	databaseClient, err := database.Dial(s.config.Postgres.String(), false)
+	if err != nil {
+		return err
 	}
 
-	// asset
-	// alchemyAssetDatasource, err := alchemy_asset.New(s.config.RPC)
-	// if err != nil {
-	//	return err
-	//}
+	database.ReplaceGlobal(databaseClient)
 
-	// NFTScan datasource
-	if s.config.NFTScan != nil {
-		datasourceNFTScan, err := nftscan.New(context.TODO(), *s.config.NFTScan)
-		if err != nil {
-			return fmt.Errorf("create nftscan datasource: %w", err)
-		}
+	return nil
+}
 
-		s.datasourcesAsset = append(s.datasourcesAsset, datasourceNFTScan)
+// InitializeQueue dials redis and the message bus, it is enough for the commands which only operate queues
+func (s *Server) InitializeQueue() error {
+	if err := s.initializeCache(); err != nil {
+		return err
+	}
+
+	return s.dialQueue()
+}
+
+func (s *Server) initializeCache() error {
+	redisClient, err := cache.Dial(s.config.Redis)
+	if err != nil {
+		return err
+	}
+
+	cache.ReplaceGlobal(redisClient)
+
+	return nil
+}
+
+// dialQueue dials the message bus, the redis driver requires redis to be initialized
+func (s *Server) dialQueue() (err error) {
+	if s.queue, err = mq.Dial(s.config.MQ, s.config.RabbitMQ); err != nil {
+		return fmt.Errorf("dial mq: %w", err)
+	}
+
+	// Keep the dead letters until they are replayed
+	if err := s.queue.Declare(context.Background(), mq.Binding{
+		Exchange:   protocol.ExchangeJob,
+		RoutingKey: protocol.IndexerDeadLetterRoutingKey,
+		Queue:      protocol.IndexerDeadLetterQueue,
+	}); err != nil {
+		return fmt.Errorf("declare %s: %w", protocol.IndexerDeadLetterQueue, err)
 	}
 
 	return nil
@@ -276,76 +380,209 @@ func (s *Server) Run() error {
 
 	defer s.employer.Stop()
 
-	go func() {
-		waitChan := make(chan struct{}, MAX_CONCURRENT_JOBS)
-		for {
-			delivery := <-rabbitmqx.GetRabbitmqDelivery()
-			if len(delivery.Body) == 0 {
-				loggerx.Global().Info("wait indexer mq reconnected")
-				time.Sleep(3 * time.Second)
+	// Cancelling ctx stops consuming, and jobCtx is passed to the datasources and workers
+	ctx, stopConsuming := context.WithCancel(context.Background())
 
-				continue
-			}
+	defer stopConsuming()
+
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+
+	defer cancelJobs()
+
+	queueWork := s.workQueue()
+	if protocol.WorkQ2RoutingKey[queueWork] == "" {
+		return fmt.Errorf("invalid work queue: %s", queueWork)
+	}
 
+	deliveryCh, err := s.queue.Consume(ctx, mq.Binding{
+		Exchange:   protocol.ExchangeJob,
+		RoutingKey: protocol.WorkQ2RoutingKey[queueWork],
+		Queue:      queueWork,
+	})
+	if err != nil {
+		return fmt.Errorf("consume %s: %w", queueWork, err)
+	}
+
+	deliveryAssetCh, err := s.queue.Consume(ctx, mq.Binding{
+		Exchange:   protocol.ExchangeJob,
+		RoutingKey: protocol.IndexerAssetRoutingKey,
+		Queue:      protocol.IndexerAssetQueue,
+	})
+	if err != nil {
+		return fmt.Errorf("consume %s: %w", protocol.IndexerAssetQueue, err)
+	}
+
+	var dispatchers sync.WaitGroup
+
+	dispatchers.Add(2)
+
+	if s.config.Backfill != nil && s.config.Backfill.Enabled {
+		dispatchers.Add(1)
+
+		go func() {
+			defer dispatchers.Done()
+
+			s.backfill.Run(ctx)
+		}()
+	}
+
+	if s.config.Reorg != nil && s.config.Reorg.Enabled {
+		dispatchers.Add(1)
+
+		go func() {
+			defer dispatchers.Done()
+
+			s.reorg.Run(ctx)
+		}()
+	}
+
+	go func() {
+		defer dispatchers.Done()
+
+		for delivery := range deliveryCh {
 			message := protocol.Message{}
 			if err := json.Unmarshal(delivery.Body, &message); err != nil {
 				loggerx.Global().Error("failed to unmarshal indexer delivery message", zap.Error(err))
 
+				// It will never succeed
+				s.publishDeadLetter(delivery, protocol.WorkQ2RoutingKey[queueWork], err)
+
 				continue
 			}
 
-			go func() {
-				waitChan <- struct{}{}
-				defer func() {
-					<-waitChan
-				}()
-
-				if err := s.handle(context.Background(), &message); err != nil {
+			s.submit(delivery, &message, laneOf(delivery, &message), indexerLockKey(&message), func() {
+				err := s.handle(jobCtx, &message)
+				if err != nil && !errors.Is(err, errLocked) {
 					loggerx.Global().Error("failed to handle message", zap.Error(err), zap.String("address", message.Address), zap.String("network", message.Network))
 				}
-			}()
+
+				s.settle(delivery, protocol.WorkQ2RoutingKey[queueWork], err)
+			})
 		}
 	}()
 
 	go func() {
-		for {
-			delivery := <-rabbitmqx.GetRabbitmqAssetDelivery()
-			if len(delivery.Body) == 0 {
-				loggerx.Global().Info("wait indexer mq reconnected")
-				time.Sleep(3 * time.Second)
-				continue
-			}
+		defer dispatchers.Done()
 
+		for delivery := range deliveryAssetCh {
 			message := protocol.Message{}
 			if err := json.Unmarshal(delivery.Body, &message); err != nil {
 				loggerx.Global().Error("failed to unmarshal indexer asset delivery message", zap.Error(err))
 
+				s.publishDeadLetter(delivery, protocol.IndexerAssetRoutingKey, err)
+
 				continue
 			}
 
-			go func() {
-				if err := s.handleAsset(context.Background(), &message); err != nil {
+			s.submit(delivery, &message, scheduler.LaneBackground, indexerAssetLockKey(&message), func() {
+				err := s.handleAsset(jobCtx, &message)
+				if err != nil && !errors.Is(err, errLocked) {
 					loggerx.Global().Error("failed to handle asset message", zap.Error(err), zap.String("address", message.Address), zap.String("network", message.Network))
 				}
-			}()
+
+				s.settle(delivery, protocol.IndexerAssetRoutingKey, err)
+			})
 		}
 	}()
 
+	go s.watchReload(ctx)
+
 	stopchan := make(chan os.Signal, 1)
 	signal.Notify(stopchan, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
-	<-stopchan
+
+	loggerx.Global().Info("stop consuming", zap.String("signal", (<-stopchan).String()))
+
+	// The buffered deliveries are requeued by the mq driver, and the pending jobs by the scheduler
+	stopConsuming()
+
+	loggerx.Global().Info("pending jobs requeued", zap.Int("jobs", s.scheduler.Stop()))
+
+	dispatchers.Wait()
+
+	abandonedJobs := s.drain(cancelJobs)
+
+	if err := s.queue.Close(); err != nil {
+		loggerx.Global().Error("failed to close mq", zap.Error(err))
+	}
+
+	loggerx.Global().Info("drain jobs completion", zap.Int("abandoned", len(abandonedJobs)))
 
 	return nil
 }
 
+// watchReload reloads the registry of datasources and workers on SIGHUP until ctx is done,
+// the running jobs keep the components they started with
+func (s *Server) watchReload(ctx context.Context) {
+	reloadchan := make(chan os.Signal, 1)
+	signal.Notify(reloadchan, syscall.SIGHUP)
+
+	defer signal.Stop(reloadchan)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reloadchan:
+		}
+
+		internalConfig, err := config.Reload()
+		if err != nil {
+			loggerx.Global().Error("failed to reload config", zap.Error(err))
+
+			continue
+		}
+
+		s.registry.Reload(internalConfig.Registry)
+
+		loggerx.Global().Info("registry reloaded")
+	}
+}
+
+// submit schedules the handling of a delivery, the delivery is requeued if the job is dropped on shutdown
+func (s *Server) submit(delivery *mq.Delivery, message *protocol.Message, lane scheduler.Lane, lockKey string, handle func()) {
+	s.jobs.add()
+
+	s.scheduler.Submit(&scheduler.Job{
+		Lane:    lane,
+		Address: message.Address,
+		Network: message.Network,
+		Run: func() {
+			defer s.jobs.done(delivery)
+
+			s.jobs.start(delivery, message, lockKey)
+
+			handle()
+		},
+		Drop: func() {
+			defer s.jobs.done(delivery)
+
+			if err := s.queue.Nack(context.Background(), delivery, true); err != nil {
+				loggerx.Global().Error("failed to requeue delivery", zap.Error(err), zap.String("queue", delivery.Queue))
+			}
+		},
+	})
+}
+
+func laneOf(delivery *mq.Delivery, message *protocol.Message) scheduler.Lane {
+	switch {
+	case message.Refresh:
+		return scheduler.LaneRefresh
+	case delivery.Queue == protocol.IndexerWorkQueueIO:
+		return scheduler.LaneIO
+	default:
+		return scheduler.LaneBackground
+	}
+}
+
 func (s *Server) handle(ctx context.Context, message *protocol.Message) (err error) {
-	lockKey := fmt.Sprintf("indexer:%v:%v", message.Address, message.Network)
+	lockKey := indexerLockKey(message)
 
 	if !s.employer.DoLock(lockKey, 2*time.Minute) {
-		return fmt.Errorf("%v lock", lockKey)
+		return fmt.Errorf("%v: %w", lockKey, errLocked)
 	}
 
-	cctx, cancel := context.WithCancel(context.Background())
+	// Stop renewing the lock once the job is cancelled
+	cctx, cancel := context.WithCancel(ctx)
 	go func(cctx context.Context) {
 		for {
 			time.Sleep(time.Second)
@@ -378,9 +615,9 @@ func (s *Server) handle(ctx context.Context, message *protocol.Message) (err err
 			"transactions": len(transactions),
 		})
 
-		// upsert address status
+		// upsert address status, the nonce is recorded once the address is indexed, so a failed attempt isn't skipped by its retry
 		addressStatus.Address = message.Address
-		go s.upsertAddress(ctx, addressStatus, len(transactions) != 0)
+		go s.upsertAddress(ctx, addressStatus, err == nil && len(transactions) != 0)
 	}()
 
 	// convert address to lowercase
@@ -400,18 +637,17 @@ func (s *Server) handle(ctx context.Context, message *protocol.Message) (err err
 		// get address status
 		addressStatus, _ = database.GetAddress(message.Address)
 
-		nonce, err = ethclient.NonceAt(context.Background(), common.HexToAddress(message.Address), nil)
-		if err == nil {
-			if addressStatus.NonceMap[message.Network] == int64(nonce) {
-				return nil
-			}
+		nonce, err = ethclient.NonceAt(ctx, common.HexToAddress(message.Address), nil)
+		if err == nil && indexed(message, addressStatus, nonce) {
+			return nil
 		}
 	}
 
 	loggerx.Global().Info("start indexing data", zap.String("address", message.Address), zap.String("network", message.Network))
 
-	// Get the time of the latest data for this address and network
-	if message.Network != protocol.NetworkEthereum || addressStatus.NonceMap[message.Network] != 0 {
+	// Get the time of the latest data for this address and network, the one of a rolled back address is before the orphaned blocks.
+	// A retry of the failed datasources keeps the time of its failed attempt, the others have upserted the later data.
+	if len(message.Datasources) == 0 && (message.Reorg || message.Network != protocol.NetworkEthereum || addressStatus.NonceMap[message.Network] != 0) {
 		var result model.Transaction
 
 		if err := database.Global().
@@ -434,51 +670,127 @@ func (s *Server) handle(ctx context.Context, message *protocol.Message) (err err
 		message.Network: int64(nonce),
 	}
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+	datasources, err := filterByName(s.datasources, message.Datasources, datasource.Datasource.Name)
+	if err != nil {
+		return err
+	}
+
+	transactions, datasourceError := s.handleDatasources(ctx, message, datasources)
+
+	// Don't upsert the partial results of a cancelled job
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := s.handleWorkers(ctx, message, transactions); err != nil {
+		return err
+	}
+
+	// Retry the message for the failed datasources only, the others have been upserted
+	return datasourceError
+}
+
+// partialError is returned by handleDatasources if some of the datasources fail, a retry of the message gets the
+// transactions from Datasources since Timestamp and BlockNumber
+type partialError struct {
+	Datasources []string
+	Timestamp   time.Time
+	BlockNumber int64
+
+	err error
+}
+
+func (e *partialError) Error() string {
+	return e.err.Error()
+}
 
-	for _, ds := range s.datasources {
+func (e *partialError) Unwrap() error {
+	return e.err
+}
+
+// handleDatasources gets the transactions of the message from the datasources concurrently, and merges the ones
+// returned by several datasources by the source precedence of the network. The failed datasources are skipped
+// and returned as a partialError along with the others' transactions.
+func (s *Server) handleDatasources(ctx context.Context, message *protocol.Message, datasources []datasource.Datasource) ([]model.Transaction, error) {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+
+		failed          []string
+		datasourceError error
+	)
+
+	// Indexed by datasource, so that the merging doesn't depend on which one returns first
+	results := make([][]model.Transaction, len(datasources))
+
+	for index, ds := range datasources {
 		wg.Add(1)
-		go func(message *protocol.Message, datasource datasource.Datasource) {
+		go func(index int, message *protocol.Message, datasource datasource.Datasource) {
 			defer wg.Done()
-			for _, network := range datasource.Networks() {
-				if network == message.Network {
-					loggerx.Global().Info("start datasource", zap.String("datasource", datasource.Name()), zap.String("address", message.Address))
-					startTime := time.Now()
-
-					// handle
-					internalTransactions, err := datasource.Handle(ctx, message)
-
-					// log
-					loggerx.Global().Info("datasource completion", zap.String("datasource", datasource.Name()), zap.String("address", message.Address), zap.Int("transactions", len(transactions)), zap.Duration("duration", time.Since(startTime)))
-
-					// Avoid blocking indexed workers
-					if err != nil {
-						loggerx.Global().Error("datasource handle failed", zap.Error(err), zap.String("network", message.Network), zap.String("address", message.Address), zap.String("datasource", datasource.Name()))
-
-						continue
-					}
-					mu.Lock()
-					transactions = append(transactions, internalTransactions...)
-					mu.Unlock()
-				}
+
+			enabled, timeout := s.registry.Datasource(datasource, message.Network)
+			if !enabled {
+				return
 			}
-		}(message, ds)
+
+			ctx := ctx
+
+			if timeout > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			loggerx.Global().Info("start datasource", zap.String("datasource", datasource.Name()), zap.String("address", message.Address))
+			startTime := time.Now()
+
+			// handle
+			internalTransactions, err := datasource.Handle(ctx, message)
+
+			// log
+			loggerx.Global().Info("datasource completion", zap.String("datasource", datasource.Name()), zap.String("address", message.Address), zap.Int("transactions", len(internalTransactions)), zap.Duration("duration", time.Since(startTime)))
+
+			// Avoid blocking indexed workers
+			if err != nil {
+				loggerx.Global().Error("datasource handle failed", zap.Error(err), zap.String("network", message.Network), zap.String("address", message.Address), zap.String("datasource", datasource.Name()))
+
+				mu.Lock()
+				failed = append(failed, datasource.Name())
+				datasourceError = multierr.Append(datasourceError, fmt.Errorf("datasource %s: %w", datasource.Name(), err))
+				mu.Unlock()
+
+				return
+			}
+
+			results[index] = internalTransactions
+		}(index, message, ds)
 
 	}
 	wg.Wait()
 
-	return s.handleWorkers(ctx, message, transactions)
+	transactions := merge.Merge(results, s.registry.Precedence)
+
+	if len(failed) == 0 {
+		return transactions, nil
+	}
+
+	return transactions, &partialError{
+		Datasources: failed,
+		Timestamp:   message.Timestamp,
+		BlockNumber: message.BlockNumber,
+		err:         datasourceError,
+	}
 }
 
 func (s *Server) handleAsset(ctx context.Context, message *protocol.Message) (err error) {
-	lockKey := fmt.Sprintf("indexer_asset:%v:%v", message.Address, message.Network)
+	lockKey := indexerAssetLockKey(message)
 
 	if !s.employer.DoLock(lockKey, 2*time.Minute) {
-		return fmt.Errorf("%v lock", lockKey)
+		return fmt.Errorf("%v: %w", lockKey, errLocked)
 	}
 
-	cctx, cancel := context.WithCancel(context.Background())
+	cctx, cancel := context.WithCancel(ctx)
 	go func(cctx context.Context) {
 		for {
 			time.Sleep(time.Second)
@@ -509,18 +821,22 @@ func (s *Server) handleAsset(ctx context.Context, message *protocol.Message) (er
 	var assets []model.Asset
 
 	for _, datasource := range s.datasourcesAsset {
-		for _, network := range datasource.Networks() {
-			if network == message.Network {
-				internalAssets, err := datasource.Handle(ctx, message)
-				// Avoid blocking indexed workers
-				if err != nil {
-					loggerx.Global().Error("datasource handle failed", zap.Error(err))
-					continue
-				}
+		if enabled, _ := s.registry.Datasource(datasource, message.Network); !enabled {
+			continue
+		}
 
-				assets = append(assets, internalAssets...)
-			}
+		internalAssets, err := datasource.Handle(ctx, message)
+		// Avoid blocking indexed workers
+		if err != nil {
+			loggerx.Global().Error("datasource handle failed", zap.Error(err))
+			continue
 		}
+
+		assets = append(assets, internalAssets...)
+	}
+
+	if len(assets) == 0 {
+		return nil
 	}
 
 	// set db
@@ -535,6 +851,128 @@ func (s *Server) handleAsset(ctx context.Context, message *protocol.Message) (er
 	return nil
 }
 
+// settle acknowledges the delivery once its message has been handled, a failed message is published again
+// with an increased retry count after an exponential delay, and goes to the dead letter queue past MAX_RETRY.
+// The delay is held by the message bus, so the delivery is acknowledged right away and the job slot is released.
+func (s *Server) settle(delivery *mq.Delivery, routingKey string, handleErr error) {
+	if handleErr == nil || errors.Is(handleErr, errLocked) {
+		if err := s.queue.Ack(context.Background(), delivery); err != nil {
+			loggerx.Global().Error("failed to ack delivery", zap.Error(err), zap.String("queue", delivery.Queue))
+		}
+
+		return
+	}
+
+	// Handlers modify the message, so start from the delivered one
+	message := protocol.Message{}
+	if err := json.Unmarshal(delivery.Body, &message); err != nil {
+		s.publishDeadLetter(delivery, routingKey, err)
+
+		return
+	}
+
+	if message.Retry >= MAX_RETRY {
+		s.publishDeadLetter(delivery, routingKey, handleErr)
+
+		return
+	}
+
+	delay := retryDelay(message.Retry)
+
+	message.Retry++
+
+	// The datasources which succeeded have been upserted
+	var partial *partialError
+	if errors.As(handleErr, &partial) {
+		message.Datasources = partial.Datasources
+		message.Timestamp, message.BlockNumber = partial.Timestamp, partial.BlockNumber
+	}
+
+	messageData, err := json.Marshal(&message)
+	if err == nil {
+		err = s.queue.PublishDelayed(context.Background(), protocol.ExchangeJob, routingKey, messageData, delay)
+	}
+
+	if err != nil {
+		loggerx.Global().Error("failed to publish retry message", zap.Error(err), zap.String("address", message.Address), zap.String("network", message.Network))
+
+		_ = s.queue.Nack(context.Background(), delivery, true)
+
+		return
+	}
+
+	if err := s.queue.Ack(context.Background(), delivery); err != nil {
+		loggerx.Global().Error("failed to ack delivery", zap.Error(err), zap.String("queue", delivery.Queue))
+	}
+}
+
+// publishDeadLetter moves the delivery to the dead letter queue, it is requeued if the dead letter can't be published
+func (s *Server) publishDeadLetter(delivery *mq.Delivery, routingKey string, handleErr error) {
+	ctx := context.Background()
+
+	deadLetter := protocol.DeadLetterMessage{
+		RoutingKey: routingKey,
+		Error:      handleErr.Error(),
+		Timestamp:  time.Now(),
+	}
+
+	// The malformed message is kept as is in the error
+	if err := json.Unmarshal(delivery.Body, &deadLetter.Message); err != nil {
+		deadLetter.Error = fmt.Sprintf("%s: %s", handleErr, delivery.Body)
+	}
+
+	deadLetterData, err := json.Marshal(&deadLetter)
+	if err == nil {
+		err = s.queue.Publish(ctx, protocol.ExchangeJob, protocol.IndexerDeadLetterRoutingKey, deadLetterData)
+	}
+
+	if err != nil {
+		loggerx.Global().Error("failed to publish dead letter", zap.Error(err), zap.String("queue", delivery.Queue))
+
+		_ = s.queue.Nack(ctx, delivery, true)
+
+		return
+	}
+
+	loggerx.Global().Warn("message moved to dead letter queue", zap.String("queue", delivery.Queue), zap.String("address", deadLetter.Message.Address), zap.String("network", deadLetter.Message.Network), zap.String("error", deadLetter.Error))
+
+	if err := s.queue.Ack(ctx, delivery); err != nil {
+		loggerx.Global().Error("failed to ack delivery", zap.Error(err), zap.String("queue", delivery.Queue))
+	}
+}
+
+// indexed reports whether the address of message has been indexed at nonce. A rolled back address is indexed again
+// regardless, since the incoming transfers don't change the nonce, and so is a retry, since its first attempt
+// has passed the check.
+func indexed(message *protocol.Message, addressStatus model.Address, nonce uint64) bool {
+	return !message.Reorg && message.Retry == 0 && len(message.Datasources) == 0 && addressStatus.NonceMap[message.Network] == int64(nonce)
+}
+
+// crawled reports whether transaction is indexed by the crawlers, it's not written by the indexers
+func crawled(transaction model.Transaction) bool {
+	return allowlist.CrawlerList.Contains(transaction.AddressTo) && strings.EqualFold(transaction.Network, allowlist.CrawlerList.Get(transaction.AddressTo))
+}
+
+func indexerLockKey(message *protocol.Message) string {
+	return fmt.Sprintf("indexer:%v:%v", message.Address, message.Network)
+}
+
+func indexerAssetLockKey(message *protocol.Message) string {
+	return fmt.Sprintf("indexer_asset:%v:%v", message.Address, message.Network)
+}
+
+func retryDelay(retry int) time.Duration {
+	if retry >= 16 {
+		return RETRY_MAX_DELAY
+	}
+
+	if delay := RETRY_BASE_DELAY << retry; delay < RETRY_MAX_DELAY {
+		return delay
+	}
+
+	return RETRY_MAX_DELAY
+}
+
 func (s *Server) upsertTransactions(ctx context.Context, message *protocol.Message, tx *gorm.DB, transactions []model.Transaction) (err error) {
 	tracer := otel.Tracer("indexer")
 	_, span := tracer.Start(ctx, "indexer:upsertTransactions")
@@ -546,11 +984,12 @@ func (s *Server) upsertTransactions(ctx context.Context, message *protocol.Messa
 	var (
 		transfers           []model.Transfer
 		updatedTransactions []model.Transaction
+		createdTransactions []model.Transaction
 	)
 
 	for _, transaction := range transactions {
 		// remove tx which has been indexed in crawler
-		if allowlist.CrawlerList.Contains(transaction.AddressTo) && strings.EqualFold(transaction.Network, allowlist.CrawlerList.Get(transaction.AddressTo)) {
+		if crawled(transaction) {
 			continue
 		}
 
@@ -565,14 +1004,35 @@ func (s *Server) upsertTransactions(ctx context.Context, message *protocol.Messa
 			transfers = append(transfers, transfer)
 		}
 
+		transaction.Finality = s.reorg.Finality(transaction.Network, transaction.BlockNumber)
+
 		updatedTransactions = append(updatedTransactions, transaction)
 	}
 
+	// The finality of the stored transactions is only advanced by the reorg checks, since the replicas
+	// which don't know the heads index the transactions as pending
+	onConflict, err := database.UpdateAllExcept(tx, &model.Transaction{}, "finality", "confirmations")
+	if err != nil {
+		tx.Rollback()
+
+		return err
+	}
+
 	for _, ts := range lo.Chunk(updatedTransactions, dbChunkSize) {
+		var created []model.Transaction
+
+		if created, err = database.CreatedTransactions(tx, ts); err != nil {
+			loggerx.Global().Error("failed to get created transactions", zap.Error(err), zap.String("network", message.Network), zap.String("address", message.Address))
+
+			tx.Rollback()
+
+			return err
+		}
+
+		createdTransactions = append(createdTransactions, created...)
+
 		if err = tx.
-			Clauses(clause.OnConflict{
-				UpdateAll: true,
-			}).
+			Clauses(onConflict).
 			Create(ts).Error; err != nil {
 			loggerx.Global().Error("failed to upsert transactions", zap.Error(err), zap.String("network", message.Network), zap.String("address", message.Address))
 
@@ -597,17 +1057,40 @@ func (s *Server) upsertTransactions(ctx context.Context, message *protocol.Messa
 		}
 	}
 
-	return tx.Commit().Error
+	if err = tx.Commit().Error; err != nil {
+		return err
+	}
+
+	s.publishRefresh(ctx, message, createdTransactions)
+
+	return nil
 }
 
-func getTransactionsMap(transactions []model.Transaction) map[string]model.Transaction {
-	transactionsMap := make(map[string]model.Transaction)
+// handleWorker runs a worker within timeout and logs its result, it returns no transactions if the worker fails
+func (s *Server) handleWorker(ctx context.Context, message *protocol.Message, internalWorker worker.Worker, transactions []model.Transaction, timeout time.Duration, epoch, size int) []model.Transaction {
+	if timeout > 0 {
+		var cancel context.CancelFunc
 
-	for _, t := range transactions {
-		transactionsMap[t.Hash] = t
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	return transactionsMap
+	// log
+	loggerx.Global().Info("start worker", zap.String("worker", internalWorker.Name()), zap.String("network", message.Network), zap.String("address", message.Address), zap.Int("epoch", epoch), zap.Int("size", size))
+	startTime := time.Now()
+
+	internalTransactions, err := internalWorker.Handle(ctx, message, transactions)
+
+	// log
+	loggerx.Global().Info("worker completion", zap.String("worker", internalWorker.Name()), zap.Int("transactions", len(internalTransactions)), zap.String("address", message.Address), zap.Duration("duration", time.Since(startTime)))
+
+	if err != nil {
+		loggerx.Global().Error("worker handle failed", zap.Error(err), zap.String("worker", internalWorker.Name()), zap.String("network", message.Network))
+
+		return nil
+	}
+
+	return internalTransactions
 }
 
 func transactionsMap2Array(transactionsMap map[string]model.Transaction) []model.Transaction {
@@ -625,6 +1108,17 @@ func (s *Server) handleWorkers(ctx context.Context, message *protocol.Message, t
 
 	defer opentelemetry.Log(span, message, transactions, err)
 
+	result := s.buildTransactions(ctx, message, transactions, s.graph.Levels, MAX_LATEST_TRANSACTIONS)
+
+	// Open a database transaction
+	tx := database.Global().WithContext(ctx).Begin()
+
+	return s.upsertTransactions(ctx, message, tx, result)
+}
+
+// buildTransactions cleans the transactions with the levels of workers, and drops the duplicated transactions and transfers,
+// it stops once limit transactions are built, and builds all of them if limit is 0
+func (s *Server) buildTransactions(ctx context.Context, message *protocol.Message, transactions []model.Transaction, levels [][]worker.Worker, limit int) []model.Transaction {
 	// Sort, latest -> oldest
 	sort.SliceStable(transactions, func(i, j int) bool {
 		return transactions[i].BlockNumber > transactions[j].BlockNumber
@@ -638,36 +1132,51 @@ func (s *Server) handleWorkers(ctx context.Context, message *protocol.Message, t
 	// Using workers to clean data
 	for epoch, ts := range lo.Chunk(transactions, 500) {
 		transactionsMap := make(map[string]model.Transaction)
-		for _, worker := range s.workers {
-			for _, network := range worker.Networks() {
-				if network == message.Network {
-					// log
-					loggerx.Global().Info("start worker", zap.String("worker", worker.Name()), zap.String("network", message.Network), zap.String("address", message.Address), zap.Int("epoch", epoch), zap.Int("size", len(result)))
-					startTime := time.Now()
 
-					internalTransactions, err := worker.Handle(ctx, message, ts)
+		for _, level := range levels {
+			var (
+				levelWorkers []worker.Worker
+				timeouts     []time.Duration
+			)
 
-					// log
-					loggerx.Global().Info("worker completion", zap.String("worker", worker.Name()), zap.Int("transactions", len(internalTransactions)), zap.String("address", message.Address), zap.Duration("duration", time.Since(startTime)))
+			for _, internalWorker := range level {
+				if enabled, timeout := s.registry.Worker(internalWorker, message.Network); enabled {
+					levelWorkers = append(levelWorkers, internalWorker)
+					timeouts = append(timeouts, timeout)
+				}
+			}
+
+			if len(levelWorkers) == 0 {
+				continue
+			}
 
-					if err != nil {
-						loggerx.Global().Error("worker handle failed", zap.Error(err), zap.String("worker", worker.Name()), zap.String("network", network))
+			// The workers of a level don't depend on each other, so they handle the same transactions concurrently
+			outputs := make([][]model.Transaction, len(levelWorkers))
 
-						continue
-					}
+			var waitGroup sync.WaitGroup
 
-					if len(internalTransactions) == 0 {
-						continue
-					}
+			for index, levelWorker := range levelWorkers {
+				waitGroup.Add(1)
 
-					newTransactionMap := getTransactionsMap(internalTransactions)
-					for _, t := range newTransactionMap {
-						transactionsMap[t.Hash] = t
-					}
+				go func(index int, levelWorker worker.Worker, ts []model.Transaction) {
+					defer waitGroup.Done()
 
-					ts = transactionsMap2Array(transactionsMap)
-				}
+					outputs[index] = s.handleWorker(ctx, message, levelWorker, ts, timeouts[index], epoch, len(result))
+				}(index, levelWorker, worker.CloneTransactions(ts))
 			}
+
+			waitGroup.Wait()
+
+			levelTransactionsMap := worker.MergeOutputs(ts, outputs)
+			if len(levelTransactionsMap) == 0 {
+				continue
+			}
+
+			for _, t := range levelTransactionsMap {
+				transactionsMap[t.Hash] = t
+			}
+
+			ts = transactionsMap2Array(transactionsMap)
 		}
 
 		for _, transaction := range ts {
@@ -704,15 +1213,12 @@ func (s *Server) handleWorkers(ctx context.Context, message *protocol.Message, t
 		}
 
 		// only update the latest 500 data
-		if len(result) >= 500 {
+		if limit > 0 && len(result) >= limit {
 			break
 		}
 	}
 
-	// Open a database transaction
-	tx := database.Global().WithContext(ctx).Begin()
-
-	return s.upsertTransactions(ctx, message, tx, result)
+	return result
 }
 
 func (s *Server) upsertAddress(ctx context.Context, address model.Address, isValid bool) {
@@ -778,8 +1284,18 @@ func (s *Server) upsertAddress(ctx context.Context, address model.Address, isVal
 	}
 }
 
+// workQueue returns the queue consumed by this indexer, defaults to the general work queue
+func (s *Server) workQueue() string {
+	if s.config.RabbitMQ == nil || s.config.RabbitMQ.QueueWork == "" {
+		return protocol.IndexerWorkQueue
+	}
+
+	return s.config.RabbitMQ.QueueWork
+}
+
 func New(config *config.Config) *Server {
 	return &Server{
 		config: config,
+		jobs:   newJobTracker(),
 	}
 }
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/mq"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// recordingQueue settles the deliveries and records the delayed messages instead of publishing them
+type recordingQueue struct {
+	mq.Queue
+
+	delayed []*protocol.Message
+}
+
+func (q *recordingQueue) Publish(context.Context, string, string, []byte) error {
+	return nil
+}
+
+func (q *recordingQueue) PublishDelayed(_ context.Context, _, _ string, body []byte, _ time.Duration) error {
+	var message protocol.Message
+	if err := json.Unmarshal(body, &message); err != nil {
+		return err
+	}
+
+	q.delayed = append(q.delayed, &message)
+
+	return nil
+}
+
+func (q *recordingQueue) Ack(context.Context, *mq.Delivery) error {
+	return nil
+}
+
+func (q *recordingQueue) Nack(context.Context, *mq.Delivery, bool) error {
+	return nil
+}
+
+func TestRetryUpsert(t *testing.T) {
+	ctx := context.Background()
+	created := dryRun(t)
+
+	unavailable := errors.New("unavailable")
+
+	assert.NoError(t, database.Global().Callback().Create().Before("gorm:create").Register("test:unavailable", func(tx *gorm.DB) {
+		_ = tx.AddError(unavailable)
+	}))
+
+	queue := &recordingQueue{}
+
+	server := newIndexServer(t)
+	server.queue = queue
+
+	message := protocol.Message{Address: owner, Network: protocol.NetworkEthereum}
+
+	body, err := json.Marshal(&message)
+	assert.NoError(t, err)
+
+	err = server.handleWorkers(ctx, &message, indexTransactions())
+	assert.ErrorIs(t, err, unavailable)
+
+	server.settle(&mq.Delivery{Body: body}, protocol.IndexerWorkRoutingKey, err)
+
+	assert.Len(t, queue.delayed, 1)
+
+	retry := queue.delayed[0]
+	assert.Equal(t, 1, retry.Retry)
+	assert.Empty(t, retry.Datasources, "all the datasources are retried")
+
+	// The retry isn't skipped even if the nonce of the address hasn't changed
+	addressStatus := model.Address{NonceMap: map[string]int64{protocol.NetworkEthereum: 7}}
+	assert.False(t, indexed(retry, addressStatus, 7))
+
+	assert.NoError(t, database.Global().Callback().Create().Remove("test:unavailable"))
+
+	assert.NoError(t, server.handleWorkers(ctx, retry, indexTransactions()))
+	assert.Len(t, *created, 1)
+}
@@ -38,6 +38,16 @@ func (s *service) Networks() []string {
 	}
 }
 
+func (s *service) Consumes() []string {
+	return nil
+}
+
+func (s *service) Produces() []string {
+	return []string{
+		worker.CapabilityTransactions,
+	}
+}
+
 func (s *service) Initialize(ctx context.Context) error {
 	return nil
 }
@@ -52,6 +52,16 @@ func (s *service) Networks() []string {
 	}
 }
 
+func (s *service) Consumes() []string {
+	return []string{
+		worker.CapabilityTransfers,
+	}
+}
+
+func (s *service) Produces() []string {
+	return nil
+}
+
 func (s *service) Initialize(_ context.Context) error {
 	return nil
 }
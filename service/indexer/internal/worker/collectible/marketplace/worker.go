@@ -60,6 +60,18 @@ func (i *internal) Networks() []string {
 	}
 }
 
+func (i *internal) Consumes() []string {
+	return []string{
+		worker.CapabilityTransactions,
+	}
+}
+
+func (i *internal) Produces() []string {
+	return []string{
+		worker.CapabilityActions,
+	}
+}
+
 func (i *internal) Initialize(ctx context.Context) error {
 	return nil
 }
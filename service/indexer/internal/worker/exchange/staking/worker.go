@@ -35,6 +35,18 @@ func (s *Staking) Networks() []string {
 	}
 }
 
+func (s *Staking) Consumes() []string {
+	return []string{
+		worker.CapabilityTransactions,
+	}
+}
+
+func (s *Staking) Produces() []string {
+	return []string{
+		worker.CapabilityActions,
+	}
+}
+
 func (s *Staking) Initialize(ctx context.Context) error {
 	return nil
 }
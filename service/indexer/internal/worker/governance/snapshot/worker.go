@@ -81,6 +81,18 @@ func (s *service) Networks() []string {
 	}
 }
 
+func (s *service) Consumes() []string {
+	return []string{
+		worker.CapabilityTransactions,
+	}
+}
+
+func (s *service) Produces() []string {
+	return []string{
+		worker.CapabilityActions,
+	}
+}
+
 func (s *service) Initialize(ctx context.Context) error {
 	return nil
 }
@@ -0,0 +1,319 @@
+package worker
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+)
+
+// Graph is the workers sorted by their dependencies, the workers of a level only depend on the previous levels,
+// so that they can run concurrently. Workers of a level keep the order they are registered in.
+type Graph struct {
+	Levels [][]Worker
+}
+
+// NewGraph builds the graph from the capabilities of workers, it fails if a worker consumes a capability
+// which no other worker produces, or the dependencies are cyclic
+func NewGraph(workers []Worker) (*Graph, error) {
+	producers := make(map[string][]int)
+
+	for index, worker := range workers {
+		for _, capability := range worker.Produces() {
+			producers[capability] = append(producers[capability], index)
+		}
+	}
+
+	var (
+		indegrees  = make([]int, len(workers))
+		dependents = make([][]int, len(workers))
+	)
+
+	for index, worker := range workers {
+		dependencies := make(map[int]struct{})
+
+		for _, capability := range worker.Consumes() {
+			if len(producers[capability]) == 0 {
+				return nil, fmt.Errorf("worker %s consumes %s which no worker produces", worker.Name(), capability)
+			}
+
+			for _, producer := range producers[capability] {
+				if producer == index {
+					return nil, fmt.Errorf("worker %s consumes %s which is produced by itself", worker.Name(), capability)
+				}
+
+				dependencies[producer] = struct{}{}
+			}
+		}
+
+		indegrees[index] = len(dependencies)
+
+		for dependency := range dependencies {
+			dependents[dependency] = append(dependents[dependency], index)
+		}
+	}
+
+	var (
+		graph  Graph
+		sorted int
+		done   = make([]bool, len(workers))
+	)
+
+	for sorted < len(workers) {
+		var level []int
+
+		for index := range workers {
+			if !done[index] && indegrees[index] == 0 {
+				level = append(level, index)
+			}
+		}
+
+		if len(level) == 0 {
+			var names []string
+
+			for index, worker := range workers {
+				if !done[index] {
+					names = append(names, worker.Name())
+				}
+			}
+
+			return nil, fmt.Errorf("cyclic dependencies between workers: %s", strings.Join(names, ", "))
+		}
+
+		levelWorkers := make([]Worker, 0, len(level))
+
+		for _, index := range level {
+			done[index] = true
+			levelWorkers = append(levelWorkers, workers[index])
+
+			for _, dependent := range dependents[index] {
+				indegrees[dependent]--
+			}
+		}
+
+		graph.Levels = append(graph.Levels, levelWorkers)
+		sorted += len(level)
+	}
+
+	return &graph, nil
+}
+
+// Filter returns the levels with only the workers in workers, and drops the empty levels
+func (g *Graph) Filter(workers []Worker) [][]Worker {
+	enabled := make(map[string]struct{}, len(workers))
+
+	for _, worker := range workers {
+		enabled[worker.Name()] = struct{}{}
+	}
+
+	levels := make([][]Worker, 0, len(g.Levels))
+
+	for _, level := range g.Levels {
+		var levelWorkers []Worker
+
+		for _, worker := range level {
+			if _, exists := enabled[worker.Name()]; exists {
+				levelWorkers = append(levelWorkers, worker)
+			}
+		}
+
+		if len(levelWorkers) > 0 {
+			levels = append(levels, levelWorkers)
+		}
+	}
+
+	return levels
+}
+
+// MergeOutputs merges the transactions returned by the workers of a level from inputs, the transactions they handled.
+// The workers edit transfers independently, so the edits are merged by transfer index: a transfer changed, added or
+// removed by a worker is taken from it, and the others are kept from inputs. The other fields of a transaction, and a
+// transfer changed by several workers, are taken from the worker whose transaction has the higher priority tag,
+// the later worker in the registration order wins the ties, like the workers overwrote each other one after another.
+func MergeOutputs(inputs []model.Transaction, outputs [][]model.Transaction) map[string]model.Transaction {
+	type edit struct {
+		transfers []model.Transfer
+		priority  int
+	}
+
+	var (
+		inputMap        = make(map[string]model.Transaction, len(inputs))
+		transactionsMap = make(map[string]model.Transaction)
+		changed         = make(map[string]bool)
+		edits           = make(map[string]map[int64]edit)
+	)
+
+	for _, transaction := range inputs {
+		inputMap[transaction.Hash] = transaction
+	}
+
+	for _, transactions := range outputs {
+		for _, transaction := range transactions {
+			input, handled := inputMap[transaction.Hash]
+
+			// A transaction passed through doesn't override the edits of the other workers
+			if handled && reflect.DeepEqual(input, transaction) {
+				if _, exists := transactionsMap[transaction.Hash]; !exists {
+					transactionsMap[transaction.Hash] = transaction
+				}
+
+				continue
+			}
+
+			priority := filter.TagPriority[transaction.Tag]
+
+			if current := transactionsMap[transaction.Hash]; !changed[transaction.Hash] || priority >= filter.TagPriority[current.Tag] {
+				transactionsMap[transaction.Hash] = transaction
+				changed[transaction.Hash] = true
+			}
+
+			if !handled {
+				continue
+			}
+
+			if edits[transaction.Hash] == nil {
+				edits[transaction.Hash] = make(map[int64]edit)
+			}
+
+			var (
+				inputTransfers  = groupTransfers(input.Transfers)
+				outputTransfers = groupTransfers(transaction.Transfers)
+			)
+
+			for index := range mergeIndexes(inputTransfers, outputTransfers) {
+				if reflect.DeepEqual(inputTransfers[index], outputTransfers[index]) {
+					continue
+				}
+
+				if current, exists := edits[transaction.Hash][index]; !exists || priority >= current.priority {
+					edits[transaction.Hash][index] = edit{transfers: outputTransfers[index], priority: priority}
+				}
+			}
+		}
+	}
+
+	for hash, transaction := range transactionsMap {
+		input, handled := inputMap[hash]
+		if !handled || !changed[hash] {
+			continue
+		}
+
+		var (
+			transfers       = groupTransfers(input.Transfers)
+			transactionEdit = edits[hash]
+		)
+
+		for index, edit := range transactionEdit {
+			if len(edit.transfers) == 0 {
+				delete(transfers, index)
+			} else {
+				transfers[index] = edit.transfers
+			}
+		}
+
+		indexes := make([]int64, 0, len(transfers))
+
+		for index := range transfers {
+			indexes = append(indexes, index)
+		}
+
+		sort.Slice(indexes, func(i, j int) bool {
+			return indexes[i] < indexes[j]
+		})
+
+		transaction.Transfers = make([]model.Transfer, 0, len(input.Transfers))
+
+		for _, index := range indexes {
+			transaction.Transfers = append(transaction.Transfers, transfers[index]...)
+		}
+
+		transactionsMap[hash] = transaction
+	}
+
+	return transactionsMap
+}
+
+// CloneTransactions deep copies transactions, so that the workers handling them concurrently don't share
+// the transfers and the metadata they update in place
+func CloneTransactions(transactions []model.Transaction) []model.Transaction {
+	clonedTransactions := make([]model.Transaction, len(transactions))
+
+	for index, transaction := range transactions {
+		transaction.Addresses = cloneSlice(transaction.Addresses)
+		transaction.Sources = cloneSlice(transaction.Sources)
+		transaction.SourceData = cloneSlice(transaction.SourceData)
+		transaction.Fee = clonePointer(transaction.Fee)
+		transaction.FeeValueUSD = clonePointer(transaction.FeeValueUSD)
+		transaction.Success = clonePointer(transaction.Success)
+
+		if transaction.Transfers != nil {
+			transfers := make([]model.Transfer, len(transaction.Transfers))
+
+			for transferIndex, transfer := range transaction.Transfers {
+				transfer.Metadata = cloneSlice(transfer.Metadata)
+				transfer.SourceData = cloneSlice(transfer.SourceData)
+				transfer.RelatedUrls = cloneSlice(transfer.RelatedUrls)
+
+				if transfer.BlockNumber != nil {
+					transfer.BlockNumber = new(big.Int).Set(transfer.BlockNumber)
+				}
+
+				transfers[transferIndex] = transfer
+			}
+
+			transaction.Transfers = transfers
+		}
+
+		clonedTransactions[index] = transaction
+	}
+
+	return clonedTransactions
+}
+
+// groupTransfers returns the transfers by index, in their order since an index may be used by several transfers
+func groupTransfers(transfers []model.Transfer) map[int64][]model.Transfer {
+	result := make(map[int64][]model.Transfer)
+
+	for _, transfer := range transfers {
+		result[transfer.Index] = append(result[transfer.Index], transfer)
+	}
+
+	return result
+}
+
+func mergeIndexes(a, b map[int64][]model.Transfer) map[int64]struct{} {
+	result := make(map[int64]struct{}, len(a)+len(b))
+
+	for index := range a {
+		result[index] = struct{}{}
+	}
+
+	for index := range b {
+		result[index] = struct{}{}
+	}
+
+	return result
+}
+
+// cloneSlice keeps the nil slices nil, so that the clones are equal to the originals
+func cloneSlice[S ~[]E, E any](slice S) S {
+	if slice == nil {
+		return nil
+	}
+
+	return append(make(S, 0, len(slice)), slice...)
+}
+
+func clonePointer[T any](pointer *T) *T {
+	if pointer == nil {
+		return nil
+	}
+
+	value := *pointer
+
+	return &value
+}
@@ -0,0 +1,185 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ worker.Worker = (*fake)(nil)
+
+type fake struct {
+	name     string
+	consumes []string
+	produces []string
+}
+
+func (f *fake) Name() string {
+	return f.name
+}
+
+func (f *fake) Networks() []string {
+	return []string{protocol.NetworkEthereum}
+}
+
+func (f *fake) Consumes() []string {
+	return f.consumes
+}
+
+func (f *fake) Produces() []string {
+	return f.produces
+}
+
+func (f *fake) Initialize(ctx context.Context) error {
+	return nil
+}
+
+func (f *fake) Handle(ctx context.Context, message *protocol.Message, transactions []model.Transaction) ([]model.Transaction, error) {
+	return transactions, nil
+}
+
+func (f *fake) Jobs() []worker.Job {
+	return nil
+}
+
+func TestNewGraph(t *testing.T) {
+	workers := []worker.Worker{
+		&fake{name: "metaverse", consumes: []string{worker.CapabilityTransfers}},
+		&fake{name: "build_transactions", produces: []string{worker.CapabilityTransactions}},
+		&fake{name: "swap", consumes: []string{worker.CapabilityTransactions}, produces: []string{worker.CapabilityActions}},
+		&fake{name: "transaction", consumes: []string{worker.CapabilityTransactions, worker.CapabilityActions}, produces: []string{worker.CapabilityTransfers}},
+		&fake{name: "staking", consumes: []string{worker.CapabilityTransactions}, produces: []string{worker.CapabilityActions}},
+		&fake{name: "friendtech", consumes: []string{worker.CapabilityTransfers}},
+	}
+
+	graph, err := worker.NewGraph(workers)
+	assert.NoError(t, err)
+
+	// Workers of a level keep the registration order
+	assert.Equal(t, [][]string{
+		{"build_transactions"},
+		{"swap", "staking"},
+		{"transaction"},
+		{"metaverse", "friendtech"},
+	}, levelNames(graph.Levels))
+
+	filtered := graph.Filter([]worker.Worker{workers[2], workers[5]})
+	assert.Equal(t, [][]string{{"swap"}, {"friendtech"}}, levelNames(filtered))
+}
+
+func TestNewGraphInvalid(t *testing.T) {
+	_, err := worker.NewGraph([]worker.Worker{
+		&fake{name: "swap", consumes: []string{worker.CapabilityTransactions}},
+	})
+	assert.ErrorContains(t, err, "no worker produces")
+
+	_, err = worker.NewGraph([]worker.Worker{
+		&fake{name: "loop", consumes: []string{worker.CapabilityActions}, produces: []string{worker.CapabilityActions}},
+	})
+	assert.ErrorContains(t, err, "produced by itself")
+
+	_, err = worker.NewGraph([]worker.Worker{
+		&fake{name: "build_transactions", produces: []string{worker.CapabilityTransactions}},
+		&fake{name: "a", consumes: []string{worker.CapabilityTransfers}, produces: []string{worker.CapabilityActions}},
+		&fake{name: "b", consumes: []string{worker.CapabilityActions}, produces: []string{worker.CapabilityTransfers}},
+	})
+	assert.EqualError(t, err, "cyclic dependencies between workers: a, b")
+}
+
+func TestMergeOutputs(t *testing.T) {
+	outputs := [][]model.Transaction{
+		{
+			{Hash: "0x1", Tag: filter.TagCollectible},
+			{Hash: "0x2", Tag: filter.TagExchange, Platform: "first"},
+		},
+		{
+			{Hash: "0x1", Tag: filter.TagExchange},
+			{Hash: "0x2", Tag: filter.TagExchange, Platform: "second"},
+			{Hash: "0x3", Tag: filter.TagTransaction},
+		},
+	}
+
+	transactionsMap := worker.MergeOutputs(nil, outputs)
+
+	assert.Len(t, transactionsMap, 3)
+	assert.Equal(t, filter.TagCollectible, transactionsMap["0x1"].Tag)
+	assert.Equal(t, "second", transactionsMap["0x2"].Platform)
+}
+
+func TestMergeOutputsTransfers(t *testing.T) {
+	inputs := []model.Transaction{
+		{
+			Hash: "0x1",
+			Tag:  filter.TagTransaction,
+			Transfers: []model.Transfer{
+				{Index: 0, Tag: filter.TagTransaction, Metadata: []byte(`{}`)},
+				{Index: 1, Tag: filter.TagTransaction, Metadata: []byte(`{}`)},
+			},
+		},
+	}
+
+	// The workers of a level edit different transfers of the same transaction concurrently
+	handlers := []func(transaction *model.Transaction){
+		func(transaction *model.Transaction) {
+			transaction.Tag = filter.TagExchange
+			transaction.Transfers[0].Tag = filter.TagExchange
+			transaction.Transfers[0].Metadata[0] = '['
+		},
+		func(transaction *model.Transaction) {
+			transaction.Transfers[1].Tag = filter.TagCollectible
+			transaction.Transfers = append(transaction.Transfers, model.Transfer{Index: 2, Tag: filter.TagSocial})
+		},
+	}
+
+	outputs := make([][]model.Transaction, len(handlers))
+
+	var waitGroup sync.WaitGroup
+
+	for index, handler := range handlers {
+		waitGroup.Add(1)
+
+		go func(index int, handler func(transaction *model.Transaction), transactions []model.Transaction) {
+			defer waitGroup.Done()
+
+			handler(&transactions[0])
+			outputs[index] = transactions
+		}(index, handler, worker.CloneTransactions(inputs))
+	}
+
+	waitGroup.Wait()
+
+	transactionsMap := worker.MergeOutputs(inputs, outputs)
+
+	assert.Len(t, transactionsMap, 1)
+
+	transaction := transactionsMap["0x1"]
+	assert.Equal(t, filter.TagExchange, transaction.Tag)
+	assert.Len(t, transaction.Transfers, 3)
+	assert.Equal(t, filter.TagExchange, transaction.Transfers[0].Tag)
+	assert.Equal(t, filter.TagCollectible, transaction.Transfers[1].Tag)
+	assert.Equal(t, filter.TagSocial, transaction.Transfers[2].Tag)
+
+	// The inputs aren't updated through the clones
+	assert.Equal(t, filter.TagTransaction, inputs[0].Transfers[0].Tag)
+	assert.Equal(t, `{}`, string(inputs[0].Transfers[0].Metadata))
+}
+
+func levelNames(levels [][]worker.Worker) (names [][]string) {
+	for _, level := range levels {
+		var levelNames []string
+
+		for _, internalWorker := range level {
+			levelNames = append(levelNames, internalWorker.Name())
+		}
+
+		names = append(names, levelNames)
+	}
+
+	return names
+}
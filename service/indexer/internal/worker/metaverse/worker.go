@@ -34,6 +34,16 @@ func (s *service) Networks() []string {
 	return network
 }
 
+func (s *service) Consumes() []string {
+	return []string{
+		worker.CapabilityTransfers,
+	}
+}
+
+func (s *service) Produces() []string {
+	return nil
+}
+
 func (s *service) Initialize(ctx context.Context) error {
 	for _, contract := range RouterMap {
 		network = append(network, contract.Network)
@@ -0,0 +1,79 @@
+package price
+
+import (
+	"context"
+
+	"github.com/naturalselectionlabs/pregod/common/database"
+	"github.com/naturalselectionlabs/pregod/common/database/model"
+	"github.com/naturalselectionlabs/pregod/common/price"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/utils/loggerx"
+	"github.com/naturalselectionlabs/pregod/service/indexer/internal/worker"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+const Name = "price"
+
+var _ worker.Worker = (*service)(nil)
+
+// service values the fees and the tokens of transactions in USD with the stored prices after all the other workers
+type service struct {
+	priceClient *price.Client
+}
+
+func (s *service) Name() string {
+	return Name
+}
+
+func (s *service) Networks() []string {
+	return lo.Keys(price.NativeCoins)
+}
+
+func (s *service) Consumes() []string {
+	return []string{
+		worker.CapabilityTransactions,
+		worker.CapabilityActions,
+		worker.CapabilityTransfers,
+	}
+}
+
+func (s *service) Produces() []string {
+	return nil
+}
+
+func (s *service) Initialize(ctx context.Context) error {
+	s.priceClient = price.New(database.Global())
+
+	return nil
+}
+
+func (s *service) Handle(ctx context.Context, message *protocol.Message, transactions []model.Transaction) ([]model.Transaction, error) {
+	tracer := otel.Tracer("price_worker")
+	_, handlerSpan := tracer.Start(ctx, "price_worker:Handle")
+
+	defer handlerSpan.End()
+
+	// The stored prices are used without waiting for CoinGecko, the missing ones are fetched in the background
+	// and the values left unset are backfilled by the prices command
+	for index := range transactions {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err := price.Enrich(ctx, &transactions[index], s.priceClient.Stored); err != nil {
+			loggerx.Global().Warn("failed to value transaction", zap.Error(err), zap.String("network", transactions[index].Network), zap.String("transaction_hash", transactions[index].Hash))
+		}
+	}
+
+	return transactions, nil
+}
+
+func (s *service) Jobs() []worker.Job {
+	return nil
+}
+
+func New() worker.Worker {
+	return &service{}
+}
@@ -39,6 +39,18 @@ func (s *service) Networks() []string {
 	}
 }
 
+func (s *service) Consumes() []string {
+	return []string{
+		worker.CapabilityTransactions,
+	}
+}
+
+func (s *service) Produces() []string {
+	return []string{
+		worker.CapabilityActions,
+	}
+}
+
 func (s *service) Initialize(ctx context.Context) (err error) {
 	s.crossbellClient, err = crossbell.New()
 	if err != nil {
@@ -36,6 +36,18 @@ func (s *service) Networks() []string {
 	}
 }
 
+func (s *service) Consumes() []string {
+	return []string{
+		worker.CapabilityTransactions,
+	}
+}
+
+func (s *service) Produces() []string {
+	return []string{
+		worker.CapabilityActions,
+	}
+}
+
 func (s *service) Initialize(ctx context.Context) (err error) {
 	return nil
 }
@@ -0,0 +1,67 @@
+package transaction
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/naturalselectionlabs/pregod/common/datasource/ethereum"
+	"github.com/naturalselectionlabs/pregod/common/datasource/ethereum/contract/erc20"
+	"github.com/naturalselectionlabs/pregod/common/datasource/ethereum/contract/erc721"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+)
+
+// parseApproval parses an Approval event, ERC-20 and ERC-721 share its signature.
+// An ERC-20 approval returns the allowance and an ERC-721 approval returns the token id,
+// approving the zero address clears the approval of an ERC-721 token.
+func parseApproval(log types.Log) (tokenID *big.Int, tokenValue *big.Int, approved bool, err error) {
+	switch len(log.Topics) {
+	case 3: // ERC-20
+		filterer, err := erc20.NewERC20Filterer(log.Address, nil)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("new erc20 filterer: %w", err)
+		}
+
+		event, err := filterer.ParseApproval(log)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("parse approval event: %w", err)
+		}
+
+		return nil, event.Value, len(event.Value.Bits()) > 0, nil
+	case 4: // ERC-721 added last topic to index
+		filterer, err := erc721.NewERC721Filterer(log.Address, nil)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("new erc721 filterer: %w", err)
+		}
+
+		event, err := filterer.ParseApproval(log)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("parse approval event: %w", err)
+		}
+
+		return event.TokenId, nil, event.Spender != ethereum.AddressGenesis, nil
+	default:
+		return nil, nil, false, ErrorUnsupportedContractEvent
+	}
+}
+
+// approvalStandard returns the standard of the approved token and the action of the approval,
+// the standard is empty for an ApprovalForAll event, which covers both ERC-721 and ERC-1155.
+func approvalStandard(tokenID *big.Int, tokenValue *big.Int, approved bool) (standard string, action string, err error) {
+	switch {
+	case tokenID == nil && tokenValue != nil:
+		standard = protocol.TokenStandardERC20
+	case tokenID != nil && tokenValue == nil:
+		standard = protocol.TokenStandardERC721
+	case tokenID == nil && tokenValue == nil: // ApprovalForAll
+	default:
+		return "", "", ErrorUnsupportedContractEvent
+	}
+
+	if approved {
+		return standard, filter.ActionApprove, nil
+	}
+
+	return standard, filter.ActionRevoke, nil
+}
@@ -0,0 +1,90 @@
+package transaction
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/naturalselectionlabs/pregod/common/datasource/ethereum"
+	"github.com/naturalselectionlabs/pregod/common/datasource/ethereum/contract/erc20"
+	"github.com/naturalselectionlabs/pregod/common/protocol"
+	"github.com/naturalselectionlabs/pregod/common/protocol/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApproval(t *testing.T) {
+	var (
+		owner   = common.HexToAddress("0x000000000000000000000000000000000000a11c")
+		spender = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+	)
+
+	// erc20Approval returns an ERC-20 Approval log, the allowance isn't indexed
+	erc20Approval := func(spender common.Address, value int64) types.Log {
+		return types.Log{
+			Topics: []common.Hash{erc20.EventHashApproval, owner.Hash(), spender.Hash()},
+			Data:   common.BigToHash(big.NewInt(value)).Bytes(),
+		}
+	}
+
+	// erc721Approval returns an ERC-721 Approval log, the token id is indexed
+	erc721Approval := func(spender common.Address, tokenID int64) types.Log {
+		return types.Log{
+			Topics: []common.Hash{erc20.EventHashApproval, owner.Hash(), spender.Hash(), common.BigToHash(big.NewInt(tokenID))},
+		}
+	}
+
+	testcases := []struct {
+		name     string
+		log      types.Log
+		standard string
+		action   string
+	}{
+		{
+			name:     "erc20 approve",
+			log:      erc20Approval(spender, 100),
+			standard: protocol.TokenStandardERC20,
+			action:   filter.ActionApprove,
+		},
+		{
+			name:     "erc20 revoke",
+			log:      erc20Approval(spender, 0),
+			standard: protocol.TokenStandardERC20,
+			action:   filter.ActionRevoke,
+		},
+		{
+			name:     "erc721 approve",
+			log:      erc721Approval(spender, 1),
+			standard: protocol.TokenStandardERC721,
+			action:   filter.ActionApprove,
+		},
+		{
+			name:     "erc721 revoke",
+			log:      erc721Approval(ethereum.AddressGenesis, 1),
+			standard: protocol.TokenStandardERC721,
+			action:   filter.ActionRevoke,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			tokenID, tokenValue, approved, err := parseApproval(testcase.log)
+			assert.NoError(t, err)
+
+			standard, action, err := approvalStandard(tokenID, tokenValue, approved)
+			assert.NoError(t, err)
+
+			assert.Equal(t, testcase.standard, standard)
+			assert.Equal(t, testcase.action, action)
+		})
+	}
+
+	_, _, _, err := parseApproval(types.Log{Topics: []common.Hash{erc20.EventHashApproval, owner.Hash()}})
+	assert.ErrorIs(t, err, ErrorUnsupportedContractEvent)
+
+	// ApprovalForAll has neither a token id nor a value
+	standard, action, err := approvalStandard(nil, nil, false)
+	assert.NoError(t, err)
+	assert.Empty(t, standard)
+	assert.Equal(t, filter.ActionRevoke, action)
+}
@@ -52,6 +52,18 @@ func (w *Worker) Networks() []string {
 	}
 }
 
+func (w *Worker) Consumes() []string {
+	return []string{
+		worker.CapabilityTransactions,
+	}
+}
+
+func (w *Worker) Produces() []string {
+	return []string{
+		worker.CapabilityActions,
+	}
+}
+
 func (w *Worker) Initialize(ctx context.Context) error {
 	return nil
 }
@@ -35,6 +35,18 @@ func (m *MultiSign) Networks() []string {
 	return protocol.EthclientNetworks
 }
 
+func (m *MultiSign) Consumes() []string {
+	return []string{
+		worker.CapabilityTransactions,
+	}
+}
+
+func (m *MultiSign) Produces() []string {
+	return []string{
+		worker.CapabilityActions,
+	}
+}
+
 func (m *MultiSign) Initialize(ctx context.Context) error {
 	gnosisSafeABI, err := contract.ContractABIs.Open("safe/GnosisSafe.abi")
 	if err != nil {
@@ -74,6 +74,19 @@ func (s *service) Networks() []string {
 	}
 }
 
+func (s *service) Consumes() []string {
+	return []string{
+		worker.CapabilityTransactions,
+		worker.CapabilityActions,
+	}
+}
+
+func (s *service) Produces() []string {
+	return []string{
+		worker.CapabilityTransfers,
+	}
+}
+
 func (s *service) Initialize(ctx context.Context) error {
 	if err := s.loadCentralizedExchangeWallets(ctx); err != nil {
 		return fmt.Errorf("initialize centralized exchange wallets: %w", err)
@@ -319,33 +332,18 @@ func (s *service) handleEthereumOriginToken(ctx context.Context, message *protoc
 			tokenValues = append(tokenValues, event.Values[i])
 		}
 	case erc20.EventHashApproval:
-		switch len(log.Topics) {
-		case 3: // ERC-20
-			filterer, err := erc20.NewERC20Filterer(log.Address, nil)
-			if err != nil {
-				return nil, fmt.Errorf("new erc20 filterer: %w", err)
-			}
-
-			event, err := filterer.ParseApproval(*log)
-			if err != nil {
-				return nil, fmt.Errorf("parse approval event: %w", err)
-			}
-
-			tokenApproval = true
-			tokenValues = append(tokenValues, event.Value)
-		case 4: // ERC-721
-			filterer, err := erc721.NewERC721Filterer(log.Address, nil)
-			if err != nil {
-				return nil, fmt.Errorf("new erc20 filterer: %w", err)
-			}
+		tokenID, tokenValue, approved, err := parseApproval(*log)
+		if err != nil {
+			return nil, err
+		}
 
-			event, err := filterer.ParseApproval(*log)
-			if err != nil {
-				return nil, fmt.Errorf("parse approval event: %w", err)
-			}
+		tokenApproval = true
+		tokenApproved = approved
 
-			tokenApproval = true
-			tokenIDs = append(tokenIDs, event.TokenId)
+		if tokenID != nil {
+			tokenIDs = append(tokenIDs, tokenID)
+		} else {
+			tokenValues = append(tokenValues, tokenValue)
 		}
 	case erc721.EventHashApprovalForAll:
 		filterer, err := erc721.NewERC721Filterer(log.Address, nil)
@@ -605,25 +603,23 @@ func (s *service) buildEthereumTokenApprovalMetadata(ctx context.Context, transa
 
 	defer snap.End()
 
+	standard, action, err := approvalStandard(tokenID, tokenValue, approved)
+	if err != nil {
+		return nil, err
+	}
+
 	var tokenMetadata *metadata.Token
-	var err error
 
-	switch {
-	case tokenID == nil && tokenValue != nil: // ERC-20
+	switch standard {
+	case protocol.TokenStandardERC20:
 		if tokenMetadata, err = s.tokenClient.ERC20ToMetadata(ctx, transaction.Network, *tokenAddress); err != nil {
 			return nil, fmt.Errorf("get erc20 metadata %s: %w", *tokenAddress, err)
 		}
 
 		tokenMetadata.SetValue(decimal.NewFromBigInt(tokenValue, 0))
 
-		if len(tokenValue.Bits()) > 0 {
-			tokenMetadata.Action = filter.ActionApprove
-		} else {
-			tokenMetadata.Action = filter.ActionRevoke
-		}
-
 		transfer.Tag, transfer.Type = filter.UpdateTagAndType(filter.TagTransaction, transfer.Tag, filter.TransactionApproval, transfer.Type)
-	case tokenID != nil && tokenValue != nil: // ERC-721
+	case protocol.TokenStandardERC721:
 		erc721, err := s.tokenClient.ERC721(ctx, transaction.Network, *tokenAddress, tokenID)
 		if err != nil {
 			return nil, fmt.Errorf("get erc-721 %s/%d: %w", *tokenAddress, tokenID, err)
@@ -642,22 +638,16 @@ func (s *service) buildEthereumTokenApprovalMetadata(ctx context.Context, transa
 
 		// Not supported
 		// transfer.RelatedUrls = ethereum.BuildURL(transfer.RelatedUrls, ethereum.BuildTokenURL(transaction.Network, *tokenAddress, tokenID.String())...)
-	case tokenID == nil && tokenValue == nil: // ERC-721 and ERC-1155
+	default: // ERC-721 and ERC-1155
 		if tokenMetadata, err = s.tokenClient.NFTToMetadata(ctx, transaction.Network, *tokenAddress, nil); err != nil {
 			return nil, fmt.Errorf("get nft metadata %s: %w", *tokenAddress, err)
 		}
 
-		if approved {
-			tokenMetadata.Action = filter.ActionApprove
-		} else {
-			tokenMetadata.Action = filter.ActionRevoke
-		}
-
 		transfer.Tag, transfer.Type = filter.UpdateTagAndType(filter.TagCollectible, transfer.Tag, filter.CollectibleApproval, transfer.Type)
-	default:
-		return nil, ErrorUnsupportedContractEvent
 	}
 
+	tokenMetadata.Action = action
+
 	metadataRaw, err := json.Marshal(tokenMetadata)
 	if err != nil {
 		return nil, fmt.Errorf("marshal token metadata: %w", err)
@@ -7,9 +7,22 @@ import (
 	"github.com/naturalselectionlabs/pregod/common/protocol"
 )
 
+const (
+	// CapabilityTransactions is the transactions built from the datasources with their receipts and logs
+	CapabilityTransactions = "transactions"
+	// CapabilityActions is the protocol specific actions, such as swaps, mints and posts
+	CapabilityActions = "actions"
+	// CapabilityTransfers is the token transfers of the transactions which aren't recognized as actions
+	CapabilityTransfers = "transfers"
+)
+
 type Worker interface {
 	Name() string
 	Networks() []string
+	// Consumes and Produces are the capabilities of the transactions, a worker runs after all the workers
+	// producing what it consumes, see NewGraph
+	Consumes() []string
+	Produces() []string
 	Initialize(ctx context.Context) error
 	Handle(ctx context.Context, message *protocol.Message, transactions []model.Transaction) ([]model.Transaction, error)
 	Jobs() []Job